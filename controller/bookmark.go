@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// BookmarkPostHandler 收藏帖子
+//
+//	@Summary		收藏帖子接口
+//	@Description	重复收藏不修改收藏时间
+//	@Tags			帖子相关接口(api分组展示使用的)
+//	@Produce		application/json
+//	@Param			Authorization	header	string	true	"Bearer JWT"
+//	@Param			id				path	int		true	"帖子ID"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/post/{id}/bookmark [post]
+func BookmarkPostHandler(ctx *gin.Context) {
+	userID, postID, ok := getBookmarkParam(ctx)
+	if !ok {
+		return
+	}
+	if err := logic.BookmarkPost(userID, postID); err != nil {
+		zap.L().Error("logic.BookmarkPost failed", zap.Int64("post_id", postID), zap.Error(err))
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(ctx, CodeInvalidParam)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// UnbookmarkPostHandler 取消收藏
+//
+//	@Summary		取消收藏接口
+//	@Tags			帖子相关接口(api分组展示使用的)
+//	@Produce		application/json
+//	@Param			Authorization	header	string	true	"Bearer JWT"
+//	@Param			id				path	int		true	"帖子ID"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/post/{id}/bookmark [delete]
+func UnbookmarkPostHandler(ctx *gin.Context) {
+	userID, postID, ok := getBookmarkParam(ctx)
+	if !ok {
+		return
+	}
+	if err := logic.UnbookmarkPost(userID, postID); err != nil {
+		zap.L().Error("logic.UnbookmarkPost failed", zap.Int64("post_id", postID), zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// GetBookmarksHandler 我的收藏
+//
+//	@Summary		收藏列表接口
+//	@Description	按收藏时间从新到旧游标分页，返回 list、next_cursor 和 has_more
+//	@Tags			用户相关接口
+//	@Produce		application/json
+//	@Param			Authorization	header	string						true	"Bearer JWT"
+//	@Param			object			query	models.ParamBookmarkList	false	"查询参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	_ResponsePostPage
+//	@Router			/me/bookmarks [get]
+func GetBookmarksHandler(ctx *gin.Context) {
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	p := &models.ParamBookmarkList{Size: 10}
+	if err := ctx.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetBookmarks with invalid param", zap.Error(err))
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	page, err := logic.GetBookmarks(userID, p)
+	if err != nil {
+		zap.L().Error("logic.GetBookmarks failed", zap.Int64("user_id", userID), zap.Error(err))
+		if errors.Is(err, logic.ErrorInvalidCursor) {
+			ResponseError(ctx, CodeInvalidParam)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, page)
+}
+
+// getBookmarkParam 获取当前用户和路径中的帖子ID，失败时已经写入响应
+func getBookmarkParam(ctx *gin.Context) (userID, postID int64, ok bool) {
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	postID, err = strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	return userID, postID, true
+}
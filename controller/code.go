@@ -14,6 +14,24 @@ const (
 
 	CodeNeedLogin
 	CodeInvalidToken
+
+	CodeNoPermission
+	CodeLoginLocked
+
+	CodeVoteTimeExpire
+	CodeVoteRepeat
+
+	CodeCommunityNotExist
+	CodeCommunityExist
+	CodeCommunityArchived
+
+	CodePostLocked
+	CodeUserBanned
+
+	CodeEmailExist
+	CodeInvalidEmailToken
+
+	CodeContentRejected
 )
 
 var CodeMsg = map[ResCode]string{
@@ -26,6 +44,24 @@ var CodeMsg = map[ResCode]string{
 
 	CodeNeedLogin:    "需要登录",
 	CodeInvalidToken: "无效的token",
+
+	CodeNoPermission: "没有操作权限",
+	CodeLoginLocked:  "登录尝试次数过多，请稍后再试",
+
+	CodeVoteTimeExpire: "投票时间已过",
+	CodeVoteRepeat:     "不允许重复投票",
+
+	CodeCommunityNotExist: "社区不存在",
+	CodeCommunityExist:    "社区名称或短名称已存在",
+	CodeCommunityArchived: "社区已归档",
+
+	CodePostLocked: "帖子已被锁定",
+	CodeUserBanned: "你已被禁止在该社区发言",
+
+	CodeEmailExist:        "邮箱已被使用",
+	CodeInvalidEmailToken: "链接无效或已过期",
+
+	CodeContentRejected: "内容未通过审核",
 }
 
 func (c ResCode) Msg() string {
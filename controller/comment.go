@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/logic"
+	"bluebell/models"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// CreateCommentHandler 发表评论
+//
+//	@Summary		发表评论接口
+//	@Description	回复帖子或者回复帖子下的某条评论
+//	@Tags			评论相关接口
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string						true	"Bearer JWT"
+//	@Param			id				path	int							true	"帖子ID"
+//	@Param			object			body	models.ParamCreateComment	true	"评论参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/post/{id}/comments [post]
+func CreateCommentHandler(ctx *gin.Context) {
+	// 1. 获取参数及参数校验
+	pid, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamCreateComment)
+	if err := ctx.ShouldBindJSON(p); err != nil {
+		zap.L().Error("controller.CreateCommentHandler: ctx.ShouldBindJSON() failed", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(ctx, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMsg(ctx, CodeInvalidParam, removeTagStruct(errs.Translate(trans)))
+		return
+	}
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	// 2. 创建评论
+	c := &models.Comment{
+		PostID:   pid,
+		ParentID: p.ParentID,
+		AuthorID: userID,
+		Content:  p.Content,
+	}
+	if err := logic.CreateComment(c); err != nil {
+		zap.L().Error("controller.CreateCommentHandler: logic.CreateComment() failed", zap.Error(err))
+		switch {
+		case errors.Is(err, mysql.ErrorInvalidID):
+			ResponseError(ctx, CodeInvalidParam)
+		case errors.Is(err, logic.ErrorPostLocked):
+			ResponseError(ctx, CodePostLocked)
+		case errors.Is(err, logic.ErrorUserBanned):
+			ResponseError(ctx, CodeUserBanned)
+		default:
+			ResponseError(ctx, CodeServerBusy)
+		}
+		return
+	}
+	// 3. 返回响应
+	ResponseSuccess(ctx, gin.H{
+		"comment_id": strconv.FormatInt(c.ID, 10),
+	})
+}
+
+// GetCommentListHandler 获取帖子的评论列表
+//
+//	@Summary		评论列表接口
+//	@Description	分页获取帖子下的顶层评论及其楼层内的回复
+//	@Tags			评论相关接口
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string					true	"Bearer JWT"
+//	@Param			id				path	int						true	"帖子ID"
+//	@Param			object			query	models.ParamCommentList	false	"分页参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	_ResponseCommentList
+//	@Router			/post/{id}/comments [get]
+func GetCommentListHandler(ctx *gin.Context) {
+	pid, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	p := &models.ParamCommentList{
+		Page: 1,
+		Size: 10,
+	}
+	if err := ctx.ShouldBindQuery(p); err != nil || p.Page < 1 || p.Size < 1 {
+		zap.L().Error("controller.GetCommentListHandler: ctx.ShouldBindQuery() failed", zap.Error(err))
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	data, err := logic.GetCommentList(pid, p)
+	if err != nil {
+		zap.L().Error("controller.GetCommentListHandler: logic.GetCommentList() failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, data)
+}
+
+// CommentVoteHandler 为评论投票
+func CommentVoteHandler(ctx *gin.Context) {
+	// 1. 获取参数及参数校验
+	p := new(models.ParamCommentVoteData)
+	if err := ctx.ShouldBindJSON(p); err != nil {
+		zap.L().Error("controller.CommentVoteHandler with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(ctx, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMsg(ctx, CodeInvalidParam, removeTagStruct(errs.Translate(trans)))
+		return
+	}
+	uid, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	// 2. 投票的逻辑处理
+	if err := logic.VoteForComment(uid, p); err != nil {
+		zap.L().Error("logic.VoteForComment failed", zap.Error(err))
+		if errors.Is(err, redis.ErrorCommentNotExist) {
+			ResponseError(ctx, CodeInvalidParam)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	// 3. 返回响应
+	ResponseSuccess(ctx, nil)
+}
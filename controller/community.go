@@ -1,10 +1,14 @@
 package controller
 
 import (
+	"bluebell/dao/mysql"
 	"bluebell/logic"
+	"bluebell/models"
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 )
 
@@ -40,8 +44,317 @@ func CommunityDetailHandler(ctx *gin.Context) {
 	data, err := logic.GetCommunityDetail(id)
 	if err != nil {
 		zap.L().Error("logic.GetCommunityDetail() failed", zap.Error(err))
+		responseCommunityError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, data)
+}
+
+// CreateCommunityHandler 创建社区
+//
+//	@Summary		创建社区接口
+//	@Description	创建社区，创建者自动成为社区的管理者
+//	@Tags			社区相关接口
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string						true	"Bearer JWT"
+//	@Param			object			body	models.ParamCreateCommunity	true	"社区信息"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/community [post]
+func CreateCommunityHandler(ctx *gin.Context) {
+	// 1. 获取参数及参数校验
+	p := new(models.ParamCreateCommunity)
+	if !bindCommunityParam(ctx, p) {
+		return
+	}
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	// 2. 创建社区
+	data, err := logic.CreateCommunity(userID, p)
+	if err != nil {
+		zap.L().Error("logic.CreateCommunity() failed", zap.Error(err))
+		responseCommunityError(ctx, err)
+		return
+	}
+	// 3. 返回响应
+	ResponseSuccess(ctx, data)
+}
+
+// UpdateCommunityHandler 修改社区信息
+//
+//	@Summary		修改社区接口
+//	@Description	创建者和版主修改社区名称、简介、图标和发帖规则，短名称不能修改
+//	@Tags			社区相关接口
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string						true	"Bearer JWT"
+//	@Param			id				path	int							true	"社区ID"
+//	@Param			object			body	models.ParamUpdateCommunity	true	"社区信息"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/community/{id} [put]
+func UpdateCommunityHandler(ctx *gin.Context) {
+	// 1. 获取参数及参数校验
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamUpdateCommunity)
+	if !bindCommunityParam(ctx, p) {
+		return
+	}
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	// 2. 修改社区
+	if err := logic.UpdateCommunity(userID, id, p); err != nil {
+		zap.L().Error("logic.UpdateCommunity() failed", zap.Error(err))
+		responseCommunityError(ctx, err)
+		return
+	}
+	// 3. 返回响应
+	ResponseSuccess(ctx, nil)
+}
+
+// ArchiveCommunityHandler 归档社区
+//
+//	@Summary		归档社区接口
+//	@Description	创建者归档社区，归档后社区不再出现在列表中，也不能再发帖，已有帖子仍可查看
+//	@Tags			社区相关接口
+//	@Produce		application/json
+//	@Param			Authorization	header	string	true	"Bearer JWT"
+//	@Param			id				path	int		true	"社区ID"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/community/{id}/archive [post]
+func ArchiveCommunityHandler(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	if err := logic.ArchiveCommunity(userID, id); err != nil {
+		zap.L().Error("logic.ArchiveCommunity() failed", zap.Error(err))
+		responseCommunityError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// GetCommunityMembersHandler 查询社区的创建者和版主
+//
+//	@Summary		社区管理者列表接口
+//	@Tags			社区相关接口
+//	@Produce		application/json
+//	@Param			Authorization	header	string	true	"Bearer JWT"
+//	@Param			id				path	int		true	"社区ID"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/community/{id}/moderators [get]
+func GetCommunityMembersHandler(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	data, err := logic.GetCommunityMembers(id)
+	if err != nil {
+		zap.L().Error("logic.GetCommunityMembers() failed", zap.Error(err))
+		responseCommunityError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, data)
+}
+
+// AddCommunityModeratorHandler 设置版主
+//
+//	@Summary		设置版主接口
+//	@Description	社区创建者把用户设置为版主
+//	@Tags			社区相关接口
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string							true	"Bearer JWT"
+//	@Param			id				path	int								true	"社区ID"
+//	@Param			object			body	models.ParamCommunityModerator	true	"版主的用户ID"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/community/{id}/moderators [post]
+func AddCommunityModeratorHandler(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamCommunityModerator)
+	if !bindCommunityParam(ctx, p) {
+		return
+	}
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	if err := logic.AddCommunityModerator(userID, id, p.UserID); err != nil {
+		zap.L().Error("logic.AddCommunityModerator() failed", zap.Error(err))
+		responseCommunityError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// RemoveCommunityModeratorHandler 取消版主
+//
+//	@Summary		取消版主接口
+//	@Tags			社区相关接口
+//	@Produce		application/json
+//	@Param			Authorization	header	string	true	"Bearer JWT"
+//	@Param			id				path	int		true	"社区ID"
+//	@Param			user_id			path	int		true	"版主的用户ID"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/community/{id}/moderators/{user_id} [delete]
+func RemoveCommunityModeratorHandler(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	moderatorID, err := strconv.ParseInt(ctx.Param("user_id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	if err := logic.RemoveCommunityModerator(userID, id, moderatorID); err != nil {
+		zap.L().Error("logic.RemoveCommunityModerator() failed", zap.Error(err))
+		responseCommunityError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// JoinCommunityHandler 加入社区
+//
+//	@Summary		加入社区接口
+//	@Description	加入社区后，社区的帖子会出现在首页 /feed 中
+//	@Tags			社区相关接口
+//	@Produce		application/json
+//	@Param			Authorization	header	string	true	"Bearer JWT"
+//	@Param			id				path	int		true	"社区ID"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/community/{id}/join [post]
+func JoinCommunityHandler(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	if err := logic.JoinCommunity(userID, id); err != nil {
+		zap.L().Error("logic.JoinCommunity() failed", zap.Error(err))
+		responseCommunityError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// LeaveCommunityHandler 退出社区
+//
+//	@Summary		退出社区接口
+//	@Tags			社区相关接口
+//	@Produce		application/json
+//	@Param			Authorization	header	string	true	"Bearer JWT"
+//	@Param			id				path	int		true	"社区ID"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/community/{id}/leave [post]
+func LeaveCommunityHandler(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	if err := logic.LeaveCommunity(userID, id); err != nil {
+		zap.L().Error("logic.LeaveCommunity() failed", zap.Error(err))
+		responseCommunityError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// GetSubscribedCommunitiesHandler 查询当前用户加入的社区
+//
+//	@Summary		已加入的社区列表接口
+//	@Tags			社区相关接口
+//	@Produce		application/json
+//	@Param			Authorization	header	string	true	"Bearer JWT"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	_ResponseCommunityList
+//	@Router			/me/communities [get]
+func GetSubscribedCommunitiesHandler(ctx *gin.Context) {
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	data, err := logic.GetSubscribedCommunities(userID)
+	if err != nil {
+		zap.L().Error("logic.GetSubscribedCommunities() failed", zap.Error(err))
 		ResponseError(ctx, CodeServerBusy)
 		return
 	}
 	ResponseSuccess(ctx, data)
 }
+
+// bindCommunityParam 解析并校验请求体，失败时直接返回错误响应
+func bindCommunityParam(ctx *gin.Context, p interface{}) bool {
+	if err := ctx.ShouldBindJSON(p); err != nil {
+		zap.L().Error("controller: invalid community param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(ctx, CodeInvalidParam)
+			return false
+		}
+		ResponseErrorWithMsg(ctx, CodeInvalidParam, removeTagStruct(errs.Translate(trans)))
+		return false
+	}
+	return true
+}
+
+// responseCommunityError 社区相关接口的错误响应
+func responseCommunityError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mysql.ErrorInvalidID):
+		ResponseError(ctx, CodeCommunityNotExist)
+	case errors.Is(err, mysql.ErrorCommunityExist):
+		ResponseError(ctx, CodeCommunityExist)
+	case errors.Is(err, mysql.ErrorUserNotExist):
+		ResponseError(ctx, CodeUserNotExist)
+	case errors.Is(err, logic.ErrorCommunityArchived):
+		ResponseError(ctx, CodeCommunityArchived)
+	case errors.Is(err, logic.ErrorInvalidSlug):
+		ResponseErrorWithMsg(ctx, CodeInvalidParam, err.Error())
+	case errors.Is(err, logic.ErrorPermissionDenied):
+		ResponseError(ctx, CodeNoPermission)
+	default:
+		ResponseError(ctx, CodeServerBusy)
+	}
+}
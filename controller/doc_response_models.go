@@ -10,8 +10,69 @@ type _ResponsePostList struct {
 	Data    []*models.ApiPostDetail `json:"data"`    // 数据
 }
 
+type _ResponsePostPage struct {
+	Code    ResCode             `json:"code"`    // 业务响应状态码
+	Message string              `json:"message"` // 提示信息
+	Data    *models.ApiPostPage `json:"data"`    // 数据
+}
+
+type _ResponseSearchResult struct {
+	Code    ResCode                 `json:"code"`    // 业务响应状态码
+	Message string                  `json:"message"` // 提示信息
+	Data    *models.ApiSearchResult `json:"data"`    // 数据
+}
+
 type _ResponseCommunityList struct {
 	Code    ResCode             `json:"code"`    // 业务响应状态码
 	Message string              `json:"message"` // 提示信息
 	Data    []*models.Community `json:"data"`    // 数据
 }
+
+type _ResponseCommentList struct {
+	Code    ResCode                    `json:"code"`    // 业务响应状态码
+	Message string                     `json:"message"` // 提示信息
+	Data    []*models.ApiCommentDetail `json:"data"`    // 数据
+}
+
+type _ResponsePostRevisionList struct {
+	Code    ResCode                `json:"code"`    // 业务响应状态码
+	Message string                 `json:"message"` // 提示信息
+	Data    []*models.PostRevision `json:"data"`    // 数据
+}
+
+type _ResponseUserProfile struct {
+	Code    ResCode                `json:"code"`    // 业务响应状态码
+	Message string                 `json:"message"` // 提示信息
+	Data    *models.ApiUserProfile `json:"data"`    // 数据
+}
+
+// _ResponseMyProfile 个人资料接口响应数据
+type _ResponseMyProfile struct {
+	Code    ResCode             `json:"code"`    // 业务响应状态码
+	Message string              `json:"message"` // 提示信息
+	Data    *models.UserProfile `json:"data"`    // 数据
+}
+
+type _ResponseKarmaLeaderboard struct {
+	Code    ResCode             `json:"code"`    // 业务响应状态码
+	Message string              `json:"message"` // 提示信息
+	Data    []*models.KarmaRank `json:"data"`    // 数据
+}
+
+type _ResponseNotificationList struct {
+	Code    ResCode                     `json:"code"`    // 业务响应状态码
+	Message string                      `json:"message"` // 提示信息
+	Data    *models.ApiNotificationList `json:"data"`    // 数据
+}
+
+type _ResponseTrendingTags struct {
+	Code    ResCode               `json:"code"`    // 业务响应状态码
+	Message string                `json:"message"` // 提示信息
+	Data    []*models.TrendingTag `json:"data"`    // 数据
+}
+
+type _ResponsePostReviewList struct {
+	Code    ResCode              `json:"code"`    // 业务响应状态码
+	Message string               `json:"message"` // 提示信息
+	Data    []*models.PostReview `json:"data"`    // 数据
+}
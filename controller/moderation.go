@@ -0,0 +1,348 @@
+package controller
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"bluebell/models"
+	"bluebell/pkg/rbac"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RemovePostHandler 版主删除帖子
+//
+//	@Summary		版主删除帖子接口
+//	@Description	社区版主或管理员删除帖子，操作记录到管理日志
+//	@Tags			版主工具
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string						true	"Bearer JWT"
+//	@Param			id				path	string						true	"帖子ID"
+//	@Param			object			body	models.ParamModeratePost	false	"删除原因"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/mod/post/{id}/remove [post]
+func RemovePostHandler(ctx *gin.Context) {
+	actor, postID, ok := getModerationTarget(ctx)
+	if !ok {
+		return
+	}
+	p := new(models.ParamModeratePost)
+	if ctx.Request.ContentLength > 0 && !bindCommunityParam(ctx, p) {
+		return
+	}
+	if err := logic.RemovePost(actor, postID, p); err != nil {
+		zap.L().Error("logic.RemovePost failed", zap.Int64("post_id", postID), zap.Error(err))
+		responseModerationError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// LockPostHandler 锁定或解锁帖子
+//
+//	@Summary		锁定帖子接口
+//	@Description	锁定后帖子不能编辑和评论，locked 为 false 时解锁
+//	@Tags			版主工具
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string					true	"Bearer JWT"
+//	@Param			id				path	string					true	"帖子ID"
+//	@Param			object			body	models.ParamLockPost	true	"锁定参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/mod/post/{id}/lock [post]
+func LockPostHandler(ctx *gin.Context) {
+	actor, postID, ok := getModerationTarget(ctx)
+	if !ok {
+		return
+	}
+	p := new(models.ParamLockPost)
+	if !bindCommunityParam(ctx, p) {
+		return
+	}
+	if err := logic.LockPost(actor, postID, p); err != nil {
+		zap.L().Error("logic.LockPost failed", zap.Int64("post_id", postID), zap.Error(err))
+		responseModerationError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// PinPostHandler 置顶或取消置顶帖子
+//
+//	@Summary		置顶帖子接口
+//	@Description	置顶的帖子显示在社区帖子列表第一页的最前面，pinned 为 false 时取消置顶
+//	@Tags			版主工具
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string				true	"Bearer JWT"
+//	@Param			id				path	string				true	"帖子ID"
+//	@Param			object			body	models.ParamPinPost	true	"置顶参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/mod/post/{id}/pin [post]
+func PinPostHandler(ctx *gin.Context) {
+	actor, postID, ok := getModerationTarget(ctx)
+	if !ok {
+		return
+	}
+	p := new(models.ParamPinPost)
+	if !bindCommunityParam(ctx, p) {
+		return
+	}
+	if err := logic.PinPost(actor, postID, p); err != nil {
+		zap.L().Error("logic.PinPost failed", zap.Int64("post_id", postID), zap.Error(err))
+		responseModerationError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// BanUserHandler 在社区中封禁用户
+//
+//	@Summary		封禁用户接口
+//	@Description	被封禁的用户不能在该社区发帖和评论，duration 为封禁小时数，0 表示永久
+//	@Tags			版主工具
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string				true	"Bearer JWT"
+//	@Param			id				path	int					true	"社区ID"
+//	@Param			object			body	models.ParamBanUser	true	"封禁参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/mod/community/{id}/ban [post]
+func BanUserHandler(ctx *gin.Context) {
+	actor, communityID, ok := getModerationTarget(ctx)
+	if !ok {
+		return
+	}
+	p := new(models.ParamBanUser)
+	if !bindCommunityParam(ctx, p) {
+		return
+	}
+	if err := logic.BanUser(actor, communityID, p); err != nil {
+		zap.L().Error("logic.BanUser failed", zap.Int64("community_id", communityID), zap.Error(err))
+		responseModerationError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// UnbanUserHandler 解除社区封禁
+//
+//	@Summary		解除封禁接口
+//	@Tags			版主工具
+//	@Produce		application/json
+//	@Param			Authorization	header	string	true	"Bearer JWT"
+//	@Param			id				path	int		true	"社区ID"
+//	@Param			user_id			path	string	true	"用户ID"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/mod/community/{id}/ban/{user_id} [delete]
+func UnbanUserHandler(ctx *gin.Context) {
+	actor, communityID, ok := getModerationTarget(ctx)
+	if !ok {
+		return
+	}
+	userID, err := strconv.ParseInt(ctx.Param("user_id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	if err := logic.UnbanUser(actor, communityID, userID); err != nil {
+		zap.L().Error("logic.UnbanUser failed", zap.Int64("community_id", communityID), zap.Error(err))
+		responseModerationError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// GetModerationLogsHandler 查询管理日志
+//
+//	@Summary		管理日志接口
+//	@Description	管理员可以查询全部日志，社区版主只能查询自己管理的社区
+//	@Tags			版主工具
+//	@Produce		application/json
+//	@Param			Authorization	header	string	true	"Bearer JWT"
+//	@Param			object			query	models.ParamModerationLogList	false	"查询参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/mod/logs [get]
+func GetModerationLogsHandler(ctx *gin.Context) {
+	actor, err := getCurrentPrincipal(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	p := &models.ParamModerationLogList{
+		Page: 1,
+		Size: 10,
+	}
+	if err := ctx.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetModerationLogsHandler with invalid param", zap.Error(err))
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	data, err := logic.GetModerationLogs(actor, p)
+	if err != nil {
+		zap.L().Error("logic.GetModerationLogs failed", zap.Error(err))
+		responseModerationError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, data)
+}
+
+// GetPostReviewsHandler 查询审核队列
+//
+//	@Summary		审核队列接口
+//	@Description	未通过内容检查的帖子在审核通过前不公开，管理员可以查询全部社区，社区版主只能查询自己管理的社区
+//	@Tags			版主工具
+//	@Produce		application/json
+//	@Param			Authorization	header	string						true	"Bearer JWT"
+//	@Param			object			query	models.ParamPostReviewList	false	"查询参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	_ResponsePostReviewList
+//	@Router			/mod/reviews [get]
+func GetPostReviewsHandler(ctx *gin.Context) {
+	actor, err := getCurrentPrincipal(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	p := &models.ParamPostReviewList{
+		Page: 1,
+		Size: 10,
+	}
+	if err := ctx.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetPostReviewsHandler with invalid param", zap.Error(err))
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	data, err := logic.GetPostReviews(actor, p)
+	if err != nil {
+		zap.L().Error("logic.GetPostReviews failed", zap.Error(err))
+		responseModerationError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, data)
+}
+
+// ApprovePostHandler 审核通过帖子
+//
+//	@Summary		审核通过接口
+//	@Description	帖子公开并按审核通过的时间发布，操作记录到管理日志
+//	@Tags			版主工具
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string						true	"Bearer JWT"
+//	@Param			id				path	string						true	"帖子ID"
+//	@Param			object			body	models.ParamModeratePost	false	"审核意见"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/mod/reviews/{id}/approve [post]
+func ApprovePostHandler(ctx *gin.Context) {
+	reviewPostHandler(ctx, true)
+}
+
+// RejectPostHandler 审核拒绝帖子
+//
+//	@Summary		审核拒绝接口
+//	@Description	拒绝后帖子被删除，操作记录到管理日志
+//	@Tags			版主工具
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string						true	"Bearer JWT"
+//	@Param			id				path	string						true	"帖子ID"
+//	@Param			object			body	models.ParamModeratePost	false	"拒绝原因"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/mod/reviews/{id}/reject [post]
+func RejectPostHandler(ctx *gin.Context) {
+	reviewPostHandler(ctx, false)
+}
+
+// reviewPostHandler 审核通过或拒绝审核队列中的帖子
+func reviewPostHandler(ctx *gin.Context, approved bool) {
+	actor, postID, ok := getModerationTarget(ctx)
+	if !ok {
+		return
+	}
+	p := new(models.ParamModeratePost)
+	if ctx.Request.ContentLength > 0 && !bindCommunityParam(ctx, p) {
+		return
+	}
+	if err := logic.ReviewPost(actor, postID, approved, p); err != nil {
+		zap.L().Error("logic.ReviewPost failed",
+			zap.Int64("post_id", postID),
+			zap.Bool("approved", approved),
+			zap.Error(err))
+		responseModerationError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// SetUserRoleHandler 修改用户的全局角色
+//
+//	@Summary		修改用户角色接口
+//	@Description	管理员修改用户的全局角色，新角色在该用户下次刷新 Token 后生效
+//	@Tags			管理员
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string				true	"Bearer JWT"
+//	@Param			id				path	string				true	"用户ID"
+//	@Param			object			body	models.ParamSetRole	true	"角色"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/admin/users/{id}/role [put]
+func SetUserRoleHandler(ctx *gin.Context) {
+	actor, userID, ok := getModerationTarget(ctx)
+	if !ok {
+		return
+	}
+	p := new(models.ParamSetRole)
+	if !bindCommunityParam(ctx, p) {
+		return
+	}
+	if err := logic.SetUserRole(actor, userID, p); err != nil {
+		zap.L().Error("logic.SetUserRole failed", zap.Int64("user_id", userID), zap.Error(err))
+		responseModerationError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// getModerationTarget 获取当前用户的权限主体和路径中的操作对象ID，失败时直接返回错误响应
+func getModerationTarget(ctx *gin.Context) (actor *rbac.Principal, id int64, ok bool) {
+	actor, err := getCurrentPrincipal(ctx)
+	if err != nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return nil, 0, false
+	}
+	id, err = strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return nil, 0, false
+	}
+	return actor, id, true
+}
+
+// responseModerationError 版主工具相关接口的错误响应
+func responseModerationError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mysql.ErrorInvalidID):
+		ResponseError(ctx, CodeInvalidParam)
+	case errors.Is(err, mysql.ErrorUserNotExist):
+		ResponseError(ctx, CodeUserNotExist)
+	case errors.Is(err, logic.ErrorPermissionDenied):
+		ResponseError(ctx, CodeNoPermission)
+	case errors.Is(err, logic.ErrorInvalidRole):
+		ResponseError(ctx, CodeInvalidParam)
+	default:
+		ResponseError(ctx, CodeServerBusy)
+	}
+}
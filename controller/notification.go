@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"bluebell/logic"
+	"bluebell/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetNotificationsHandler 通知列表
+//
+//	@Summary		通知列表接口
+//	@Description	分页查询当前用户的通知（回复、赞成票、@），由新到旧排序，同时返回未读数
+//	@Tags			通知相关接口
+//	@Produce		application/json
+//	@Param			Authorization	header	string							true	"Bearer JWT"
+//	@Param			object			query	models.ParamNotificationList	false	"查询参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	_ResponseNotificationList
+//	@Router			/notifications [get]
+func GetNotificationsHandler(ctx *gin.Context) {
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	p := &models.ParamNotificationList{
+		Page: 1,
+		Size: 20,
+	}
+	if err := ctx.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetNotifications with invalid param", zap.Error(err))
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	data, err := logic.GetNotifications(userID, p)
+	if err != nil {
+		zap.L().Error("logic.GetNotifications failed", zap.Int64("user_id", userID), zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, data)
+}
+
+// GetUnreadCountHandler 未读通知数
+//
+//	@Summary		未读通知数接口
+//	@Tags			通知相关接口
+//	@Produce		application/json
+//	@Param			Authorization	header	string	true	"Bearer JWT"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/notifications/unread [get]
+func GetUnreadCountHandler(ctx *gin.Context) {
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	unread, err := logic.GetUnreadCount(userID)
+	if err != nil {
+		zap.L().Error("logic.GetUnreadCount failed", zap.Int64("user_id", userID), zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, gin.H{"unread": unread})
+}
+
+// ReadNotificationsHandler 标记通知为已读
+//
+//	@Summary		标记已读接口
+//	@Description	把指定的通知标记为已读，ids 为空时标记全部，返回剩余的未读数
+//	@Tags			通知相关接口
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string							true	"Bearer JWT"
+//	@Param			object			body	models.ParamReadNotifications	false	"通知ID"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/notifications/read [post]
+func ReadNotificationsHandler(ctx *gin.Context) {
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	p := new(models.ParamReadNotifications)
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(p); err != nil {
+			zap.L().Error("ReadNotifications with invalid param", zap.Error(err))
+			ResponseError(ctx, CodeInvalidParam)
+			return
+		}
+	}
+	unread, err := logic.ReadNotifications(userID, p)
+	if err != nil {
+		zap.L().Error("logic.ReadNotifications failed", zap.Int64("user_id", userID), zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, gin.H{"unread": unread})
+}
@@ -1,8 +1,10 @@
 package controller
 
 import (
+	"bluebell/dao/mysql"
 	"bluebell/logic"
 	"bluebell/models"
+	"errors"
 	"fmt"
 	"strconv"
 
@@ -23,7 +25,6 @@ func CreatePostHandler(ctx *gin.Context) {
 	// 从请求中获取当前用户的ID
 	userID, err := getcurrentUser(ctx)
 	if err != nil {
-		ResponseError(ctx, CodeNeedLogin)
 		return
 	}
 	fmt.Println("userID:", userID)
@@ -31,11 +32,24 @@ func CreatePostHandler(ctx *gin.Context) {
 	// 2. 创建帖子
 	if err := logic.CreatePost(p); err != nil {
 		zap.L().Error("controller.CreatePostHandler: logic.CreatePost() failed", zap.Error(err))
-		ResponseError(ctx, CodeServerBusy)
+		switch {
+		case errors.Is(err, mysql.ErrorInvalidID):
+			ResponseError(ctx, CodeCommunityNotExist)
+		case errors.Is(err, logic.ErrorCommunityArchived):
+			ResponseError(ctx, CodeCommunityArchived)
+		case errors.Is(err, logic.ErrorUserBanned):
+			ResponseError(ctx, CodeUserBanned)
+		case errors.Is(err, logic.ErrorTooManyTags), errors.Is(err, logic.ErrorInvalidTag):
+			ResponseErrorWithMsg(ctx, CodeInvalidParam, err.Error())
+		case errors.Is(err, logic.ErrorContentRejected):
+			ResponseErrorWithMsg(ctx, CodeContentRejected, err.Error())
+		default:
+			ResponseError(ctx, CodeServerBusy)
+		}
 		return
 	}
-	// 3. 返回响应
-	ResponseSuccess(ctx, nil)
+	// 3. 返回响应，status 为待审核时帖子在审核通过前不公开
+	ResponseSuccess(ctx, p)
 }
 
 // GetPostDetailHandler 获取帖子详情
@@ -59,16 +73,142 @@ func GetPostDetailHandler(ctx *gin.Context) {
 		ResponseError(ctx, CodeInvalidParam)
 		return
 	}
-	// 2. 根据ID取出帖子数据
-	data, err := logic.GetPostByID(pid)
+	// 2. 根据ID取出帖子数据，带上当前用户的投票
+	uid := getOptionalUser(ctx)
+	data, err := logic.GetPostByID(uid, pid)
 	if err != nil {
 		zap.L().Error("controller.GetPostDetailHandler: logic.GetPostByID() failed", zap.Error(err))
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(ctx, CodeInvalidParam)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, data)
+}
+
+// UpdatePostHandler 编辑帖子
+//
+//	@Summary		编辑帖子接口
+//	@Description	作者编辑自己的帖子，编辑前的版本会保存到修订历史中；编辑后的内容同样经过内容检查，需要人工审核时帖子在审核通过前不公开
+//	@Tags			帖子相关接口(api分组展示使用的)
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string					true	"Bearer JWT"
+//	@Param			id				path	int						true	"帖子ID"
+//	@Param			object			body	models.ParamUpdatePost	true	"帖子标题和内容"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/post/{id} [put]
+func UpdatePostHandler(ctx *gin.Context) {
+	// 1. 获取参数及参数校验
+	pid, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	p := new(models.ParamUpdatePost)
+	if err := ctx.ShouldBindJSON(p); err != nil {
+		zap.L().Error("controller.UpdatePostHandler: ctx.ShouldBindJSON() failed", zap.Error(err))
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	// 2. 编辑帖子
+	post := &models.Post{
+		ID:      pid,
+		Title:   p.Title,
+		Content: p.Content,
+		Tags:    p.Tags,
+	}
+	if err := logic.UpdatePost(userID, post); err != nil {
+		zap.L().Error("controller.UpdatePostHandler: logic.UpdatePost() failed", zap.Error(err))
+		responsePostWriteError(ctx, err)
+		return
+	}
+	// 3. 返回响应
+	ResponseSuccess(ctx, nil)
+}
+
+// DeletePostHandler 删除帖子
+//
+//	@Summary		删除帖子接口
+//	@Description	作者删除自己的帖子（软删除）
+//	@Tags			帖子相关接口(api分组展示使用的)
+//	@Produce		application/json
+//	@Param			Authorization	header	string	true	"Bearer JWT"
+//	@Param			id				path	int		true	"帖子ID"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/post/{id} [delete]
+func DeletePostHandler(ctx *gin.Context) {
+	pid, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	if err := logic.DeletePost(userID, pid); err != nil {
+		zap.L().Error("controller.DeletePostHandler: logic.DeletePost() failed", zap.Error(err))
+		responsePostWriteError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// GetPostRevisionsHandler 获取帖子的修订历史
+//
+//	@Summary		帖子修订历史接口
+//	@Description	按版本号由新到旧返回帖子每次编辑前的标题和内容
+//	@Tags			帖子相关接口(api分组展示使用的)
+//	@Produce		application/json
+//	@Param			Authorization	header	string	true	"Bearer JWT"
+//	@Param			id				path	int		true	"帖子ID"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	_ResponsePostRevisionList
+//	@Router			/post/{id}/revisions [get]
+func GetPostRevisionsHandler(ctx *gin.Context) {
+	pid, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	data, err := logic.GetPostRevisions(pid)
+	if err != nil {
+		zap.L().Error("controller.GetPostRevisionsHandler: logic.GetPostRevisions() failed", zap.Error(err))
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(ctx, CodeInvalidParam)
+			return
+		}
 		ResponseError(ctx, CodeServerBusy)
 		return
 	}
 	ResponseSuccess(ctx, data)
 }
 
+// responsePostWriteError 编辑/删除帖子失败时的统一响应
+func responsePostWriteError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mysql.ErrorInvalidID):
+		ResponseError(ctx, CodeInvalidParam)
+	case errors.Is(err, logic.ErrorPermissionDenied):
+		ResponseError(ctx, CodeNoPermission)
+	case errors.Is(err, logic.ErrorPostLocked):
+		ResponseError(ctx, CodePostLocked)
+	case errors.Is(err, logic.ErrorTooManyTags), errors.Is(err, logic.ErrorInvalidTag):
+		ResponseErrorWithMsg(ctx, CodeInvalidParam, err.Error())
+	default:
+		ResponseError(ctx, CodeServerBusy)
+	}
+}
+
 // GetPostListHandler 获取帖子列表
 func GetPostListHandler(ctx *gin.Context) {
 	page, size := getPageInfo(ctx)
@@ -88,6 +228,8 @@ func GetPostListHandler(ctx *gin.Context) {
 //
 //	@Summary		升级版帖子列表接口
 //	@Description	可按社区按时间或分数排序查询帖子列表接口
+//	@Description	携带 tag 参数时只查询该标签的帖子，可以与 community_id 同时使用
+//	@Description	携带 cursor 参数（第一页传空）时按游标分页，返回 list、next_cursor 和 has_more
 //	@Tags			帖子相关接口(api分组展示使用的)
 //	@Accept			application/json
 //	@Produce		application/json
@@ -95,6 +237,7 @@ func GetPostListHandler(ctx *gin.Context) {
 //	@Param			object			query	models.ParamPostList	false	"查询参数"
 //	@Security		ApiKeyAuth
 //	@Success		200	{object}	_ResponsePostList
+//	@Success		200	{object}	_ResponsePostPage	"游标分页"
 //	@Router			/posts2 [get]
 func GetPostListHandler2(ctx *gin.Context) {
 	// 1. 获取参数： 时间 or 分数
@@ -113,9 +256,29 @@ func GetPostListHandler2(ctx *gin.Context) {
 		return
 	}
 
-	data, err := logic.GetPostListNew(p)
+	uid := getOptionalUser(ctx)
+	// 携带 cursor 参数时使用游标分页，不携带时保持原来的 page/size 分页
+	if _, ok := ctx.GetQuery("cursor"); ok {
+		page, err := logic.GetPostListByCursor(uid, p)
+		if err != nil {
+			zap.L().Error("logic.GetPostListByCursor failed", zap.Error(err))
+			if errors.Is(err, logic.ErrorInvalidCursor) || errors.Is(err, logic.ErrorInvalidTag) {
+				ResponseError(ctx, CodeInvalidParam)
+				return
+			}
+			ResponseError(ctx, CodeServerBusy)
+			return
+		}
+		ResponseSuccess(ctx, page)
+		return
+	}
+	data, err := logic.GetPostListNew(uid, p)
 	if err != nil {
 		zap.L().Error("logic.GetPostList2 failed", zap.Error(err))
+		if errors.Is(err, logic.ErrorInvalidTag) {
+			ResponseError(ctx, CodeInvalidParam)
+			return
+		}
 		ResponseError(ctx, CodeServerBusy)
 		return
 	}
@@ -123,6 +286,42 @@ func GetPostListHandler2(ctx *gin.Context) {
 	ResponseSuccess(ctx, data)
 }
 
+// GetFeedHandler 首页帖子列表
+//
+//	@Summary		首页帖子列表接口
+//	@Description	当前用户加入的所有社区的帖子，按时间或分数排序
+//	@Tags			帖子相关接口(api分组展示使用的)
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string					true	"Bearer JWT"
+//	@Param			object			query	models.ParamPostList	false	"查询参数，忽略 community_id"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	_ResponsePostList
+//	@Router			/feed [get]
+func GetFeedHandler(ctx *gin.Context) {
+	p := &models.ParamPostList{
+		Page:  1,
+		Size:  10,
+		Order: models.OrderTime,
+	}
+	if err := ctx.ShouldBindQuery(p); err != nil {
+		zap.L().Error("controller.GetFeedHandler ctx.ShouldBindQuery failed", zap.Error(err))
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	data, err := logic.GetFeed(userID, p)
+	if err != nil {
+		zap.L().Error("logic.GetFeed failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, data)
+}
+
 // GetCommunityPostListHander 根据社区查询帖子列表
 //func GetCommunityPostListHander(ctx *gin.Context) {
 //	// 1. 获取参数： 时间 or 分数
@@ -1,13 +1,19 @@
 package controller
 
 import (
+	"bluebell/pkg/jwt"
+	"bluebell/pkg/rbac"
 	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
-const CtxUserIDKey = "userID"
+const (
+	CtxUserIDKey    = "userID"
+	CtxClaimsKey    = "claims"    // 当前请求 Access Token 的声明
+	CtxPrincipalKey = "principal" // RequirePermission 从数据库加载的当前用户角色和管理的社区
+)
 
 var ErrorUserNotLogin = errors.New("用户未登录")
 
@@ -26,6 +32,29 @@ func getcurrentUser(ctx *gin.Context) (userID int64, err error) {
 	return userID, nil
 }
 
+// getOptionalUser 获取当前登录用户的ID，未登录时返回 0，不写入响应
+func getOptionalUser(ctx *gin.Context) int64 {
+	userID, _ := ctx.Value(CtxUserIDKey).(int64)
+	return userID
+}
+
+// getCurrentPrincipal 获取当前登录用户的角色和管理的社区
+// 优先使用 RequirePermission 从数据库加载的最新数据，否则使用 Token 中的声明
+func getCurrentPrincipal(ctx *gin.Context) (*rbac.Principal, error) {
+	if p, ok := ctx.Value(CtxPrincipalKey).(*rbac.Principal); ok {
+		return p, nil
+	}
+	v, ok := ctx.Get(CtxClaimsKey)
+	if !ok {
+		return nil, ErrorUserNotLogin
+	}
+	claims, ok := v.(*jwt.MyClaims)
+	if !ok {
+		return nil, ErrorUserNotLogin
+	}
+	return claims.Principal(), nil
+}
+
 // getPageInfo 获取分页参数
 func getPageInfo(ctx *gin.Context) (int64, int64) {
 	// 获取分页参数
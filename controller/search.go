@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"bluebell/logic"
+	"bluebell/models"
+	"bluebell/pkg/search"
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SearchHandler 搜索帖子
+//
+//	@Summary		搜索帖子接口
+//	@Description	按关键词搜索帖子标题和内容，可按社区和发帖时间过滤，结果按相关度排序
+//	@Tags			帖子相关接口(api分组展示使用的)
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string				true	"Bearer JWT"
+//	@Param			object			query	models.ParamSearch	false	"查询参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	_ResponseSearchResult
+//	@Router			/search [get]
+func SearchHandler(ctx *gin.Context) {
+	// 1. 获取参数及参数校验
+	p := &models.ParamSearch{
+		Page: 1,
+		Size: 10,
+	}
+	if err := ctx.ShouldBindQuery(p); err != nil {
+		zap.L().Error("controller.SearchHandler ctx.ShouldBindQuery failed", zap.Error(err))
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	p.Keyword = strings.TrimSpace(p.Keyword)
+	if p.Keyword == "" || p.Page <= 0 || p.Size <= 0 || (p.End > 0 && p.End < p.Start) {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	// 2. 搜索
+	uid := getOptionalUser(ctx)
+	data, err := logic.SearchPosts(uid, p)
+	if err != nil {
+		zap.L().Error("logic.SearchPosts failed", zap.Error(err))
+		if errors.Is(err, search.ErrorEmptyKeyword) {
+			ResponseError(ctx, CodeInvalidParam)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	// 3. 返回响应
+	ResponseSuccess(ctx, data)
+}
@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"bluebell/logic"
+	"bluebell/models"
+	"bluebell/pkg/jwt"
+	"bluebell/pkg/stream"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	// streamHeartbeat 心跳间隔，避免代理因为连接空闲而断开
+	streamHeartbeat = 25 * time.Second
+	// streamWriteTimeout WebSocket 单条消息的写超时
+	streamWriteTimeout = 10 * time.Second
+	// streamTokenCheck 推送期间检查 Access Token 是否已被注销或吊销的间隔
+	streamTokenCheck = 30 * time.Second
+)
+
+// wsUpgrader 使用默认的 CheckOrigin，浏览器发起的跨域连接会被拒绝
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// StreamHandler 实时推送（SSE）
+//
+//	@Summary		实时推送接口（SSE）
+//	@Description	推送订阅社区的新帖子（post.created）和订阅帖子的最新票数（post.voted），每 25 秒发送一次心跳注释；Access Token 过期或被吊销时发送 expired 事件并断开
+//	@Tags			实时推送接口
+//	@Produce		text/event-stream
+//	@Param			Authorization	header	string				false	"Bearer JWT，也可以用 access_token 查询参数"
+//	@Param			object			query	models.ParamStream	false	"订阅的社区和帖子"
+//	@Security		ApiKeyAuth
+//	@Success		200
+//	@Router			/stream [get]
+func StreamHandler(ctx *gin.Context) {
+	sub, ok := subscribeStream(ctx)
+	if !ok {
+		return
+	}
+	defer logic.UnsubscribeStream(sub)
+	expired, stop := watchStreamToken(ctx)
+	defer stop()
+
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no") // 关闭 nginx 的缓冲
+	ticker := time.NewTicker(streamHeartbeat)
+	defer ticker.Stop()
+	// 先发送一条 ready 事件，客户端据此确认订阅成功
+	ctx.SSEvent("ready", gin.H{})
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case <-expired:
+			// 客户端收到后需要刷新 Token 再重新连接
+			ctx.SSEvent("expired", gin.H{})
+			return false
+		case m, ok := <-sub.Messages():
+			if !ok {
+				return false
+			}
+			ctx.SSEvent(m.Type, m.Data)
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// StreamWSHandler 实时推送（WebSocket）
+//
+//	@Summary		实时推送接口（WebSocket）
+//	@Description	与 SSE 接口推送相同的消息，每条消息为 {"channel","type","data"} 格式的 JSON 文本；Access Token 过期或被吊销时以 1008 关闭连接
+//	@Tags			实时推送接口
+//	@Param			Authorization	header	string				false	"Bearer JWT，也可以用 access_token 查询参数"
+//	@Param			object			query	models.ParamStream	false	"订阅的社区和帖子"
+//	@Security		ApiKeyAuth
+//	@Success		101
+//	@Router			/stream/ws [get]
+func StreamWSHandler(ctx *gin.Context) {
+	sub, ok := subscribeStream(ctx)
+	if !ok {
+		return
+	}
+	defer logic.UnsubscribeStream(sub)
+
+	// Upgrade 失败时已经写入了响应
+	conn, err := wsUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		zap.L().Warn("websocket upgrade failed", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+	expired, stop := watchStreamToken(ctx)
+	defer stop()
+	// 客户端不需要发送消息，读取只是为了处理控制帧和及时发现连接关闭
+	conn.SetReadLimit(512)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(streamHeartbeat)
+	defer ticker.Stop()
+	for {
+		var err error
+		select {
+		case <-closed:
+			return
+		case <-expired:
+			msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "token expired")
+			_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(streamWriteTimeout))
+			return
+		case m, ok := <-sub.Messages():
+			if !ok {
+				return
+			}
+			err = writeStreamMessage(conn, m)
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			err = conn.WriteMessage(websocket.PingMessage, nil)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// subscribeStream 校验参数并订阅，失败时已经写入响应
+func subscribeStream(ctx *gin.Context) (*stream.Subscriber, bool) {
+	p := new(models.ParamStream)
+	if err := ctx.ShouldBindQuery(p); err != nil {
+		zap.L().Error("Stream with invalid param", zap.Error(err))
+		ResponseError(ctx, CodeInvalidParam)
+		return nil, false
+	}
+	sub, err := logic.SubscribeStream(p)
+	if err != nil {
+		if errors.Is(err, logic.ErrorEmptyStream) {
+			ResponseErrorWithMsg(ctx, CodeInvalidParam, err.Error())
+		} else {
+			ResponseError(ctx, CodeInvalidParam)
+		}
+		return nil, false
+	}
+	return sub, true
+}
+
+// writeStreamMessage 以 JSON 文本发送一条推送消息
+func writeStreamMessage(conn *websocket.Conn, m stream.Message) error {
+	b, err := json.Marshal(&m)
+	if err != nil {
+		zap.L().Error("json.Marshal stream message failed", zap.Error(err))
+		return nil
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	return conn.WriteMessage(websocket.TextMessage, b)
+}
+
+// watchStreamToken 返回一个在 Access Token 过期或被注销、吊销时关闭的 channel
+// 推送连接只在建立时经过 JWT 认证，连接期间需要重新检查；推送结束时调用 stop
+func watchStreamToken(ctx *gin.Context) (expired <-chan struct{}, stop func()) {
+	ch := make(chan struct{})
+	done := make(chan struct{})
+	v, _ := ctx.Get(CtxClaimsKey)
+	claims, ok := v.(*jwt.MyClaims)
+	if !ok || claims == nil {
+		close(ch)
+		return ch, func() {}
+	}
+	go func() {
+		defer close(ch)
+		var expire <-chan time.Time
+		if claims.ExpiresAt != nil {
+			timer := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+			defer timer.Stop()
+			expire = timer.C
+		}
+		ticker := time.NewTicker(streamTokenCheck)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-expire:
+				return
+			case <-ticker.C:
+				revoked, err := logic.IsAccessTokenRevoked(claims)
+				if err != nil {
+					// Redis 暂时不可用时不断开连接，下次再检查
+					zap.L().Warn("logic.IsAccessTokenRevoked failed", zap.Error(err))
+					continue
+				}
+				if revoked {
+					return
+				}
+			}
+		}
+	}()
+	return ch, func() { close(done) }
+}
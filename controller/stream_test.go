@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"bluebell/pkg/jwt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchStreamToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newCtx := func(claims *jwt.MyClaims) *gin.Context {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		if claims != nil {
+			ctx.Set(CtxClaimsKey, claims)
+		}
+		return ctx
+	}
+	isClosed := func(ch <-chan struct{}, wait time.Duration) bool {
+		select {
+		case <-ch:
+			return true
+		case <-time.After(wait):
+			return false
+		}
+	}
+
+	// 没有 Token 声明时立即关闭
+	expired, stop := watchStreamToken(newCtx(nil))
+	assert.True(t, isClosed(expired, time.Second))
+	stop()
+
+	// Token 到期时关闭
+	claims := &jwt.MyClaims{RegisteredClaims: jwtlib.RegisteredClaims{
+		ExpiresAt: jwtlib.NewNumericDate(time.Now().Add(50 * time.Millisecond)),
+	}}
+	expired, stop = watchStreamToken(newCtx(claims))
+	assert.True(t, isClosed(expired, time.Second))
+	stop()
+
+	// 推送结束后停止检查
+	claims = &jwt.MyClaims{RegisteredClaims: jwtlib.RegisteredClaims{
+		ExpiresAt: jwtlib.NewNumericDate(time.Now().Add(time.Hour)),
+	}}
+	expired, stop = watchStreamToken(newCtx(claims))
+	assert.False(t, isClosed(expired, 20*time.Millisecond))
+	stop()
+	assert.True(t, isClosed(expired, time.Second))
+}
@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"bluebell/logic"
+	"bluebell/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// TrendingTagsHandler 热门标签
+//
+//	@Summary		热门标签接口
+//	@Description	按最近 24 小时内新帖子的使用次数排序的标签
+//	@Tags			帖子相关接口(api分组展示使用的)
+//	@Produce		application/json
+//	@Param			Authorization	header	string						true	"Bearer JWT"
+//	@Param			object			query	models.ParamTrendingTags	false	"查询参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	_ResponseTrendingTags
+//	@Router			/tags/trending [get]
+func TrendingTagsHandler(ctx *gin.Context) {
+	p := &models.ParamTrendingTags{Size: 20}
+	if err := ctx.ShouldBindQuery(p); err != nil {
+		zap.L().Error("TrendingTags with invalid param", zap.Error(err))
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	data, err := logic.GetTrendingTags(p)
+	if err != nil {
+		zap.L().Error("logic.GetTrendingTags failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, data)
+}
@@ -4,8 +4,12 @@ import (
 	"bluebell/dao/mysql"
 	"bluebell/logic"
 	"bluebell/models"
+	"bluebell/pkg/jwt"
 	"errors"
 	"fmt"
+	"math"
+	"net/http"
+	"strconv"
 
 	"github.com/go-playground/validator/v10"
 
@@ -47,6 +51,10 @@ func SignupHandler(ctx *gin.Context) {
 			ResponseError(ctx, CodeUserExist)
 			return
 		}
+		if errors.Is(err, mysql.ErrorEmailExist) {
+			ResponseError(ctx, CodeEmailExist)
+			return
+		}
 		ResponseError(ctx, CodeServerBusy)
 		return
 	}
@@ -66,17 +74,25 @@ func LoginHandler(ctx *gin.Context) {
 			return
 		}
 		ResponseErrorWithMsg(ctx, CodeInvalidParam, removeTagStruct(err.Translate(trans)))
+		return
 	}
 	// 2. 业务处理 --> 调用 logic 函数
-	user, err := logic.Login(p)
+	user, err := logic.Login(p, ctx.ClientIP())
 	if err != nil {
 		zap.L().Error("Logic.Login failed", zap.String("username: ", p.Username),
 			zap.Error(err))
-		if errors.Is(err, mysql.ErrorUserNotExist) {
-			ResponseError(ctx, CodeUserNotExist)
+		var locked *logic.LoginLockedError
+		if errors.As(err, &locked) {
+			ctx.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(locked.RetryAfter.Seconds())), 10))
+			ResponseError(ctx, CodeLoginLocked)
 			return
 		}
-		ResponseError(ctx, CodeInvalidPassword)
+		if errors.Is(err, mysql.ErrorUserNotExist) || errors.Is(err, mysql.ErrorInvalidPassword) {
+			// 用户不存在和密码错误返回相同的响应，避免枚举用户名
+			ResponseError(ctx, CodeInvalidPassword)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
 		return
 	}
 	// 3. 返回响应
@@ -87,3 +103,366 @@ func LoginHandler(ctx *gin.Context) {
 		"refresh_token": user.RefreshToken,
 	})
 }
+
+// UnlockLoginHandler 管理员解除用户名和/或IP的登录锁定
+func UnlockLoginHandler(ctx *gin.Context) {
+	p := new(models.ParamUnlockLogin)
+	if err := ctx.ShouldBindJSON(p); err != nil {
+		zap.L().Error("UnlockLogin with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(ctx, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMsg(ctx, CodeInvalidParam, removeTagStruct(errs.Translate(trans)))
+		return
+	}
+	if err := logic.UnlockLogin(p); err != nil {
+		zap.L().Error("logic.UnlockLogin failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// RefreshTokenHandler 使用 Refresh Token 换取新的 Access Token 和 Refresh Token
+func RefreshTokenHandler(ctx *gin.Context) {
+	p := new(models.ParamRefreshToken)
+	if err := ctx.ShouldBindJSON(p); err != nil {
+		zap.L().Error("RefreshToken with invalid param", zap.Error(err))
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	user, err := logic.RefreshToken(p)
+	if err != nil {
+		zap.L().Error("logic.RefreshToken failed", zap.Error(err))
+		if errors.Is(err, logic.ErrorTokenRevoked) {
+			ResponseError(ctx, CodeInvalidToken)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, gin.H{
+		"user_id":       fmt.Sprintf("%d", user.UserID),
+		"user_name":     user.Username,
+		"access_token":  user.AccessToken,
+		"refresh_token": user.RefreshToken,
+	})
+}
+
+// LogoutHandler 注销当前登录
+func LogoutHandler(ctx *gin.Context) {
+	v, ok := ctx.Get(CtxClaimsKey)
+	claims, _ := v.(*jwt.MyClaims)
+	if !ok || claims == nil {
+		ResponseError(ctx, CodeNeedLogin)
+		return
+	}
+	if err := logic.Logout(claims); err != nil {
+		zap.L().Error("logic.Logout failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// JWKSHandler 公开 JWT 校验用的公钥，供其他服务校验 bluebell 签发的 Token
+// 按 RFC 7517 的格式直接返回，不使用统一的响应结构
+func JWKSHandler(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, jwt.PublicKeys())
+}
+
+// GetUserProfileHandler 用户主页
+//
+//	@Summary		用户主页接口
+//	@Description	查询用户的公开资料、收到的投票和发布的帖子，帖子按 order 排序
+//	@Tags			用户相关接口
+//	@Produce		application/json
+//	@Param			Authorization	header	string					true	"Bearer JWT"
+//	@Param			id				path	string					true	"用户ID"
+//	@Param			object			query	models.ParamPostList	false	"帖子分页参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	_ResponseUserProfile
+//	@Router			/user/{id} [get]
+func GetUserProfileHandler(ctx *gin.Context) {
+	userID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	p := &models.ParamPostList{
+		Page:  1,
+		Size:  10,
+		Order: models.OrderTime,
+	}
+	if err := ctx.ShouldBindQuery(p); err != nil {
+		zap.L().Error("GetUserProfile with invalid param", zap.Error(err))
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	uid := getOptionalUser(ctx)
+	data, err := logic.GetUserProfile(uid, userID, p)
+	if err != nil {
+		zap.L().Error("logic.GetUserProfile failed", zap.Int64("user_id", userID), zap.Error(err))
+		responseUserError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, data)
+}
+
+// GetMyProfileHandler 查询当前用户的资料
+//
+//	@Summary		个人资料接口
+//	@Description	查询当前用户的资料，包括邮箱、帖子数和收到的投票
+//	@Tags			用户相关接口
+//	@Produce		application/json
+//	@Param			Authorization	header	string	true	"Bearer JWT"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	_ResponseMyProfile
+//	@Router			/me [get]
+func GetMyProfileHandler(ctx *gin.Context) {
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	data, err := logic.GetMyProfile(userID)
+	if err != nil {
+		zap.L().Error("logic.GetMyProfile failed", zap.Int64("user_id", userID), zap.Error(err))
+		responseUserError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, data)
+}
+
+// UpdateProfileHandler 修改当前用户的资料
+//
+//	@Summary		修改个人资料接口
+//	@Description	修改邮箱、性别、简介和头像，只修改请求中携带的字段
+//	@Tags			用户相关接口
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string						true	"Bearer JWT"
+//	@Param			object			body	models.ParamUpdateProfile	true	"个人资料"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/me [patch]
+func UpdateProfileHandler(ctx *gin.Context) {
+	p := new(models.ParamUpdateProfile)
+	if err := ctx.ShouldBindJSON(p); err != nil {
+		zap.L().Error("UpdateProfile with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(ctx, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMsg(ctx, CodeInvalidParam, removeTagStruct(errs.Translate(trans)))
+		return
+	}
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	data, err := logic.UpdateProfile(userID, p)
+	if err != nil {
+		zap.L().Error("logic.UpdateProfile failed", zap.Int64("user_id", userID), zap.Error(err))
+		responseUserError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, data)
+}
+
+// ChangePasswordHandler 修改当前用户的密码
+//
+//	@Summary		修改密码接口
+//	@Description	校验旧密码后设置新密码，旧密码错误计入登录失败次数；成功后所有设备都需要重新登录
+//	@Tags			用户相关接口
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			Authorization	header	string						true	"Bearer JWT"
+//	@Param			object			body	models.ParamChangePassword	true	"新旧密码"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/me/password [put]
+func ChangePasswordHandler(ctx *gin.Context) {
+	p := new(models.ParamChangePassword)
+	if err := ctx.ShouldBindJSON(p); err != nil {
+		zap.L().Error("ChangePassword with invalid param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(ctx, CodeInvalidParam)
+			return
+		}
+		ResponseErrorWithMsg(ctx, CodeInvalidParam, removeTagStruct(errs.Translate(trans)))
+		return
+	}
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	if err := logic.ChangePassword(userID, p, ctx.ClientIP()); err != nil {
+		zap.L().Error("logic.ChangePassword failed", zap.Int64("user_id", userID), zap.Error(err))
+		responseUserError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// responseUserError 用户资料相关接口的错误响应
+func responseUserError(ctx *gin.Context, err error) {
+	var locked *logic.LoginLockedError
+	switch {
+	case errors.As(err, &locked):
+		ctx.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(locked.RetryAfter.Seconds())), 10))
+		ResponseError(ctx, CodeLoginLocked)
+	case errors.Is(err, mysql.ErrorUserNotExist):
+		ResponseError(ctx, CodeUserNotExist)
+	case errors.Is(err, mysql.ErrorInvalidPassword):
+		ResponseError(ctx, CodeInvalidPassword)
+	case errors.Is(err, mysql.ErrorEmailExist):
+		ResponseError(ctx, CodeEmailExist)
+	case errors.Is(err, logic.ErrorInvalidAvatarURL), errors.Is(err, logic.ErrorEmailNotSet):
+		ResponseErrorWithMsg(ctx, CodeInvalidParam, err.Error())
+	case errors.Is(err, logic.ErrorInvalidEmailToken):
+		ResponseError(ctx, CodeInvalidEmailToken)
+	default:
+		ResponseError(ctx, CodeServerBusy)
+	}
+}
+
+// KarmaLeaderboardHandler karma 排行榜
+//
+//	@Summary		karma 排行榜接口
+//	@Description	按用户的帖子收到的赞成票减反对票排序，指定 community_id 时查询社区排行榜
+//	@Tags			用户相关接口
+//	@Produce		application/json
+//	@Param			Authorization	header	string							true	"Bearer JWT"
+//	@Param			object			query	models.ParamKarmaLeaderboard	false	"查询参数"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	_ResponseKarmaLeaderboard
+//	@Router			/leaderboard/karma [get]
+func KarmaLeaderboardHandler(ctx *gin.Context) {
+	p := &models.ParamKarmaLeaderboard{Size: 20}
+	if err := ctx.ShouldBindQuery(p); err != nil {
+		zap.L().Error("KarmaLeaderboard with invalid param", zap.Error(err))
+		ResponseError(ctx, CodeInvalidParam)
+		return
+	}
+	data, err := logic.GetKarmaLeaderboard(p)
+	if err != nil {
+		zap.L().Error("logic.GetKarmaLeaderboard failed", zap.Error(err))
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			ResponseError(ctx, CodeCommunityNotExist)
+			return
+		}
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, data)
+}
+
+// bindUserParam 解析并校验请求体，失败时直接返回错误响应
+func bindUserParam(ctx *gin.Context, p interface{}) bool {
+	if err := ctx.ShouldBindJSON(p); err != nil {
+		zap.L().Error("controller: invalid user param", zap.Error(err))
+		errs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			ResponseError(ctx, CodeInvalidParam)
+			return false
+		}
+		ResponseErrorWithMsg(ctx, CodeInvalidParam, removeTagStruct(errs.Translate(trans)))
+		return false
+	}
+	return true
+}
+
+// VerifyEmailHandler 验证邮箱
+//
+//	@Summary		验证邮箱接口
+//	@Description	使用验证邮件链接中的令牌验证邮箱，令牌只能使用一次
+//	@Tags			用户相关接口
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			object	body	models.ParamEmailToken	true	"令牌"
+//	@Success		200	{object}	ResponseData
+//	@Router			/auth/email/verify [post]
+func VerifyEmailHandler(ctx *gin.Context) {
+	p := new(models.ParamEmailToken)
+	if !bindUserParam(ctx, p) {
+		return
+	}
+	if err := logic.VerifyEmail(p); err != nil {
+		zap.L().Error("logic.VerifyEmail failed", zap.Error(err))
+		responseUserError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// ResendVerifyEmailHandler 重新发送邮箱验证邮件
+//
+//	@Summary		重新发送验证邮件接口
+//	@Tags			用户相关接口
+//	@Produce		application/json
+//	@Param			Authorization	header	string	true	"Bearer JWT"
+//	@Security		ApiKeyAuth
+//	@Success		200	{object}	ResponseData
+//	@Router			/me/email/verify [post]
+func ResendVerifyEmailHandler(ctx *gin.Context) {
+	userID, err := getcurrentUser(ctx)
+	if err != nil {
+		return
+	}
+	if err := logic.ResendVerifyEmail(userID); err != nil {
+		zap.L().Error("logic.ResendVerifyEmail failed", zap.Int64("user_id", userID), zap.Error(err))
+		responseUserError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// ForgotPasswordHandler 发送重置密码邮件
+//
+//	@Summary		忘记密码接口
+//	@Description	向注册邮箱发送重置密码链接，邮箱未注册时同样返回成功
+//	@Tags			用户相关接口
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			object	body	models.ParamForgotPassword	true	"注册邮箱"
+//	@Success		200	{object}	ResponseData
+//	@Router			/auth/password/forgot [post]
+func ForgotPasswordHandler(ctx *gin.Context) {
+	p := new(models.ParamForgotPassword)
+	if !bindUserParam(ctx, p) {
+		return
+	}
+	if err := logic.ForgotPassword(p); err != nil {
+		zap.L().Error("logic.ForgotPassword failed", zap.Error(err))
+		ResponseError(ctx, CodeServerBusy)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
+
+// ResetPasswordHandler 重置密码
+//
+//	@Summary		重置密码接口
+//	@Description	使用重置密码邮件链接中的令牌设置新密码，令牌只能使用一次
+//	@Tags			用户相关接口
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			object	body	models.ParamResetPassword	true	"令牌和新密码"
+//	@Success		200	{object}	ResponseData
+//	@Router			/auth/password/reset [post]
+func ResetPasswordHandler(ctx *gin.Context) {
+	p := new(models.ParamResetPassword)
+	if !bindUserParam(ctx, p) {
+		return
+	}
+	if err := logic.ResetPassword(p); err != nil {
+		zap.L().Error("logic.ResetPassword failed", zap.Error(err))
+		responseUserError(ctx, err)
+		return
+	}
+	ResponseSuccess(ctx, nil)
+}
@@ -1,8 +1,11 @@
 package controller
 
 import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
 	"bluebell/logic"
 	"bluebell/models"
+	"errors"
 	"fmt"
 
 	"go.uber.org/zap"
@@ -37,15 +40,24 @@ func PostVoteHandler(ctx *gin.Context) {
 	// 获取用户ID
 	uid, err := getcurrentUser(ctx)
 	if err != nil {
-		ResponseError(ctx, CodeNeedLogin)
 		return
 	}
 	// 2. 投票的逻辑处理
-	if err := logic.VoteForPost(uid, p); err != nil {
+	res, err := logic.VoteForPost(uid, p)
+	if err != nil {
 		zap.L().Error("logic.VoteForPost failed", zap.Error(err))
-		ResponseError(ctx, CodeServerBusy)
+		switch {
+		case errors.Is(err, redis.ErrorVoteTimeExpire):
+			ResponseError(ctx, CodeVoteTimeExpire)
+		case errors.Is(err, redis.ErrorVoteRepeat):
+			ResponseError(ctx, CodeVoteRepeat)
+		case errors.Is(err, mysql.ErrorInvalidID):
+			ResponseError(ctx, CodeInvalidParam)
+		default:
+			ResponseError(ctx, CodeServerBusy)
+		}
 		return
 	}
-	// 3. 返回响应
-	ResponseSuccess(ctx, nil)
+	// 3. 返回响应，带上帖子最新的票数
+	ResponseSuccess(ctx, res)
 }
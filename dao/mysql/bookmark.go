@@ -0,0 +1,61 @@
+package mysql
+
+import (
+	"bluebell/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GetBookmarks 查询用户收藏的所有帖子
+func GetBookmarks(userID int64) (data []*models.Bookmark, err error) {
+	sqlStr := "select user_id, post_id, save_time from bookmark where user_id = ?"
+	data = make([]*models.Bookmark, 0)
+	err = db.Select(&data, sqlStr, userID)
+	return
+}
+
+// PersistBookmarks 用 Redis 中的收藏覆盖 MySQL 中的收藏，data 的 key 为用户ID，在一个事务中执行
+// Redis 中已经取消的收藏从 MySQL 中删除，data 中用户的收藏为空时删除该用户的所有收藏
+func PersistBookmarks(data map[int64][]*models.Bookmark) (err error) {
+	if len(data) == 0 {
+		return nil
+	}
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	for userID, bookmarks := range data {
+		if len(bookmarks) == 0 {
+			if _, err = tx.Exec("delete from bookmark where user_id = ?", userID); err != nil {
+				return err
+			}
+			continue
+		}
+		postIDs := make([]int64, 0, len(bookmarks))
+		for _, b := range bookmarks {
+			postIDs = append(postIDs, b.PostID)
+		}
+		var query string
+		var args []interface{}
+		query, args, err = sqlx.In("delete from bookmark where user_id = ? and post_id not in (?)", userID, postIDs)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(tx.Rebind(query), args...); err != nil {
+			return err
+		}
+		_, err = tx.NamedExec(`
+        INSERT INTO bookmark (user_id, post_id, save_time)
+        VALUES (:user_id, :post_id, :save_time)
+        ON DUPLICATE KEY UPDATE save_time = VALUES(save_time)`, bookmarks)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
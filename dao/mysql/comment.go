@@ -0,0 +1,85 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateComment 创建一条新评论
+func CreateComment(c *models.Comment) (err error) {
+	sqlStr := "insert into comment(comment_id, post_id, parent_id, root_id, author_id, content) values(?,?,?,?,?,?)"
+	_, err = db.Exec(sqlStr, c.ID, c.PostID, c.ParentID, c.RootID, c.AuthorID, c.Content)
+	return
+}
+
+// GetCommentByID 根据评论ID查询评论
+func GetCommentByID(id int64) (c *models.Comment, err error) {
+	c = new(models.Comment)
+	sqlStr := `select comment_id, post_id, parent_id, root_id, author_id, status, content, create_time
+	from comment where comment_id = ?`
+	if err = db.Get(c, sqlStr, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrorInvalidID
+		}
+		return nil, err
+	}
+	return
+}
+
+// GetRootCommentList 分页查询帖子下的顶层评论，由旧到新排序
+func GetRootCommentList(postID, page, size int64) (data []*models.Comment, err error) {
+	sqlStr := `select comment_id, post_id, parent_id, root_id, author_id, status, content, create_time
+	from comment
+	where post_id = ? and root_id = 0
+	order by create_time, id
+	limit ?,?`
+	data = make([]*models.Comment, 0, size)
+	err = db.Select(&data, sqlStr, postID, (page-1)*size, size)
+	return
+}
+
+// GetCommentsByRootIDs 查询给定楼层下的所有回复
+func GetCommentsByRootIDs(rootIDs []int64) (data []*models.Comment, err error) {
+	if len(rootIDs) == 0 {
+		return
+	}
+	sqlStr := `select comment_id, post_id, parent_id, root_id, author_id, status, content, create_time
+	from comment
+	where root_id in (?)
+	order by create_time, id`
+	query, args, err := sqlx.In(sqlStr, rootIDs)
+	if err != nil {
+		return nil, err
+	}
+	query = db.Rebind(query)
+	err = db.Select(&data, query, args...)
+	return
+}
+
+// GetCommentNumByPostIDs 批量查询帖子的评论数
+func GetCommentNumByPostIDs(postIDs []int64) (data map[int64]int64, err error) {
+	data = make(map[int64]int64, len(postIDs))
+	if len(postIDs) == 0 {
+		return
+	}
+	sqlStr := `select post_id, count(comment_id) as num
+	from comment
+	where post_id in (?)
+	group by post_id`
+	query, args, err := sqlx.In(sqlStr, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	query = db.Rebind(query)
+	var nums []*models.PostCommentNum
+	if err = db.Select(&nums, query, args...); err != nil {
+		return nil, err
+	}
+	for _, n := range nums {
+		data[n.PostID] = n.Num
+	}
+	return
+}
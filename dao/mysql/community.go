@@ -5,13 +5,18 @@ import (
 	"database/sql"
 	"errors"
 
+	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
 
+// communityDetailColumns 查询社区详情的字段
+const communityDetailColumns = `community_id, community_name, slug, introduction, icon_url, rules, status, creator_id, create_time`
+
+// GetCommunityList 查询所有未归档的社区
 func GetCommunityList() (data []*models.Community, err error) {
 	// 查询所有的社区（community_id, community_name)
-	sqlStr := "select community_id, community_name from community"
-	if err = db.Select(&data, sqlStr); err != nil {
+	sqlStr := "select community_id, community_name, slug from community where status = ?"
+	if err = db.Select(&data, sqlStr, models.CommunityStatusNormal); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			zap.L().Warn("there is no data in community")
 			err = nil
@@ -22,13 +27,202 @@ func GetCommunityList() (data []*models.Community, err error) {
 
 // GetCommunityDetailByID 根据ID查询指定的社区详情
 func GetCommunityDetailByID(id int64) (cd *models.CommunityDetail, err error) {
-	sqlStr := "select community_id, community_name, introduction, create_time from community where community_id = ?"
+	sqlStr := "select " + communityDetailColumns + " from community where community_id = ?"
 	cd = new(models.CommunityDetail)
 	if err := db.Get(cd, sqlStr, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			zap.L().Warn("there is no data in community")
 			err = ErrorInvalidID
 		}
+		return cd, err
+	}
+	return cd, nil
+}
+
+// GetCommunityDetailsByIDs 根据ID批量查询社区详情
+func GetCommunityDetailsByIDs(ids []int64) (data map[int64]*models.CommunityDetail, err error) {
+	data = make(map[int64]*models.CommunityDetail, len(ids))
+	if len(ids) == 0 {
+		return
+	}
+	sqlStr := "select " + communityDetailColumns + " from community where community_id in (?)"
+	query, args, err := sqlx.In(sqlStr, ids)
+	if err != nil {
+		return nil, err
+	}
+	query = db.Rebind(query)
+	var communities []*models.CommunityDetail
+	if err = db.Select(&communities, query, args...); err != nil {
+		return nil, err
+	}
+	for _, c := range communities {
+		data[c.ID] = c
+	}
+	return
+}
+
+// CreateCommunity 创建社区，社区ID在已有的最大ID上加一，创建者同时写入 community_member
+// 查重和分配ID都在事务中进行，锁住最大的社区ID使并发创建串行执行；唯一索引冲突同样返回 ErrorCommunityExist
+func CreateCommunity(c *models.CommunityDetail) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+		if isDuplicateEntry(err) {
+			err = ErrorCommunityExist
+		}
+	}()
+	var maxID int64
+	sqlStr := "select ifnull(max(community_id), 0) from community for update"
+	if err = tx.Get(&maxID, sqlStr); err != nil {
+		return err
+	}
+	var count int64
+	sqlStr = "select count(community_id) from community where community_name = ? or slug = ?"
+	if err = tx.Get(&count, sqlStr, c.Name, c.Slug); err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrorCommunityExist
+	}
+	sqlStr = `insert into community(community_id, community_name, slug, introduction, icon_url, rules, creator_id)
+	values(?, ?, ?, ?, ?, ?, ?)`
+	if _, err = tx.Exec(sqlStr, maxID+1, c.Name, c.Slug, c.Intro, c.IconURL, c.Rules, c.CreatorID); err != nil {
+		return err
+	}
+	if err = tx.Get(c, "select "+communityDetailColumns+" from community where slug = ?", c.Slug); err != nil {
+		return err
+	}
+	sqlStr = "insert into community_member(community_id, user_id, role) values(?, ?, ?)"
+	if _, err = tx.Exec(sqlStr, c.ID, c.CreatorID, models.CommunityRoleCreator); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateCommunity 修改社区的名称、简介、图标和发帖规则，社区不存在时返回 ErrorInvalidID
+func UpdateCommunity(c *models.CommunityDetail) (err error) {
+	var count int64
+	sqlStr := "select count(community_id) from community where community_name = ? and community_id != ?"
+	if err = db.Get(&count, sqlStr, c.Name, c.ID); err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrorCommunityExist
+	}
+	sqlStr = `update community set community_name = ?, introduction = ?, icon_url = ?, rules = ?
+	where community_id = ?`
+	ret, err := db.Exec(sqlStr, c.Name, c.Intro, c.IconURL, c.Rules, c.ID)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			return ErrorCommunityExist
+		}
+		return err
+	}
+	// 内容没有变化时 RowsAffected 也为 0，需要再查一次
+	if n, err := ret.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	if err = db.Get(&count, "select count(community_id) from community where community_id = ?", c.ID); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrorInvalidID
+	}
+	return nil
+}
+
+// ArchiveCommunity 归档社区，归档后不能再发帖；社区不存在或已归档时返回 ErrorInvalidID
+func ArchiveCommunity(id int64) (err error) {
+	sqlStr := "update community set status = ? where community_id = ? and status = ?"
+	ret, err := db.Exec(sqlStr, models.CommunityStatusArchived, id, models.CommunityStatusNormal)
+	if err != nil {
+		return err
+	}
+	n, err := ret.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrorInvalidID
+	}
+	return
+}
+
+// GetCommunityMemberRole 查询用户在社区中的角色，不是创建者或版主时返回 0
+func GetCommunityMemberRole(communityID, userID int64) (role int8, err error) {
+	sqlStr := "select role from community_member where community_id = ? and user_id = ?"
+	if err = db.Get(&role, sqlStr, communityID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
 	}
-	return cd, err
+	return
+}
+
+// GetCommunityMembers 查询社区的创建者和版主
+func GetCommunityMembers(communityID int64) (data []*models.CommunityMember, err error) {
+	sqlStr := `select community_id, user_id, role, create_time
+	from community_member
+	where community_id = ?
+	order by role desc, create_time`
+	err = db.Select(&data, sqlStr, communityID)
+	return
+}
+
+// AddCommunityModerator 设置版主，已经是版主或创建者时不做修改
+func AddCommunityModerator(communityID, userID int64) (err error) {
+	sqlStr := "insert ignore into community_member(community_id, user_id, role) values(?, ?, ?)"
+	_, err = db.Exec(sqlStr, communityID, userID, models.CommunityRoleModerator)
+	return
+}
+
+// RemoveCommunityModerator 取消版主，不会删除创建者
+func RemoveCommunityModerator(communityID, userID int64) (err error) {
+	sqlStr := "delete from community_member where community_id = ? and user_id = ? and role = ?"
+	_, err = db.Exec(sqlStr, communityID, userID, models.CommunityRoleModerator)
+	return
+}
+
+// JoinCommunity 加入社区，已经加入时不做修改
+func JoinCommunity(userID, communityID int64) (err error) {
+	sqlStr := "insert ignore into community_subscription(user_id, community_id) values(?, ?)"
+	_, err = db.Exec(sqlStr, userID, communityID)
+	return
+}
+
+// LeaveCommunity 退出社区
+func LeaveCommunity(userID, communityID int64) (err error) {
+	sqlStr := "delete from community_subscription where user_id = ? and community_id = ?"
+	_, err = db.Exec(sqlStr, userID, communityID)
+	return
+}
+
+// GetSubscribedCommunityIDs 查询用户加入的社区ID
+func GetSubscribedCommunityIDs(userID int64) (ids []int64, err error) {
+	sqlStr := "select community_id from community_subscription where user_id = ?"
+	err = db.Select(&ids, sqlStr, userID)
+	return
+}
+
+// GetSubscribedCommunities 查询用户加入的社区，已归档的社区也会返回
+func GetSubscribedCommunities(userID int64) (data []*models.Community, err error) {
+	sqlStr := `select c.community_id, c.community_name, c.slug
+	from community_subscription s
+	join community c on c.community_id = s.community_id
+	where s.user_id = ?
+	order by s.create_time desc`
+	err = db.Select(&data, sqlStr, userID)
+	return
+}
+
+// GetModeratedCommunityIDs 查询用户担任创建者或版主的社区ID
+func GetModeratedCommunityIDs(userID int64) (ids []int64, err error) {
+	sqlStr := "select community_id from community_member where user_id = ? and role >= ?"
+	err = db.Select(&ids, sqlStr, userID, models.CommunityRoleModerator)
+	return
 }
@@ -1,10 +1,25 @@
 package mysql
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
 
 var (
 	ErrorUserExist       = errors.New("用户已存在")
 	ErrorUserNotExist    = errors.New("用户不存在")
 	ErrorInvalidPassword = errors.New("用户名或密码错误")
 	ErrorInvalidID       = errors.New("无效的ID")
+	ErrorCommunityExist  = errors.New("社区名称或短名称已存在")
+	ErrorEmailExist      = errors.New("邮箱已被使用")
 )
+
+// errDuplicateEntry 违反唯一索引时 MySQL 返回的错误码
+const errDuplicateEntry = 1062
+
+// isDuplicateEntry 判断错误是否为违反唯一索引
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errDuplicateEntry
+}
@@ -0,0 +1,214 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 版主和管理员的操作，每个操作与它的管理日志在同一个事务中写入
+
+// withModerationLog 在事务中执行 fn，成功后写入管理日志
+func withModerationLog(l *models.ModerationLog, fn func(tx *sqlx.Tx) error) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if err = fn(tx); err != nil {
+		return err
+	}
+	sqlStr := `insert into moderation_log(actor_id, action, community_id, target_type, target_id, reason)
+	values(?, ?, ?, ?, ?, ?)`
+	if _, err = tx.Exec(sqlStr, l.ActorID, l.Action, l.CommunityID, l.TargetType, l.TargetID, l.Reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// execAffected 执行更新语句，没有行被修改时返回 ErrorInvalidID
+func execAffected(tx *sqlx.Tx, sqlStr string, args ...interface{}) error {
+	ret, err := tx.Exec(sqlStr, args...)
+	if err != nil {
+		return err
+	}
+	n, err := ret.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrorInvalidID
+	}
+	return nil
+}
+
+// RemovePost 版主删除帖子（软删除）
+func RemovePost(l *models.ModerationLog) error {
+	return withModerationLog(l, func(tx *sqlx.Tx) error {
+		sqlStr := "update post set status = ?, pinned = 0 where post_id = ? and status = ?"
+		return execAffected(tx, sqlStr, models.PostStatusDeleted, l.TargetID, models.PostStatusNormal)
+	})
+}
+
+// SetPostLocked 锁定或解锁帖子
+func SetPostLocked(l *models.ModerationLog, locked bool) error {
+	return withModerationLog(l, func(tx *sqlx.Tx) error {
+		sqlStr := "update post set locked = ? where post_id = ? and status = ?"
+		_, err := tx.Exec(sqlStr, locked, l.TargetID, models.PostStatusNormal)
+		return err
+	})
+}
+
+// SetPostPinned 置顶或取消置顶帖子
+func SetPostPinned(l *models.ModerationLog, pinned bool) error {
+	return withModerationLog(l, func(tx *sqlx.Tx) error {
+		sqlStr := "update post set pinned = ? where post_id = ? and status = ?"
+		_, err := tx.Exec(sqlStr, pinned, l.TargetID, models.PostStatusNormal)
+		return err
+	})
+}
+
+// BanUser 在社区中封禁用户，已被封禁时更新原因和解封时间
+func BanUser(l *models.ModerationLog, expire *time.Time) error {
+	return withModerationLog(l, func(tx *sqlx.Tx) error {
+		sqlStr := `insert into community_ban(community_id, user_id, moderator_id, reason, expire_time)
+		values(?, ?, ?, ?, ?)
+		on duplicate key update moderator_id = values(moderator_id), reason = values(reason),
+		expire_time = values(expire_time), create_time = now()`
+		_, err := tx.Exec(sqlStr, l.CommunityID, l.TargetID, l.ActorID, l.Reason, expire)
+		return err
+	})
+}
+
+// UnbanUser 解除社区封禁
+func UnbanUser(l *models.ModerationLog) error {
+	return withModerationLog(l, func(tx *sqlx.Tx) error {
+		sqlStr := "delete from community_ban where community_id = ? and user_id = ?"
+		return execAffected(tx, sqlStr, l.CommunityID, l.TargetID)
+	})
+}
+
+// SetUserRole 修改用户的全局角色
+func SetUserRole(l *models.ModerationLog, role string) error {
+	return withModerationLog(l, func(tx *sqlx.Tx) error {
+		var n int64
+		if err := tx.Get(&n, "select count(user_id) from user where user_id = ?", l.TargetID); err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrorUserNotExist
+		}
+		_, err := tx.Exec("update user set role = ? where user_id = ?", role, l.TargetID)
+		return err
+	})
+}
+
+// IsUserBanned 用户是否在社区中被封禁且未到解封时间
+func IsUserBanned(communityID, userID int64) (banned bool, err error) {
+	var expire sql.NullTime
+	sqlStr := "select expire_time from community_ban where community_id = ? and user_id = ?"
+	if err = db.Get(&expire, sqlStr, communityID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return !expire.Valid || expire.Time.After(time.Now()), nil
+}
+
+// GetModerationLogs 分页查询管理日志，由新到旧排序，communityIDs 为空时查询全部
+func GetModerationLogs(communityIDs []int64, page, size int64) (data []*models.ModerationLog, err error) {
+	data = make([]*models.ModerationLog, 0)
+	sqlStr := `select id, actor_id, action, community_id, target_type, target_id, reason, create_time
+	from moderation_log`
+	args := []interface{}{}
+	if len(communityIDs) > 0 {
+		sqlStr += " where community_id in (?)"
+		args = append(args, communityIDs)
+	}
+	sqlStr += " order by id desc limit ?, ?"
+	args = append(args, (page-1)*size, size)
+	query, args, err := sqlx.In(sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Select(&data, db.Rebind(query), args...)
+	return
+}
+
+// GetPostReview 查询帖子的待审核记录，不在审核队列中时返回 ErrorInvalidID
+func GetPostReview(postID int64) (data *models.PostReview, err error) {
+	data = new(models.PostReview)
+	sqlStr := `select r.post_id, r.community_id, r.author_id, r.kind, r.reasons, r.status, r.reviewer_id,
+	r.create_time, r.review_time, p.title, p.content
+	from post_review r join post p on p.post_id = r.post_id
+	where r.post_id = ? and r.status = ?`
+	if err = db.Get(data, sqlStr, postID, models.ReviewStatusPending); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrorInvalidID
+		}
+		return nil, err
+	}
+	return
+}
+
+// GetPostReviews 分页查询审核队列中待审核的帖子，先进入队列的在前；communityIDs 为空时不限社区
+func GetPostReviews(communityIDs []int64, page, size int64) (data []*models.PostReview, err error) {
+	data = make([]*models.PostReview, 0)
+	sqlStr := `select r.post_id, r.community_id, r.author_id, r.kind, r.reasons, r.status, r.reviewer_id,
+	r.create_time, r.review_time, p.title, p.content
+	from post_review r join post p on p.post_id = r.post_id
+	where r.status = ?`
+	args := []interface{}{models.ReviewStatusPending}
+	if len(communityIDs) > 0 {
+		sqlStr += " and r.community_id in (?)"
+		args = append(args, communityIDs)
+	}
+	sqlStr += " order by r.id limit ?, ?"
+	args = append(args, (page-1)*size, size)
+	query, args, err := sqlx.In(sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Select(&data, db.Rebind(query), args...)
+	return
+}
+
+// ReviewPost 审核帖子
+// 新帖子通过时公开帖子，发布时间改为审核通过的时间，拒绝时删除帖子；
+// 编辑后的帖子通过时恢复公开，发布时间不变，拒绝时恢复为编辑前的版本
+func ReviewPost(l *models.ModerationLog, kind int8, approved bool) error {
+	return withModerationLog(l, func(tx *sqlx.Tx) error {
+		reviewStatus := models.ReviewStatusApproved
+		postSQL := "update post set status = ?, create_time = now() where post_id = ? and status = ?"
+		postArgs := []interface{}{models.PostStatusNormal, l.TargetID, models.PostStatusPending}
+		switch {
+		case kind == models.ReviewKindEdit && approved:
+			postSQL = "update post set status = ? where post_id = ? and status = ?"
+		case kind == models.ReviewKindEdit:
+			reviewStatus = models.ReviewStatusRejected
+			postSQL = `update post p join (
+				select title, content from post_revision where post_id = ? order by revision desc limit 1
+			) r set p.title = r.title, p.content = r.content, p.status = ?
+			where p.post_id = ? and p.status = ?`
+			postArgs = append([]interface{}{l.TargetID}, postArgs...)
+		case !approved:
+			reviewStatus = models.ReviewStatusRejected
+			postSQL = "update post set status = ? where post_id = ? and status = ?"
+			postArgs[0] = models.PostStatusDeleted
+		}
+		sqlStr := `update post_review set status = ?, reviewer_id = ?, review_time = now()
+		where post_id = ? and status = ?`
+		if err := execAffected(tx, sqlStr, reviewStatus, l.ActorID, l.TargetID, models.ReviewStatusPending); err != nil {
+			return err
+		}
+		return execAffected(tx, postSQL, postArgs...)
+	})
+}
@@ -0,0 +1,76 @@
+package mysql
+
+import (
+	"bluebell/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateNotification 保存通知，同一个对象的同类通知已存在时忽略，返回是否新增
+func CreateNotification(n *models.Notification) (created bool, err error) {
+	sqlStr := `insert ignore into notification(user_id, actor_id, type, post_id, comment_id, content)
+	values(?, ?, ?, ?, ?, ?)`
+	ret, err := db.Exec(sqlStr, n.UserID, n.ActorID, n.Type, n.PostID, n.CommentID, n.Content)
+	if err != nil {
+		return false, err
+	}
+	rows, err := ret.RowsAffected()
+	return rows > 0, err
+}
+
+// GetNotifications 分页查询用户的通知，由新到旧排序
+func GetNotifications(userID int64, unreadOnly bool, page, size int64) (data []*models.Notification, err error) {
+	sqlStr := `select n.id, n.user_id, n.actor_id, coalesce(u.username, '') as actor_name, n.type, n.post_id,
+	n.comment_id, n.content, n.is_read, n.create_time
+	from notification n
+	left join user u on u.user_id = n.actor_id
+	where n.user_id = ?`
+	args := []interface{}{userID}
+	if unreadOnly {
+		sqlStr += " and n.is_read = 0"
+	}
+	sqlStr += " order by n.id desc limit ?, ?"
+	args = append(args, (page-1)*size, size)
+	data = make([]*models.Notification, 0)
+	err = db.Select(&data, sqlStr, args...)
+	return
+}
+
+// CountUnreadNotifications 统计用户的未读通知数
+func CountUnreadNotifications(userID int64) (count int64, err error) {
+	sqlStr := "select count(id) from notification where user_id = ? and is_read = 0"
+	err = db.Get(&count, sqlStr, userID)
+	return
+}
+
+// MarkNotificationsRead 把用户的通知标记为已读，ids 为空时标记全部，返回实际修改的条数
+func MarkNotificationsRead(userID int64, ids []int64) (n int64, err error) {
+	sqlStr := "update notification set is_read = 1 where user_id = ? and is_read = 0"
+	args := []interface{}{userID}
+	if len(ids) > 0 {
+		sqlStr += " and id in (?)"
+		args = append(args, ids)
+	}
+	query, args, err := sqlx.In(sqlStr, args...)
+	if err != nil {
+		return 0, err
+	}
+	ret, err := db.Exec(db.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return ret.RowsAffected()
+}
+
+// GetUsersByUsernames 根据用户名批量查询用户
+func GetUsersByUsernames(names []string) (data []*models.User, err error) {
+	if len(names) == 0 {
+		return
+	}
+	query, args, err := sqlx.In("select user_id, username from user where username in (?)", names)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Select(&data, db.Rebind(query), args...)
+	return
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 	"time"
 )
@@ -60,7 +61,7 @@ func PersistPostOnce(postScores []*models.PostScore, postVotes []*models.PostVot
 		_, err = tx.NamedExec(`
         INSERT INTO post_scores (post_id, score)
         VALUES (:post_id, :score)
-        ON DUPLICATE KEY UPDATE score = VALUES(score)`, postScores)
+        ON DUPLICATE KEY UPDATE score = IF(archived = 1, score, VALUES(score))`, postScores) // 已归档的分数不再更新
 		if err != nil {
 			zap.L().Error("failed to insert post scores", zap.Error(err))
 			_ = tx.Rollback() // 回滚事务
@@ -89,3 +90,95 @@ func PersistPostOnce(postScores []*models.PostScore, postVotes []*models.PostVot
 	zap.L().Info("successfully persisted post scores and post votes")
 	return nil
 }
+
+// ArchivePosts 归档投票期已结束的帖子：写入全部投票记录，并冻结最终分数和票数，在一个事务中执行
+func ArchivePosts(scores []*models.ArchivedPostScore, votes []*models.PostVoteData) (err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if len(votes) > 0 {
+		_, err = tx.NamedExec(`
+        INSERT INTO post_votes (post_id, user_id, direction)
+        VALUES (:post_id, :user_id, :direction)
+        ON DUPLICATE KEY UPDATE direction = VALUES(direction)`, votes)
+		if err != nil {
+			return err
+		}
+	}
+	if len(scores) > 0 {
+		_, err = tx.NamedExec(`
+        INSERT INTO post_scores (post_id, score, up_votes, down_votes, archived)
+        VALUES (:post_id, :score, :up_votes, :down_votes, 1)
+        ON DUPLICATE KEY UPDATE score = VALUES(score), up_votes = VALUES(up_votes),
+            down_votes = VALUES(down_votes), archived = 1`, scores)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetArchivedPostScores 批量查询已归档帖子的最终分数和票数
+func GetArchivedPostScores(ids []int64) (data map[int64]*models.ArchivedPostScore, err error) {
+	data = make(map[int64]*models.ArchivedPostScore, len(ids))
+	if len(ids) == 0 {
+		return
+	}
+	sqlStr := `select post_id, score, up_votes, down_votes
+	from post_scores
+	where post_id in (?) and archived = 1`
+	query, args, err := sqlx.In(sqlStr, ids)
+	if err != nil {
+		return nil, err
+	}
+	query = db.Rebind(query)
+	var scores []*models.ArchivedPostScore
+	if err = db.Select(&scores, query, args...); err != nil {
+		return nil, err
+	}
+	for _, s := range scores {
+		data[s.ID] = s
+	}
+	return
+}
+
+// GetUserPostVotes 批量查询用户对已归档帖子的投票方向
+func GetUserPostVotes(userID int64, postIDs []int64) (data map[int64]int8, err error) {
+	data = make(map[int64]int8, len(postIDs))
+	if len(postIDs) == 0 {
+		return
+	}
+	sqlStr := `select post_id, user_id, direction
+	from post_votes
+	where user_id = ? and post_id in (?)`
+	query, args, err := sqlx.In(sqlStr, userID, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	query = db.Rebind(query)
+	var votes []*models.PostVoteData
+	if err = db.Select(&votes, query, args...); err != nil {
+		return nil, err
+	}
+	for _, v := range votes {
+		data[v.PostID] = int8(v.Direction)
+	}
+	return
+}
+
+// GetUserKarma 按作者和社区统计帖子收到的赞成票减反对票，已删除的帖子收到的投票同样计入
+func GetUserKarma() (data []*models.UserKarma, err error) {
+	sqlStr := `select p.author_id as user_id, p.community_id, sum(v.direction) as karma
+	from post_votes v
+	join post p on p.post_id = v.post_id
+	group by p.author_id, p.community_id`
+	err = db.Select(&data, sqlStr)
+	return
+}
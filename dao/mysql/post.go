@@ -11,21 +11,53 @@ import (
 	"go.uber.org/zap"
 )
 
-// CreatePost 创建一个新帖子
-func CreatePost(p *models.Post) (err error) {
-	sqlStr := "insert into Post(post_id, author_id,  community_id, title, content) values(?,?,?,?,?)" // status, create_time
-	_, err = db.Exec(sqlStr, p.ID, p.AuthorID, p.CommunityID, p.Title, p.Content)
+// postColumns 查询帖子的字段
+const postColumns = "post_id, author_id, community_id, status, locked, pinned, title, content, create_time"
+
+// CreatePost 创建一个新帖子，帖子和标签在同一个事务中保存
+// review 不为空时帖子进入审核队列，帖子的状态应为待审核
+func CreatePost(p *models.Post, review *models.PostReview) (err error) {
+	tx, err := db.Beginx()
 	if err != nil {
 		return err
 	}
-	return
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	sqlStr := "insert into Post(post_id, author_id,  community_id, status, title, content) values(?,?,?,?,?,?)" // create_time
+	_, err = tx.Exec(sqlStr, p.ID, p.AuthorID, p.CommunityID, p.Status, p.Title, p.Content)
+	if err != nil {
+		return err
+	}
+	if err = insertPostTags(tx, p.ID, p.Tags); err != nil {
+		return err
+	}
+	if review != nil {
+		review.PostID, review.CommunityID, review.AuthorID = p.ID, p.CommunityID, p.AuthorID
+		if err = savePostReview(tx, review); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// savePostReview 在事务中把帖子加入审核队列，帖子以前审核过时覆盖原来的审核记录
+func savePostReview(tx *sqlx.Tx, review *models.PostReview) error {
+	sqlStr := `insert into post_review(post_id, community_id, author_id, kind, reasons) values(?, ?, ?, ?, ?)
+	on duplicate key update community_id = values(community_id), kind = values(kind), reasons = values(reasons),
+	status = ?, reviewer_id = 0, create_time = now(), review_time = null`
+	_, err := tx.Exec(sqlStr, review.PostID, review.CommunityID, review.AuthorID, review.Kind, review.Reasons,
+		models.ReviewStatusPending)
+	return err
 }
 
 // GetPostByID 根据帖子ID查询指定帖子的详细信息
 func GetPostByID(id int64) (data *models.Post, err error) {
 	data = new(models.Post)
-	sqlStr := `select post_id, author_id, community_id, title, content, create_time from post where post_id = ?`
-	if err = db.Get(data, sqlStr, id); err != nil {
+	sqlStr := "select " + postColumns + " from post where post_id = ? and status = ?"
+	if err = db.Get(data, sqlStr, id, models.PostStatusNormal); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			zap.L().Warn("there is no data in post")
 			err = nil
@@ -36,24 +68,25 @@ func GetPostByID(id int64) (data *models.Post, err error) {
 
 // GetPostList 获取帖子列表 帖子由新到旧排序
 func GetPostList(page, size int64) (data []*models.Post, err error) {
-	sqlStr := `select post_id, author_id, community_id, title, content, create_time 
-	from post 
+	sqlStr := "select " + postColumns + `
+	from post
+	where status = ?
 	ORDER BY create_time
 	DESC   # 默认ASC
     limit ?,?`
 	data = make([]*models.Post, 0, 2)
-	err = db.Select(&data, sqlStr, (page-1)*size, size)
+	err = db.Select(&data, sqlStr, models.PostStatusNormal, (page-1)*size, size)
 	return
 }
 
 // GetPostListByIDs 根据给定的ID列表查询帖子数据
 func GetPostListByIDs(ids []string) (data []*models.Post, err error) {
 	zap.L().Debug("GetPostListByIDs", zap.Strings("ids", ids))
-	sqlStr := `select post_id, author_id, community_id, title, content, create_time
+	sqlStr := "select " + postColumns + `
 			   from post
-			   where post_id in (?)
+			   where post_id in (?) and status = ?
 			   order by FIND_IN_SET(post_id, ?)`
-	query, args, err := sqlx.In(sqlStr, ids, strings.Join(ids, ","))
+	query, args, err := sqlx.In(sqlStr, ids, models.PostStatusNormal, strings.Join(ids, ","))
 	if err != nil {
 		return nil, err
 	}
@@ -62,3 +95,101 @@ func GetPostListByIDs(ids []string) (data []*models.Post, err error) {
 	err = db.Select(&data, query, args...)
 	return
 }
+
+// GetPostIDsByAuthor 查询用户发布的所有帖子ID
+func GetPostIDsByAuthor(authorID int64) (ids []string, err error) {
+	sqlStr := "select post_id from post where author_id = ? and status = ?"
+	err = db.Select(&ids, sqlStr, authorID, models.PostStatusNormal)
+	return
+}
+
+// UpdatePost 编辑帖子标题、内容和标签，在同一个事务中把编辑前的版本保存为修订记录，返回编辑前的标签
+// review 不为空时帖子重新进入审核队列，帖子的状态应为待审核
+func UpdatePost(p *models.Post, editorID int64, review *models.PostReview) (oldTags []string, err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// 1. 锁定帖子并取出编辑前的标题和内容
+	old := new(models.Post)
+	sqlStr := "select post_id, title, content from post where post_id = ? and status = ? for update"
+	if err = tx.Get(old, sqlStr, p.ID, models.PostStatusNormal); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrorInvalidID
+		}
+		return nil, err
+	}
+	// 2. 保存编辑前的快照，版本号在帖子行锁的保护下递增
+	var revision int32
+	sqlStr = "select coalesce(max(revision), 0) from post_revision where post_id = ?"
+	if err = tx.Get(&revision, sqlStr, p.ID); err != nil {
+		return nil, err
+	}
+	sqlStr = "insert into post_revision(post_id, revision, editor_id, title, content) values(?,?,?,?,?)"
+	if _, err = tx.Exec(sqlStr, p.ID, revision+1, editorID, old.Title, old.Content); err != nil {
+		return nil, err
+	}
+	// 3. 更新帖子，未通过内容检查时加入审核队列
+	sqlStr = "update post set title = ?, content = ?, status = ? where post_id = ?"
+	if _, err = tx.Exec(sqlStr, p.Title, p.Content, p.Status, p.ID); err != nil {
+		return nil, err
+	}
+	if review != nil {
+		if err = savePostReview(tx, review); err != nil {
+			return nil, err
+		}
+	}
+	// 4. p.Tags 不为 nil 时替换帖子的标签，返回替换前的标签用于同步 Redis
+	// 旧标签在同一个事务中加锁读取，并发编辑时不会基于过期的标签计算增减
+	if p.Tags != nil {
+		oldTags = make([]string, 0)
+		sqlStr = "select tag from post_tag where post_id = ? order by tag for update"
+		if err = tx.Select(&oldTags, sqlStr, p.ID); err != nil {
+			return nil, err
+		}
+		if _, err = tx.Exec("delete from post_tag where post_id = ?", p.ID); err != nil {
+			return nil, err
+		}
+		if err = insertPostTags(tx, p.ID, p.Tags); err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return oldTags, nil
+}
+
+// DeletePost 软删除帖子，只修改帖子状态
+func DeletePost(id int64) (err error) {
+	sqlStr := "update post set status = ? where post_id = ? and status = ?"
+	ret, err := db.Exec(sqlStr, models.PostStatusDeleted, id, models.PostStatusNormal)
+	if err != nil {
+		return err
+	}
+	n, err := ret.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrorInvalidID
+	}
+	return
+}
+
+// GetPostRevisions 查询帖子的修订历史，由新到旧排序
+func GetPostRevisions(postID int64) (data []*models.PostRevision, err error) {
+	sqlStr := `select post_id, revision, editor_id, title, content, create_time
+	from post_revision
+	where post_id = ?
+	order by revision desc`
+	data = make([]*models.PostRevision, 0)
+	err = db.Select(&data, sqlStr, postID)
+	return
+}
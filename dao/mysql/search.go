@@ -0,0 +1,58 @@
+package mysql
+
+import (
+	"bluebell/models"
+	"bluebell/pkg/search"
+	"strings"
+)
+
+// FulltextIndex 基于 post 表 FULLTEXT 索引（ngram 解析器）的搜索实现
+// 索引由 MySQL 随帖子的增删改自动维护，Index 和 Remove 不需要做任何事；已删除的帖子通过 status 过滤。
+type FulltextIndex struct{}
+
+func NewFulltextIndex() *FulltextIndex {
+	return &FulltextIndex{}
+}
+
+func (FulltextIndex) Index(*search.Document) error { return nil }
+
+func (FulltextIndex) Remove(int64) error { return nil }
+
+func (FulltextIndex) Search(q *search.Query) (*search.Result, error) {
+	keyword := strings.TrimSpace(q.Keyword)
+	if keyword == "" {
+		return nil, search.ErrorEmptyKeyword
+	}
+	where := ` from post
+	where match(title, content) against(? in natural language mode) and status = ?`
+	args := []interface{}{keyword, models.PostStatusNormal}
+	if q.CommunityID != 0 {
+		where += " and community_id = ?"
+		args = append(args, q.CommunityID)
+	}
+	if !q.Start.IsZero() {
+		where += " and create_time >= ?"
+		args = append(args, q.Start)
+	}
+	if !q.End.IsZero() {
+		where += " and create_time <= ?"
+		args = append(args, q.End)
+	}
+
+	res := new(search.Result)
+	if err := db.Get(&res.Total, "select count(post_id)"+where, args...); err != nil {
+		return nil, err
+	}
+	if res.Total == 0 {
+		return res, nil
+	}
+	// order by 中的 match() 与 where 中的完全相同，MySQL 只计算一次相关度
+	sqlStr := `select post_id` + where + `
+	order by match(title, content) against(? in natural language mode) desc, post_id desc
+	limit ?, ?`
+	args = append(args, keyword, q.Offset, q.Limit)
+	if err := db.Select(&res.IDs, sqlStr, args...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
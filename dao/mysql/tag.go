@@ -0,0 +1,47 @@
+package mysql
+
+import (
+	"bluebell/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// insertPostTags 在事务中保存帖子的标签
+func insertPostTags(tx *sqlx.Tx, postID int64, tags []string) error {
+	sqlStr := "insert into post_tag(post_id, tag) values(?,?)"
+	for _, tag := range tags {
+		if _, err := tx.Exec(sqlStr, postID, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPostTags 查询帖子的标签
+func GetPostTags(postID int64) (tags []string, err error) {
+	sqlStr := "select tag from post_tag where post_id = ? order by tag"
+	tags = make([]string, 0)
+	err = db.Select(&tags, sqlStr, postID)
+	return
+}
+
+// GetTagsByPostIDs 批量查询帖子的标签
+func GetTagsByPostIDs(postIDs []int64) (data map[int64][]string, err error) {
+	data = make(map[int64][]string, len(postIDs))
+	if len(postIDs) == 0 {
+		return
+	}
+	sqlStr := "select post_id, tag from post_tag where post_id in (?) order by post_id, tag"
+	query, args, err := sqlx.In(sqlStr, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	var rows []*models.PostTag
+	if err = db.Select(&rows, db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		data[row.PostID] = append(data[row.PostID], row.Tag)
+	}
+	return
+}
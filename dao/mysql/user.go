@@ -2,17 +2,18 @@ package mysql
 
 import (
 	"bluebell/models"
-	"crypto/md5"
+	"bluebell/pkg/password"
 	"database/sql"
-	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
 
+	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
 
 // 把每一步数据库操作封装成函数，等待logic层调用
 
-const secret = "WMGray"
-
 // CheckUserExist 检查指定用户名的用户是否存在
 func CheckUserExist(username string) (err error) {
 	sqlStr := "select count(user_id) from user where username = ?"
@@ -29,25 +30,23 @@ func CheckUserExist(username string) (err error) {
 // InsertUser 向数据库中插入一条新的用户记录
 func InsertUser(user *models.User) (err error) {
 	// 密码加密
-	user.Password = encryptPassword(user.Password)
+	user.Password, err = password.Hash(user.Password)
+	if err != nil {
+		return err
+	}
 
 	// 执行SQL语句
-	sqlStr := "insert into user(user_id, username, password) values(?, ?, ?)"
-	_, err = db.Exec(sqlStr, user.UserID, user.Username, user.Password)
+	sqlStr := "insert into user(user_id, username, password, email) values(?, ?, ?, ?)"
+	_, err = db.Exec(sqlStr, user.UserID, user.Username, user.Password, user.Email)
 	return
 }
 
-func encryptPassword(password string) string {
-	h := md5.New()
-	h.Write([]byte(secret))
-	return hex.EncodeToString(h.Sum([]byte(password)))
-}
-
 func Login(user *models.User) (err error) {
 	opassword := user.Password
-	sqlStr := "select user_id, username, password from user where username = ?"
+	sqlStr := "select user_id, username, password, role from user where username = ?"
 	err = db.Get(user, sqlStr, user.Username)
 	if err == sql.ErrNoRows {
+		password.VerifyDummy(opassword)
 		return ErrorUserNotExist
 	}
 	if err != nil {
@@ -55,21 +54,183 @@ func Login(user *models.User) (err error) {
 		return err
 	}
 	// 判断密码是否正确
-	//fmt.Println("user.Password", user.Password)
-	if encryptPassword(opassword) != user.Password {
-		//fmt.Println(user.Password, opassword)
+	ok, needRehash, err := password.Verify(opassword, user.Password)
+	if err != nil {
+		return err
+	}
+	if !ok {
 		return ErrorInvalidPassword
 	}
-	return
+	// 旧版哈希或参数过时的哈希，登录成功后透明地重新哈希
+	if needRehash {
+		if err := updatePasswordHash(user.UserID, user.Password, opassword); err != nil {
+			zap.L().Error("mysql.updatePasswordHash failed",
+				zap.Int64("user_id", user.UserID),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// updatePasswordHash 使用当前默认算法重新哈希密码
+// 带上旧哈希作为条件，避免覆盖并发修改过的密码
+func updatePasswordHash(userID int64, oldHash, plain string) error {
+	newHash, err := password.Hash(plain)
+	if err != nil {
+		return err
+	}
+	sqlStr := "update user set password = ? where user_id = ? and password = ?"
+	_, err = db.Exec(sqlStr, newHash, userID, oldHash)
+	return err
 }
 
 // GetUserByID 根据用户ID查询用户信息
 func GetUserByID(id int64) (user *models.User, err error) {
 	user = new(models.User)
-	sqlStr := "select user_id, username from user where user_id = ?"
+	sqlStr := "select user_id, username, role from user where user_id = ?"
 	if err = db.Get(user, sqlStr, id); err != nil {
 		zap.L().Error("mysql.GetUserID() failed. ", zap.Error(err))
 		return
 	}
 	return
 }
+
+// GetUsersByIDs 根据用户ID批量查询用户信息
+func GetUsersByIDs(ids []int64) (data map[int64]*models.User, err error) {
+	data = make(map[int64]*models.User, len(ids))
+	if len(ids) == 0 {
+		return
+	}
+	sqlStr := "select user_id, username, avatar_url from user where user_id in (?)"
+	query, args, err := sqlx.In(sqlStr, ids)
+	if err != nil {
+		return nil, err
+	}
+	query = db.Rebind(query)
+	var users []*models.User
+	if err = db.Select(&users, query, args...); err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		data[u.UserID] = u
+	}
+	return
+}
+
+// GetUserProfile 查询用户资料
+func GetUserProfile(id int64) (data *models.UserProfile, err error) {
+	data = new(models.UserProfile)
+	sqlStr := `select user_id, username, coalesce(email, '') as email, email_verified, gender, bio, avatar_url, create_time
+	from user where user_id = ?`
+	if err = db.Get(data, sqlStr, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrorUserNotExist
+		}
+		return nil, err
+	}
+	return
+}
+
+// GetUserCreateTime 查询用户的注册时间，没有记录注册时间时返回零值
+func GetUserCreateTime(id int64) (time.Time, error) {
+	var t sql.NullTime
+	sqlStr := "select create_time from user where user_id = ?"
+	if err := db.Get(&t, sqlStr, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, ErrorUserNotExist
+		}
+		return time.Time{}, err
+	}
+	return t.Time, nil
+}
+
+// CheckEmailExist 检查邮箱是否已被其他用户使用
+func CheckEmailExist(email string, userID int64) (err error) {
+	sqlStr := "select count(user_id) from user where email = ? and user_id != ?"
+	var count int64
+	if err = db.Get(&count, sqlStr, email, userID); err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrorEmailExist
+	}
+	return
+}
+
+// UpdateUserProfile 修改用户资料，只更新参数中不为空的字段
+// 邮箱为空字符串表示清除邮箱，保存为 NULL，避免多个用户的空邮箱违反唯一索引
+func UpdateUserProfile(userID int64, p *models.ParamUpdateProfile) (err error) {
+	sets := make([]string, 0, 5)
+	args := make([]interface{}, 0, 6)
+	if p.Email != nil {
+		// 单表 update 按顺序赋值，先比较旧邮箱，修改邮箱后需要重新验证
+		sets = append(sets, "email_verified = if(email <=> nullif(?, ''), email_verified, 0)", "email = nullif(?, '')")
+		args = append(args, *p.Email, *p.Email)
+	}
+	if p.Gender != nil {
+		sets = append(sets, "gender = ?")
+		args = append(args, *p.Gender)
+	}
+	if p.Bio != nil {
+		sets = append(sets, "bio = ?")
+		args = append(args, *p.Bio)
+	}
+	if p.AvatarURL != nil {
+		sets = append(sets, "avatar_url = ?")
+		args = append(args, *p.AvatarURL)
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	sqlStr := "update user set " + strings.Join(sets, ", ") + " where user_id = ?"
+	if _, err = db.Exec(sqlStr, append(args, userID)...); isDuplicateEntry(err) {
+		// 并发修改时 CheckEmailExist 可能都通过，以唯一索引为准
+		return ErrorEmailExist
+	}
+	return
+}
+
+// UpdatePassword 使用当前默认算法哈希新密码并保存
+func UpdatePassword(userID int64, plain string) error {
+	hash, err := password.Hash(plain)
+	if err != nil {
+		return err
+	}
+	sqlStr := "update user set password = ? where user_id = ?"
+	_, err = db.Exec(sqlStr, hash, userID)
+	return err
+}
+
+// GetUserByEmail 根据邮箱查询用户
+func GetUserByEmail(email string) (user *models.User, err error) {
+	user = new(models.User)
+	sqlStr := "select user_id, username, email from user where email = ?"
+	if err = db.Get(user, sqlStr, email); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrorUserNotExist
+		}
+		return nil, err
+	}
+	return
+}
+
+// SetEmailVerified 把用户的邮箱标记为已验证，邮箱已被修改时返回 ErrorInvalidID
+func SetEmailVerified(userID int64, email string) error {
+	sqlStr := "update user set email_verified = 1 where user_id = ? and email = ?"
+	ret, err := db.Exec(sqlStr, userID, email)
+	if err != nil {
+		return err
+	}
+	// 已经验证过时 RowsAffected 也为 0，需要再查一次
+	if n, err := ret.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	var count int64
+	if err = db.Get(&count, "select count(user_id) from user where user_id = ? and email = ?", userID, email); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrorInvalidID
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package redis
+
+import (
+	"bluebell/models"
+	"context"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 收藏
+// 用户的收藏保存在按收藏时间排序的 zset 中，有变化的用户记在 KeyBookmarkDirtySet，由持久化任务写入 MySQL。
+// Redis 数据丢失后，第一次访问时从 MySQL 重新加载；加载标记保证用户取消全部收藏后不会从 MySQL 恢复尚未持久化的旧数据。
+// 持久化时先把用户从 KeyBookmarkDirtySet 移到 KeyBookmarkPersistSet，写入 MySQL 后才移除，
+// 进程崩溃或写入失败时这些用户留在 KeyBookmarkPersistSet 中，下次持久化优先处理。
+
+// claimDirtyUsersScript 有上次未完成的用户时直接返回，否则从待持久化集合移出最多 ARGV[1] 个用户
+var claimDirtyUsersScript = redis.NewScript(`
+local ids = redis.call('SMEMBERS', KEYS[2])
+if #ids > 0 then
+	return ids
+end
+ids = redis.call('SPOP', KEYS[1], ARGV[1])
+if #ids > 0 then
+	redis.call('SADD', KEYS[2], unpack(ids))
+end
+return ids
+`)
+
+func getUserBookmarkKey(userID int64) string {
+	return getRedisKey(KeyUserBookmarkZSetPF + strconv.FormatInt(userID, 10))
+}
+
+func getUserBookmarkLoadedKey(userID int64) string {
+	return getRedisKey(KeyUserBookmarkLoadedPF + strconv.FormatInt(userID, 10))
+}
+
+// BookmarksLoaded 用户的收藏是否已经加载到 Redis
+func BookmarksLoaded(userID int64) (bool, error) {
+	n, err := client.Exists(ctx, getUserBookmarkLoadedKey(userID)).Result()
+	return n > 0, err
+}
+
+// LoadBookmarks 把 MySQL 中的收藏加载到 Redis，已经存在的收藏不覆盖
+func LoadBookmarks(userID int64, data []*models.Bookmark) error {
+	pipeline := client.TxPipeline()
+	if len(data) > 0 {
+		members := make([]redis.Z, 0, len(data))
+		for _, b := range data {
+			members = append(members, redis.Z{Score: float64(b.SaveTime), Member: b.PostID})
+		}
+		pipeline.ZAddNX(ctx, getUserBookmarkKey(userID), members...)
+	}
+	pipeline.Set(ctx, getUserBookmarkLoadedKey(userID), 1, 0)
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+// AddBookmark 收藏帖子，已经收藏过时不修改收藏时间，返回是否新增
+func AddBookmark(userID, postID, saveTime int64) (bool, error) {
+	pipeline := client.TxPipeline()
+	cmd := pipeline.ZAddNX(ctx, getUserBookmarkKey(userID), redis.Z{Score: float64(saveTime), Member: postID})
+	pipeline.SAdd(ctx, getRedisKey(KeyBookmarkDirtySet), userID)
+	if _, err := pipeline.Exec(ctx); err != nil {
+		return false, err
+	}
+	return cmd.Val() > 0, nil
+}
+
+// RemoveBookmark 取消收藏，返回是否删除
+func RemoveBookmark(userID, postID int64) (bool, error) {
+	pipeline := client.TxPipeline()
+	cmd := pipeline.ZRem(ctx, getUserBookmarkKey(userID), postID)
+	pipeline.SAdd(ctx, getRedisKey(KeyBookmarkDirtySet), userID)
+	if _, err := pipeline.Exec(ctx); err != nil {
+		return false, err
+	}
+	return cmd.Val() > 0, nil
+}
+
+// GetBookmarkIDsAfter 按收藏时间从新到旧查询排在游标 (saveTime, postID) 之后的 count 个帖子，postID 为空时从第一个开始
+func GetBookmarkIDsAfter(userID int64, saveTime float64, postID string, count int64) (ids []string, saveTimes []float64, err error) {
+	return getIDsAfter(getUserBookmarkKey(userID), saveTime, postID, count)
+}
+
+// ClaimDirtyBookmarkUsers 取出一批需要持久化收藏的用户，优先返回上次没有完成的用户，否则取出最多 count 个收藏有变化的用户
+// 写入 MySQL 后需要调用 FinishBookmarkUsers，在此之前用户有新的变化时会重新加入待持久化集合
+func ClaimDirtyBookmarkUsers(ctx context.Context, count int) ([]int64, error) {
+	keys := []string{getRedisKey(KeyBookmarkDirtySet), getRedisKey(KeyBookmarkPersistSet)}
+	res, err := claimDirtyUsersScript.Run(ctx, client, keys, count).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]int64, 0, len(res))
+	for _, id := range res {
+		userIDs = append(userIDs, mustParseInt64(id))
+	}
+	return userIDs, nil
+}
+
+// FinishBookmarkUsers 用户的收藏已写入 MySQL，从正在持久化的集合中移除
+func FinishBookmarkUsers(ctx context.Context, userIDs []int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	members := make([]interface{}, 0, len(userIDs))
+	for _, id := range userIDs {
+		members = append(members, id)
+	}
+	return client.SRem(ctx, getRedisKey(KeyBookmarkPersistSet), members...).Err()
+}
+
+// FetchBookmarks 批量查询用户的所有收藏，key 为用户ID
+// 没有加载标记的用户（Redis 中的数据已丢失）不返回，避免用不完整的数据覆盖 MySQL
+func FetchBookmarks(ctx context.Context, userIDs []int64) (map[int64][]*models.Bookmark, error) {
+	pipeline := client.Pipeline()
+	cmds := make(map[int64]*redis.ZSliceCmd, len(userIDs))
+	loaded := make(map[int64]*redis.IntCmd, len(userIDs))
+	for _, userID := range userIDs {
+		cmds[userID] = pipeline.ZRangeWithScores(ctx, getUserBookmarkKey(userID), 0, -1)
+		loaded[userID] = pipeline.Exists(ctx, getUserBookmarkLoadedKey(userID))
+	}
+	if _, err := pipeline.Exec(ctx); err != nil {
+		return nil, err
+	}
+	data := make(map[int64][]*models.Bookmark, len(userIDs))
+	for userID, cmd := range cmds {
+		if loaded[userID].Val() < 1 {
+			continue
+		}
+		bookmarks := make([]*models.Bookmark, 0, len(cmd.Val()))
+		for _, z := range cmd.Val() {
+			bookmarks = append(bookmarks, &models.Bookmark{
+				UserID:   userID,
+				PostID:   mustParseInt64(z.Member.(string)),
+				SaveTime: int64(z.Score),
+			})
+		}
+		data[userID] = bookmarks
+	}
+	return data, nil
+}
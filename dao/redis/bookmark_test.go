@@ -0,0 +1,39 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimDirtyBookmarkUsers(t *testing.T) {
+	mr.FlushAll()
+	c := context.Background()
+	for _, id := range []string{"1", "2", "3"} {
+		_, err := mr.SAdd(getRedisKey(KeyBookmarkDirtySet), id)
+		require.NoError(t, err)
+	}
+	claimed, err := ClaimDirtyBookmarkUsers(c, 10)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int64{1, 2, 3}, claimed)
+
+	// 没有调用 FinishBookmarkUsers（写入失败或进程崩溃），下次仍然返回这批用户
+	again, err := ClaimDirtyBookmarkUsers(c, 10)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, claimed, again)
+
+	// 持久化期间有新变化的用户重新加入待持久化集合，完成后不会丢失
+	_, err = mr.SAdd(getRedisKey(KeyBookmarkDirtySet), "1")
+	require.NoError(t, err)
+	require.NoError(t, FinishBookmarkUsers(c, claimed))
+	rest, err := ClaimDirtyBookmarkUsers(c, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1}, rest)
+	require.NoError(t, FinishBookmarkUsers(c, rest))
+
+	rest, err = ClaimDirtyBookmarkUsers(c, 10)
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+}
@@ -0,0 +1,86 @@
+package redis
+
+import (
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 评论投票沿用帖子投票的 ZSet 结构：
+//   - KeyCommentVotedZSetPF + commentID 记录每个用户的投票方向
+//   - KeyCommentScoreZSet 记录每条评论的净得票（赞成票 - 反对票）
+// 评论投票没有时间限制
+
+var ErrorCommentNotExist = errors.New("评论不存在")
+
+// CreateComment 初始化评论分数
+func CreateComment(commentID int64) error {
+	return client.ZAdd(ctx, getRedisKey(KeyCommentScoreZSet), redis.Z{
+		Score:  0,
+		Member: commentID,
+	}).Err()
+}
+
+// commentVoteScript 在一个脚本中完成评论投票的检查和更新，避免并发投票时丢失更新
+// KEYS: 评论分数 zset、评论投票记录 zset
+// ARGV: 评论ID、用户ID、投票方向
+// 返回状态：0 成功、1 评论不存在、2 重复投票
+var commentVoteScript = redis.NewScript(`
+if not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	return 1
+end
+local direction = tonumber(ARGV[3])
+local ov = tonumber(redis.call('ZSCORE', KEYS[2], ARGV[2]) or 0)
+if ov == direction then
+	return 2
+end
+redis.call('ZINCRBY', KEYS[1], direction - ov, ARGV[1])
+if direction == 0 then
+	redis.call('ZREM', KEYS[2], ARGV[2])
+else
+	redis.call('ZADD', KEYS[2], direction, ARGV[2])
+end
+return 0
+`)
+
+// VoteForComment 为评论投票
+func VoteForComment(userID, commentID string, direction float64) error {
+	keys := []string{
+		getRedisKey(KeyCommentScoreZSet),
+		getRedisKey(KeyCommentVotedZSetPF + commentID),
+	}
+	res, err := commentVoteScript.Run(ctx, client, keys, commentID, userID, direction).Int64()
+	if err != nil {
+		return err
+	}
+	switch res {
+	case 1:
+		// 评论创建时会写入分数 ZSet，不在其中说明评论不存在
+		return ErrorCommentNotExist
+	case 2:
+		return ErrorVoteRepeat
+	}
+	return nil
+}
+
+// GetCommentVoteData 根据ids查询每条评论的赞成票数和反对票数
+func GetCommentVoteData(ids []string) (up, down []int64, err error) {
+	pipeline := client.Pipeline()
+	upCmds := make([]*redis.IntCmd, 0, len(ids))
+	downCmds := make([]*redis.IntCmd, 0, len(ids))
+	for _, id := range ids {
+		key := getRedisKey(KeyCommentVotedZSetPF + id)
+		upCmds = append(upCmds, pipeline.ZCount(ctx, key, "1", "1"))
+		downCmds = append(downCmds, pipeline.ZCount(ctx, key, "-1", "-1"))
+	}
+	if _, err = pipeline.Exec(ctx); err != nil {
+		return nil, nil, err
+	}
+	up = make([]int64, 0, len(ids))
+	down = make([]int64, 0, len(ids))
+	for i := range ids {
+		up = append(up, upCmds[i].Val())
+		down = append(down, downCmds[i].Val())
+	}
+	return
+}
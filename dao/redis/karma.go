@@ -0,0 +1,115 @@
+package redis
+
+import (
+	"bluebell/models"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 用户 karma：用户的帖子收到的赞成票减反对票
+// 投票时在投票脚本中原子地更新，持久化任务每次写入投票记录后用 MySQL 中的投票记录重建
+
+// karmaBatchSize 重建 karma 时每条 ZADD 命令写入的用户数
+const karmaBatchSize = 500
+
+// getKarmaKey 全局或社区的 karma key，communityID 为 0 时表示全局
+func getKarmaKey(communityID int64) string {
+	if communityID == 0 {
+		return getRedisKey(KeyUserKarmaZSet)
+	}
+	return getCommunityKarmaKey(communityID)
+}
+
+// getCommunityKarmaKey 社区 karma 的 key
+func getCommunityKarmaKey(communityID int64) string {
+	return getRedisKey(KeyCommunityKarmaZSetPF + strconv.FormatInt(communityID, 10))
+}
+
+// GetUserKarma 查询用户的全局 karma
+func GetUserKarma(userID int64) (int64, error) {
+	karma, err := client.ZScore(ctx, getRedisKey(KeyUserKarmaZSet), strconv.FormatInt(userID, 10)).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return int64(karma), err
+}
+
+// GetKarmaLeaderboard 查询 karma 排行榜的前 size 名，communityID 为 0 时查询全局排行榜
+func GetKarmaLeaderboard(communityID, size int64) ([]redis.Z, error) {
+	return client.ZRevRangeWithScores(ctx, getKarmaKey(communityID), 0, size-1).Result()
+}
+
+// getCommunityKarmaKeys 查询已有的社区 karma key
+func getCommunityKarmaKeys() ([]string, error) {
+	prefix := getRedisKey(KeyCommunityKarmaZSetPF)
+	var keys []string
+	iter := client.Scan(ctx, 0, prefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		// 跳过临时 key 等不是社区 karma 的 key
+		if _, err := strconv.ParseInt(strings.TrimPrefix(iter.Val(), prefix), 10, 64); err != nil {
+			continue
+		}
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// ReplaceKarma 用重新统计的数据替换全局和各社区的 karma
+// 先写入临时 key，再用 RENAME 原子地替换，替换前的投票增量以统计结果为准；
+// 统计结果中没有的社区（例如帖子已全部删除）的 karma key 一并删除
+func ReplaceKarma(data []*models.UserKarma) error {
+	global := make(map[int64]int64)
+	communities := make(map[int64][]redis.Z)
+	for _, k := range data {
+		global[k.UserID] += k.Karma
+		communities[k.CommunityID] = append(communities[k.CommunityID], redis.Z{
+			Score:  float64(k.Karma),
+			Member: k.UserID,
+		})
+	}
+	sets := make(map[string][]redis.Z, len(communities)+1)
+	for cid, members := range communities {
+		sets[getCommunityKarmaKey(cid)] = members
+	}
+	members := make([]redis.Z, 0, len(global))
+	for uid, karma := range global {
+		members = append(members, redis.Z{Score: float64(karma), Member: uid})
+	}
+	sets[getRedisKey(KeyUserKarmaZSet)] = members
+
+	existing, err := getCommunityKarmaKeys()
+	if err != nil {
+		return err
+	}
+
+	pipeline := client.Pipeline()
+	for key, members := range sets {
+		tmp := key + ":tmp"
+		pipeline.Del(ctx, tmp)
+		for start := 0; start < len(members); start += karmaBatchSize {
+			end := min(start+karmaBatchSize, len(members))
+			pipeline.ZAdd(ctx, tmp, members[start:end]...)
+		}
+	}
+	if _, err := pipeline.Exec(ctx); err != nil {
+		return err
+	}
+	tx := client.TxPipeline()
+	for key, members := range sets {
+		if len(members) == 0 {
+			tx.Del(ctx, key)
+			continue
+		}
+		tx.Rename(ctx, key+":tmp", key)
+	}
+	for _, key := range existing {
+		if _, ok := sets[key]; !ok {
+			tx.Del(ctx, key)
+		}
+	}
+	_, err = tx.Exec(ctx)
+	return err
+}
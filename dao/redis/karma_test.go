@@ -0,0 +1,36 @@
+package redis
+
+import (
+	"bluebell/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceKarma(t *testing.T) {
+	mr.FlushAll()
+	require.NoError(t, ReplaceKarma([]*models.UserKarma{
+		{UserID: 1, CommunityID: 1, Karma: 3},
+		{UserID: 1, CommunityID: 2, Karma: -1},
+		{UserID: 2, CommunityID: 2, Karma: 5},
+	}))
+	karma, err := GetUserKarma(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), karma)
+	assert.True(t, mr.Exists(getCommunityKarmaKey(1)))
+
+	// 社区 1 的帖子已全部删除，重建后社区 1 的 karma key 被删除
+	require.NoError(t, ReplaceKarma([]*models.UserKarma{
+		{UserID: 2, CommunityID: 2, Karma: 4},
+	}))
+	assert.False(t, mr.Exists(getCommunityKarmaKey(1)))
+	karma, err = GetUserKarma(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), karma)
+	members, err := GetKarmaLeaderboard(2, 10)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, "2", members[0].Member)
+	assert.Equal(t, float64(4), members[0].Score)
+}
@@ -5,12 +5,56 @@ package redis
 // redis key注意使用命名空间的方式,方便查询和拆分
 
 const (
-	Prefix             = "bluebell:"   // 项目key前缀
-	KeyPostTimeZSet    = "post:time"   // zset;贴子及发帖时间
-	KeyPostScoreZSet   = "post:score"  // zset;贴子及投票的分数
-	KeyPostVotedZSetPF = "post:voted:" // zset;记录用户及投票类型;参数是post id
+	Prefix             = "bluebell:"     // 项目key前缀
+	KeyPostTimeZSet    = "post:time"     // zset;贴子及发帖时间
+	KeyPostRankZSetPF  = "post:"         // zset;贴子及各排序算法的分数;参数是排序方式（score、hot、gravity、best）
+	KeyPostScoreZSet   = "post:score"    // zset;贴子及投票的分数，即 KeyPostRankZSetPF + score
+	KeyPostVotedZSetPF = "post:voted:"   // zset;记录用户及投票类型;参数是post id
+	KeyPostArchivedSet = "post:archived" // set;投票已归档到 MySQL 的帖子id
+
+	KeyPostUnarchivedZSet      = "post:unarchived"        // zset;投票尚未归档的帖子及发帖时间，归档时移除
+	KeyPostUnarchivedLoadedKey = "post:unarchived:loaded" // string;KeyPostUnarchivedZSet 已根据发帖时间和归档记录建立
 
 	KeyCommunitySetPF = "community:" // set;保存每个分区下帖子的id
+	KeyFeedZSetPF     = "feed:"      // zset;用户加入的社区的帖子，缓存60秒;参数是排序zset和用户id
+
+	KeyCommunityPinnedZSetPF = "community:pinned:" // zset;社区中置顶的帖子及置顶时间;参数是社区id
+
+	KeyTagSetPF          = "tag:"           // set;每个标签下帖子的id;参数是标签
+	KeyTagTrendingZSetPF = "tags:trending:" // zset;每小时新帖子中各标签的使用次数;参数是小时数（unix时间/3600）
+	KeyTagTrendingZSet   = "tags:trending"  // zset;滑动窗口内各标签的使用次数，缓存60秒
+
+	KeyUserPostSetPF        = "user:posts:" // set;用户发布的帖子id;参数是用户id
+	KeyUserKarmaZSet        = "user:karma"  // zset;用户及收到的赞成票减反对票
+	KeyCommunityKarmaZSetPF = "user:karma:" // zset;用户及在社区中收到的赞成票减反对票;参数是社区id
+
+	KeyUserPostLoadedPF = "user:posts:loaded:" // string;用户的帖子已从 MySQL 加载到 Redis;参数是用户id
+
+	KeyUserBookmarkZSetPF   = "user:bookmarks:"        // zset;用户收藏的帖子及收藏时间（毫秒）;参数是用户id
+	KeyUserBookmarkLoadedPF = "user:bookmarks:loaded:" // string;用户的收藏已从 MySQL 加载到 Redis;参数是用户id
+	KeyBookmarkDirtySet     = "bookmark:dirty"         // set;收藏有变化、尚未持久化到 MySQL 的用户id
+	KeyBookmarkPersistSet   = "bookmark:persisting"    // set;正在持久化收藏的用户id，写入 MySQL 后移除
+
+	KeyCommentScoreZSet   = "comment:score"  // zset;评论及投票的分数
+	KeyCommentVotedZSetPF = "comment:voted:" // zset;记录用户及投票类型;参数是comment id
+
+	KeyRefreshTokenPF     = "token:refresh:"   // string;当前有效的refresh token，值为family id;参数是jti
+	KeyTokenFamilyRevoked = "token:revoked:"   // string;已吊销的token家族;参数是family id
+	KeyTokenBlacklistPF   = "token:blacklist:" // string;已注销的access token;参数是jti
+	KeyEmailTokenPF       = "token:email:"     // string;邮件中尚未使用的一次性令牌，值为用户id:邮箱;参数是用途和nonce
+
+	KeyUserTokenFamilyZSetPF = "token:families:" // zset;用户的token家族及过期时间;参数是用户id
+
+	KeyRateLimitPF = "ratelimit:" // hash;令牌桶的令牌数和更新时间;参数是路由和用户ID/IP
+
+	KeyLoginFailPF = "login:fail:" // string;统计窗口内的登录失败次数;参数是user:用户名或ip:IP
+	KeyLoginLockPF = "login:lock:" // string;登录锁定标记，过期即解锁;参数同上
+
+	KeyNotificationUnreadPF = "notification:unread:" // string;用户的未读通知数;参数是用户id
+
+	KeyPostQuotaPF = "post:quota:" // string;新账号在统计窗口内发布的帖子数;参数是用户id
+
+	KeyPubSubChannelPF = "pubsub:" // pub/sub频道;实时推送的消息;参数是 community:社区id 或 post:帖子id
 )
 
 // 给redis key加上前缀
@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 未读通知数，以 MySQL 为准
+// key 不存在时由调用方从 MySQL 统计后写回，写入时带过期时间，计数出现偏差也会在过期后自动修正
+
+const unreadExpiration = 7 * 24 * time.Hour
+
+// unreadIncrScript 只在计数存在时增减，计数不存在时等待下次读取时从 MySQL 重建，避免从 0 开始计数
+// KEYS: 未读数 key
+// ARGV: 增量、过期时间（秒）
+var unreadIncrScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+local n = redis.call('INCRBY', KEYS[1], ARGV[1])
+if n < 0 then
+	redis.call('SET', KEYS[1], 0)
+end
+redis.call('EXPIRE', KEYS[1], ARGV[2])
+return 1
+`)
+
+func getUnreadKey(userID int64) string {
+	return getRedisKey(KeyNotificationUnreadPF + strconv.FormatInt(userID, 10))
+}
+
+// GetUnreadCount 查询未读通知数，计数不存在时 ok 为 false
+func GetUnreadCount(userID int64) (count int64, ok bool, err error) {
+	count, err = client.Get(ctx, getUnreadKey(userID)).Int64()
+	if errors.Is(err, Nil) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return count, true, nil
+}
+
+// SetUnreadCount 写入从 MySQL 统计的未读通知数
+func SetUnreadCount(userID, count int64) error {
+	return client.Set(ctx, getUnreadKey(userID), count, unreadExpiration).Err()
+}
+
+// IncrUnreadCount 增减未读通知数
+func IncrUnreadCount(userID, delta int64) error {
+	return unreadIncrScript.Run(ctx, client, []string{getUnreadKey(userID)}, delta, int64(unreadExpiration.Seconds())).Err()
+}
@@ -129,8 +129,12 @@ func fetchPostVotesOnce(ctx context.Context) (data []*models.PostVoteData, err e
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch post IDs from Redis: %w", err)
 	}
+	return FetchPostVotesByIDs(ctx, postIDs)
+}
 
-	// 2. 使用 Pipeline 批量获取帖子投票数据
+// FetchPostVotesByIDs 批量获取指定帖子的所有投票记录
+func FetchPostVotesByIDs(ctx context.Context, postIDs []string) (data []*models.PostVoteData, err error) {
+	// 1. 使用 Pipeline 批量获取帖子投票数据
 	pipeline := client.Pipeline()
 	cmders := make(map[string]*redis.ZSliceCmd, len(postIDs)) // 存储每个命令的返回结果
 
@@ -146,7 +150,7 @@ func fetchPostVotesOnce(ctx context.Context) (data []*models.PostVoteData, err e
 		return nil, err
 	}
 
-	// 3. 解析 Pipeline 返回结果
+	// 2. 解析 Pipeline 返回结果
 	data = make([]*models.PostVoteData, 0)
 	for _, postID := range postIDs {
 		// 检查每条命令的执行结果
@@ -156,30 +160,90 @@ func fetchPostVotesOnce(ctx context.Context) (data []*models.PostVoteData, err e
 			continue
 		}
 
-		// 获取投票记录
+		// 获取投票记录，member 为用户ID，score 为投票方向
 		votes, _ := cmd.Result()
-		if len(votes) == 0 {
-			// 如果没有投票记录，生成默认值
-			//data = append(data, &models.PostVoteData{
-			//	PostID: mustParseInt64(postID),
-			//})
-			continue
-		}
-
-		// 打印vote的值、数据类型
-		for direction, userID := range votes {
-			userID := userID.Member.(string)
-
+		for _, vote := range votes {
 			data = append(data, &models.PostVoteData{
 				PostID:    mustParseInt64(postID),
-				UserID:    mustParseInt64(userID),
-				Direction: int32(direction),
+				UserID:    mustParseInt64(vote.Member.(string)),
+				Direction: int32(vote.Score),
 			})
 		}
 	}
 	return data, nil
 }
 
+// GetExpiredPostIDs 获取投票期已结束且尚未归档的帖子ID，最多返回 limit 个
+// 只扫描 KeyPostUnarchivedZSet，已归档的帖子在归档时已被移除，不会随着帖子增多拖慢扫描
+func GetExpiredPostIDs(ctx context.Context, limit int) ([]string, error) {
+	if err := loadUnarchivedPosts(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load unarchived posts: %w", err)
+	}
+	threshold := time.Now().Unix() - oneWeekInSeconds
+	postIDs, err := client.ZRangeByScore(ctx, getRedisKey(KeyPostUnarchivedZSet), &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(threshold, 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch expired post IDs: %w", err)
+	}
+	return postIDs, nil
+}
+
+// loadUnarchivedPosts 第一次使用时根据 KeyPostTimeZSet 和 KeyPostArchivedSet 建立尚未归档的帖子索引
+// 之后新帖子在 CreatePost 中加入索引，归档或删除时移除
+func loadUnarchivedPosts(ctx context.Context) error {
+	n, err := client.Exists(ctx, getRedisKey(KeyPostUnarchivedLoadedKey)).Result()
+	if err != nil || n > 0 {
+		return err
+	}
+	posts, err := client.ZRangeWithScores(ctx, getRedisKey(KeyPostTimeZSet), 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	archived, err := client.SMembers(ctx, getRedisKey(KeyPostArchivedSet)).Result()
+	if err != nil {
+		return err
+	}
+	archivedSet := make(map[string]struct{}, len(archived))
+	for _, id := range archived {
+		archivedSet[id] = struct{}{}
+	}
+	members := make([]redis.Z, 0, len(posts))
+	for _, z := range posts {
+		if _, ok := archivedSet[z.Member.(string)]; !ok {
+			members = append(members, z)
+		}
+	}
+	pipeline := client.TxPipeline()
+	if len(members) > 0 {
+		pipeline.ZAdd(ctx, getRedisKey(KeyPostUnarchivedZSet), members...)
+	}
+	pipeline.Set(ctx, getRedisKey(KeyPostUnarchivedLoadedKey), 1, 0)
+	_, err = pipeline.Exec(ctx)
+	return err
+}
+
+// MarkPostsArchived 标记帖子的投票已归档到 MySQL 并从尚未归档的帖子索引中移除，clean 为 true 时同时删除 Redis 中的投票记录
+func MarkPostsArchived(ctx context.Context, postIDs []string, clean bool) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+	pipeline := client.TxPipeline()
+	members := make([]interface{}, 0, len(postIDs))
+	for _, id := range postIDs {
+		members = append(members, id)
+		if clean {
+			pipeline.Del(ctx, getRedisKey(KeyPostVotedZSetPF+id))
+		}
+	}
+	pipeline.SAdd(ctx, getRedisKey(KeyPostArchivedSet), members...)
+	pipeline.ZRem(ctx, getRedisKey(KeyPostUnarchivedZSet), members...)
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
 // mustParseInt64 将字符串解析为 int64
 func mustParseInt64(s string) int64 {
 	v, _ := strconv.ParseInt(s, 10, 64) // 将字符串 s 解析为 int64，基数为 10
@@ -3,38 +3,55 @@ package redis
 import (
 	"bluebell/models"
 	"context"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-// GetPostVoteData 根据ids查询每篇帖子的投赞成票的数据
-func GetPostVoteData(ids []string) (data []int64, err error) {
-	//data = make([]int64, 0, len(ids))
-	//for _, id := range ids {
-	//	key := getRedisKey(KeyPostVotedZSetPF + id)
-	//	v := client.ZCount(ctx, key, "1", "1").Val()
-	//	data = append(data, v)
-	//}
+// GetPostVoteData 根据ids查询每篇帖子的赞成票数、反对票数以及用户 userID 的投票，userID 为 0 时不查询
+// archived 标记帖子的投票是否已归档，已归档帖子的票数以 MySQL 为准（Redis 中的记录可能已被清理）
+func GetPostVoteData(ids []string, userID int64) (data []*models.PostVoteStats, archived []bool, err error) {
 	// 使用pipeline一次发送多条命令，减少RTT
 	pipeline := client.Pipeline()
-	data = make([]int64, 0, len(ids))
+	upCmds := make([]*redis.IntCmd, 0, len(ids))
+	downCmds := make([]*redis.IntCmd, 0, len(ids))
+	myCmds := make([]*redis.FloatCmd, 0, len(ids))
+	uid := strconv.FormatInt(userID, 10)
 	for _, id := range ids {
 		key := getRedisKey(KeyPostVotedZSetPF + id)
-		pipeline.ZCount(ctx, key, "1", "1")
+		upCmds = append(upCmds, pipeline.ZCount(ctx, key, "1", "1"))
+		downCmds = append(downCmds, pipeline.ZCount(ctx, key, "-1", "-1"))
+		if userID != 0 {
+			myCmds = append(myCmds, pipeline.ZScore(ctx, key, uid))
+		}
 	}
-	cmders, err := pipeline.Exec(ctx)
-	if err != nil {
-		return nil, err
+	members := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		members = append(members, id)
 	}
-	for _, cmder := range cmders {
-		v := cmder.(*redis.IntCmd).Val()
-		data = append(data, v)
+	archivedCmd := pipeline.SMIsMember(ctx, getRedisKey(KeyPostArchivedSet), members...)
+	// 用户没有投过票时 ZScore 返回 redis.Nil，不算错误
+	if _, err = pipeline.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, nil, err
+	}
+	data = make([]*models.PostVoteStats, 0, len(ids))
+	for i := range ids {
+		s := &models.PostVoteStats{
+			UpVotes:   upCmds[i].Val(),
+			DownVotes: downCmds[i].Val(),
+		}
+		if userID != 0 {
+			s.MyVote = int8(myCmds[i].Val())
+		}
+		s.Score = s.UpVotes - s.DownVotes
+		data = append(data, s)
 	}
-	return
+	return data, archivedCmd.Val(), nil
 }
 
 // getIDsFormKey 按照分数从大到小的顺序查询指定数量的元素
@@ -49,69 +66,374 @@ func getIDsFormKey(key string, page, size int64) ([]string, error) {
 // GetPostIDInOrder 根据给定的orderType获取帖子ID
 func GetPostIDInOrder(p *models.ParamPostList) ([]string, error) {
 	// 1. 根据用户请求中携带的order参数确定要查询的redis key
-	key := getRedisKey(KeyPostTimeZSet)
-	if p.Order == models.OrderScore {
-		key = getRedisKey(KeyPostScoreZSet)
-	}
+	key := getRedisKey(getOrderKey(p.Order))
 
 	return getIDsFormKey(key, p.Page, p.Size)
 }
 
 // GetCommunityPostIDsInOrder 根据社区ID和给定的orderType获取帖子ID
 func GetCommunityPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
-	// 1.根据用户请求中携带的order参数确定要查询的redis key
-	orderkey := KeyPostTimeZSet       // 默认是时间
-	if p.Order == models.OrderScore { // 按照分数请求
-		orderkey = KeyPostScoreZSet
+	key, err := getCommunityOrderKey(p)
+	if err != nil {
+		return nil, err
 	}
+	// 存在的就直接根据key查询ids
+	return getIDsFormKey(key, p.Page, p.Size)
+}
+
+// GetCommunityPostIDsPinnedFirst 社区帖子列表，置顶的帖子 pinned 排在最前面，其余帖子按 p.Order 排列
+// 分页针对合并后的列表，每页最多 p.Size 篇，置顶的帖子不会在后面的页中重复出现
+func GetCommunityPostIDsPinnedFirst(p *models.ParamPostList, pinned []string) ([]string, error) {
+	if len(pinned) == 0 {
+		return GetCommunityPostIDsInOrder(p)
+	}
+	key, err := getCommunityOrderKey(p)
+	if err != nil {
+		return nil, err
+	}
+	k := int64(len(pinned))
+	start := (p.Page - 1) * p.Size
+	end := start + p.Size
+	ids := make([]string, 0, p.Size)
+	if start < k {
+		ids = append(ids, pinned[start:min(end, k)]...)
+	}
+	if end <= k {
+		return ids, nil
+	}
+	// 去掉置顶帖子后的列表中需要第 [from, to) 篇，先根据置顶帖子的排名换算成在 zset 中的排名
+	from, to := max(start-k, 0), end-k
+	pipeline := client.Pipeline()
+	cmds := make([]*redis.IntCmd, 0, len(pinned))
+	isPinned := make(map[string]struct{}, len(pinned))
+	for _, id := range pinned {
+		cmds = append(cmds, pipeline.ZRevRank(ctx, key, id))
+		isPinned[id] = struct{}{}
+	}
+	// 置顶的帖子不在列表中时 ZRevRank 返回 redis.Nil，单独判断
+	if _, err := pipeline.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	ranks := make([]int64, 0, len(cmds))
+	for _, cmd := range cmds {
+		if cmd.Err() == nil {
+			ranks = append(ranks, cmd.Val())
+		}
+	}
+	slices.Sort(ranks)
+	rank := from
+	for _, r := range ranks {
+		if r > rank {
+			break
+		}
+		rank++
+	}
+	rest, err := client.ZRevRange(ctx, key, rank, rank+(to-from)+int64(len(ranks))-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range rest {
+		if int64(len(ids)) >= p.Size {
+			break
+		}
+		if _, ok := isPinned[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// getCommunityOrderKey 社区帖子按指定顺序排列的 zset，不存在时计算并缓存
+func getCommunityOrderKey(p *models.ParamPostList) (string, error) {
+	// 1.根据用户请求中携带的order参数确定要查询的redis key
+	orderkey := getOrderKey(p.Order) // 默认是时间
 
 	// 使用zinterstore 把分区的帖子set与帖子分数的zset生成一个新的zset
 	// 针对新的zset 按之前的逻辑取数据
 
 	// 社区的key
-	cKey := KeyCommunitySetPF + strconv.Itoa(int(p.CommunityID))
+	cKey := getRedisKey(KeyCommunitySetPF + strconv.Itoa(int(p.CommunityID)))
 
 	// 利用缓存key减少zinterstore执行的次数 缓存key
-	key := orderkey + strconv.Itoa(int(p.CommunityID))
+	key := getCommunityOrderCacheKey(orderkey, p.CommunityID)
 	if client.Exists(ctx, key).Val() < 1 {
 		// 不存在，需要计算
 		pipeline := client.Pipeline()
 		pipeline.ZInterStore(ctx, key, &redis.ZStore{
-			Keys:      []string{cKey, orderkey}, // 两个key的交集
-			Aggregate: "MAX",                    // 将两个zset函数聚合的时候 求最大值
+			Keys:    []string{cKey, getRedisKey(orderkey)}, // 两个key的交集
+			Weights: []float64{0, 1},                       // 只保留排序 zset 的分数，分数可能为负，不能用 MAX 聚合
 		}) // zinterstore 计算
 		pipeline.Expire(ctx, key, 60*time.Second) // 设置超时时间
 		_, err := pipeline.Exec(ctx)
 		if err != nil {
+			return "", err
+		}
+	}
+	return key, nil
+}
+
+// postCursorScript 按分数从大到小查询排在游标 (score, member) 之后的帖子
+// 分数严格小于游标的部分用 ZREVRANGEBYSCORE 的开区间查询；
+// 与游标同分的帖子：游标帖子分数未变时从它的排名之后继续，否则按成员的字典序（与 Redis 中同分元素的顺序一致）过滤
+// KEYS: 排序 zset
+// ARGV: 游标分数（为空表示第一页）、游标帖子ID、数量
+// 返回 [member1, score1, member2, score2, ...]
+var postCursorScript = redis.NewScript(`
+local count = tonumber(ARGV[3])
+if ARGV[1] == '' then
+	return redis.call('ZREVRANGEBYSCORE', KEYS[1], '+inf', '-inf', 'WITHSCORES', 'LIMIT', 0, count)
+end
+local res = {}
+local s = redis.call('ZSCORE', KEYS[1], ARGV[2])
+if s and tonumber(s) == tonumber(ARGV[1]) then
+	local higher = redis.call('ZCOUNT', KEYS[1], '(' .. ARGV[1], '+inf')
+	local rank = redis.call('ZREVRANK', KEYS[1], ARGV[2])
+	res = redis.call('ZREVRANGEBYSCORE', KEYS[1], ARGV[1], ARGV[1], 'WITHSCORES', 'LIMIT', rank - higher + 1, count)
+else
+	local ties = redis.call('ZREVRANGEBYSCORE', KEYS[1], ARGV[1], ARGV[1], 'WITHSCORES')
+	for i = 1, #ties, 2 do
+		if #res >= count * 2 then
+			break
+		end
+		if ties[i] < ARGV[2] then
+			table.insert(res, ties[i])
+			table.insert(res, ties[i + 1])
+		end
+	end
+end
+local rest = count - #res / 2
+if rest > 0 then
+	local lower = redis.call('ZREVRANGEBYSCORE', KEYS[1], '(' .. ARGV[1], '-inf', 'WITHSCORES', 'LIMIT', 0, rest)
+	for _, v in ipairs(lower) do
+		table.insert(res, v)
+	end
+end
+return res
+`)
+
+// GetPostIDsAfter 按 p.Order 查询排在游标 (score, postID) 之后的 count 篇帖子，postID 为空时从第一篇开始
+// p.CommunityID 不为 0 时只查询该社区的帖子，p.Tag 不为空时只查询该标签的帖子
+func GetPostIDsAfter(p *models.ParamPostList, score float64, postID string, count int64) (ids []string, scores []float64, err error) {
+	key := getRedisKey(getOrderKey(p.Order))
+	switch {
+	case p.Tag != "":
+		key, err = getTagOrderKey(p)
+	case p.CommunityID != 0:
+		key, err = getCommunityOrderKey(p)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return getIDsAfter(key, score, postID, count)
+}
+
+// getIDsAfter 按分数从大到小查询 zset 中排在游标 (score, member) 之后的 count 个元素，member 为空时从第一个开始
+func getIDsAfter(key string, score float64, member string, count int64) (ids []string, scores []float64, err error) {
+	var cursorScore string
+	if member != "" {
+		cursorScore = strconv.FormatFloat(score, 'g', -1, 64)
+	}
+	res, err := postCursorScript.Run(ctx, client, []string{key}, cursorScore, member, count).StringSlice()
+	if err != nil {
+		return nil, nil, err
+	}
+	ids = make([]string, 0, len(res)/2)
+	scores = make([]float64, 0, len(res)/2)
+	for i := 0; i+1 < len(res); i += 2 {
+		s, err := strconv.ParseFloat(res[i+1], 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, res[i])
+		scores = append(scores, s)
+	}
+	return ids, scores, nil
+}
+
+// GetFeedPostIDsInOrder 按给定的 order 获取用户加入的社区 communityIDs 中的帖子ID
+func GetFeedPostIDsInOrder(userID int64, communityIDs []int64, p *models.ParamPostList) ([]string, error) {
+	if len(communityIDs) == 0 {
+		return nil, nil
+	}
+	orderKey := getOrderKey(p.Order)
+	key := getFeedCacheKey(orderKey, userID)
+	if client.Exists(ctx, key).Val() < 1 {
+		// 与社区帖子列表相同的思路：先用 zunionstore 把用户加入的所有社区的帖子 set 合并到临时 key，
+		// 再与排序 zset 做 zinterstore 得到带分数的帖子列表，结果按用户缓存
+		cKeys := make([]string, 0, len(communityIDs))
+		weights := make([]float64, 0, len(communityIDs))
+		for _, id := range communityIDs {
+			cKeys = append(cKeys, getRedisKey(KeyCommunitySetPF+strconv.FormatInt(id, 10)))
+			weights = append(weights, 0)
+		}
+		unionKey := key + ":union"
+		pipeline := client.TxPipeline()
+		pipeline.ZUnionStore(ctx, unionKey, &redis.ZStore{
+			Keys:    cKeys,
+			Weights: weights,
+		})
+		pipeline.ZInterStore(ctx, key, &redis.ZStore{
+			Keys:    []string{unionKey, getRedisKey(orderKey)},
+			Weights: []float64{0, 1},
+		})
+		pipeline.Del(ctx, unionKey)
+		pipeline.Expire(ctx, key, 60*time.Second)
+		if _, err := pipeline.Exec(ctx); err != nil {
 			return nil, err
 		}
 	}
-	// 存在的就直接根据key查询ids
 	return getIDsFormKey(key, p.Page, p.Size)
 }
 
-// CreatePost 创建帖子
-func CreatePost(postID, communityID int64) error {
+// ClearFeedCache 用户加入或退出社区后删除其首页缓存
+func ClearFeedCache(userID int64) error {
+	orderKeys := orderKeys()
+	keys := make([]string, 0, len(orderKeys))
+	for _, orderKey := range orderKeys {
+		keys = append(keys, getFeedCacheKey(orderKey, userID))
+	}
+	return client.Del(ctx, keys...).Err()
+}
+
+// getFeedCacheKey 用户首页帖子按指定顺序排列的缓存key
+func getFeedCacheKey(orderKey string, userID int64) string {
+	return getRedisKey(KeyFeedZSetPF + orderKey + ":" + strconv.FormatInt(userID, 10))
+}
+
+// getCommunityOrderCacheKey 社区帖子按指定顺序排列的缓存key
+func getCommunityOrderCacheKey(orderKey string, communityID int64) string {
+	return getRedisKey(orderKey + strconv.Itoa(int(communityID)))
+}
+
+// getUserPostKey 用户发布的帖子的 key
+func getUserPostKey(userID int64) string {
+	return getRedisKey(KeyUserPostSetPF + strconv.FormatInt(userID, 10))
+}
+
+// getUserOrderCacheKey 用户发布的帖子按指定顺序排列的缓存key
+func getUserOrderCacheKey(orderKey string, userID int64) string {
+	return getRedisKey(orderKey + ":user:" + strconv.FormatInt(userID, 10))
+}
+
+func getUserPostLoadedKey(userID int64) string {
+	return getRedisKey(KeyUserPostLoadedPF + strconv.FormatInt(userID, 10))
+}
+
+// UserPostsLoaded 用户发布的帖子是否已经从 MySQL 加载到 Redis
+// 使用单独的标记而不是帖子set是否存在：升级后发的第一篇帖子会创建帖子set，但之前的帖子仍需要加载
+func UserPostsLoaded(userID int64) (bool, error) {
+	n, err := client.Exists(ctx, getUserPostLoadedKey(userID)).Result()
+	return n > 0, err
+}
+
+// LoadUserPosts 把 MySQL 中用户发布的帖子加入用户的帖子set并设置加载标记，用于补全升级前发布的帖子
+func LoadUserPosts(userID int64, postIDs []string) error {
+	pipeline := client.TxPipeline()
+	if len(postIDs) > 0 {
+		members := make([]interface{}, 0, len(postIDs))
+		for _, id := range postIDs {
+			members = append(members, id)
+		}
+		pipeline.SAdd(ctx, getUserPostKey(userID), members...)
+	}
+	pipeline.Set(ctx, getUserPostLoadedKey(userID), 1, 0)
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+// GetUserPostIDs 查询用户发布的所有帖子ID
+func GetUserPostIDs(userID int64) ([]string, error) {
+	return client.SMembers(ctx, getUserPostKey(userID)).Result()
+}
+
+// GetUserPostIDsInOrder 按指定顺序分页查询用户发布的帖子ID
+// 与社区帖子列表相同，使用 zinterstore 计算并缓存60秒
+func GetUserPostIDsInOrder(userID int64, p *models.ParamPostList) ([]string, error) {
+	orderKey := getOrderKey(p.Order)
+	key := getUserOrderCacheKey(orderKey, userID)
+	if client.Exists(ctx, key).Val() < 1 {
+		pipeline := client.Pipeline()
+		pipeline.ZInterStore(ctx, key, &redis.ZStore{
+			Keys:    []string{getUserPostKey(userID), getRedisKey(orderKey)},
+			Weights: []float64{0, 1},
+		})
+		pipeline.Expire(ctx, key, 60*time.Second)
+		if _, err := pipeline.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return getIDsFormKey(key, p.Page, p.Size)
+}
+
+// CreatePost 创建帖子，ranks 为帖子在各个排序方式下的初始分数，tags 为规范化后的标签
+func CreatePost(postID, communityID, authorID, createTime int64, ranks map[string]float64, tags []string) error {
 	pipeline := client.TxPipeline() // 获取一个事务
 	// 帖子时间
 	pipeline.ZAdd(ctx, getRedisKey(KeyPostTimeZSet), redis.Z{
-		Score:  float64(time.Now().Unix()),
+		Score:  float64(createTime),
 		Member: postID,
 	})
-
-	// 帖子分数
-	pipeline.ZAdd(ctx, getRedisKey(KeyPostScoreZSet), redis.Z{
-		Score:  0,
+	// 投票尚未归档
+	pipeline.ZAdd(ctx, getRedisKey(KeyPostUnarchivedZSet), redis.Z{
+		Score:  float64(createTime),
 		Member: postID,
 	})
+
+	// 帖子分数
+	for name, score := range ranks {
+		pipeline.ZAdd(ctx, getRedisKey(getRankKey(name)), redis.Z{
+			Score:  score,
+			Member: postID,
+		})
+	}
 	// 把帖子id加到社区的set
 	cKey := getRedisKey(KeyCommunitySetPF + strconv.Itoa(int(communityID)))
 	pipeline.SAdd(ctx, cKey, postID)
+	// 把帖子id加到作者的set
+	pipeline.SAdd(ctx, getUserPostKey(authorID), postID)
+	// 把帖子id加到标签的set，并计入热门标签
+	addPostTags(pipeline, postID, createTime, tags)
 	// 提交事务
 	_, err := pipeline.Exec(ctx)
 	return err
 }
 
+// RemovePost 从排序、社区和作者相关的 key 中移除帖子，帖子被删除后不再出现在列表中
+func RemovePost(postID, communityID, authorID int64) error {
+	pipeline := client.TxPipeline()
+	pipeline.SRem(ctx, getRedisKey(KeyCommunitySetPF+strconv.Itoa(int(communityID))), postID)
+	pipeline.SRem(ctx, getUserPostKey(authorID), postID)
+	pipeline.ZRem(ctx, getCommunityPinnedKey(communityID), postID)
+	pipeline.ZRem(ctx, getRedisKey(KeyPostUnarchivedZSet), postID)
+	for _, key := range orderKeys() {
+		pipeline.ZRem(ctx, getRedisKey(key), postID)
+		// 社区和用户帖子列表的 zinterstore 缓存也要同步移除，否则在缓存过期前仍会返回
+		pipeline.ZRem(ctx, getCommunityOrderCacheKey(key, communityID), postID)
+		pipeline.ZRem(ctx, getUserOrderCacheKey(key, authorID), postID)
+	}
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+// getCommunityPinnedKey 社区置顶帖子的 key
+func getCommunityPinnedKey(communityID int64) string {
+	return getRedisKey(KeyCommunityPinnedZSetPF + strconv.FormatInt(communityID, 10))
+}
+
+// SetPostPinned 置顶或取消置顶社区中的帖子
+func SetPostPinned(postID, communityID int64, pinned bool) error {
+	key := getCommunityPinnedKey(communityID)
+	if !pinned {
+		return client.ZRem(ctx, key, postID).Err()
+	}
+	return client.ZAdd(ctx, key, redis.Z{Score: float64(time.Now().Unix()), Member: postID}).Err()
+}
+
+// GetPinnedPostIDs 查询社区中置顶的帖子ID，最近置顶的在前
+func GetPinnedPostIDs(communityID int64) ([]string, error) {
+	return client.ZRevRange(ctx, getCommunityPinnedKey(communityID), 0, -1).Result()
+}
+
 // GetPostIDsByTimeRange  获取指定时间范围内的帖子id
 func GetPostIDsByTimeRange(ctx context.Context, expiredDays int) ([]string, error) {
 	// 计算过期时间的阈值
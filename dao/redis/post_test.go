@@ -0,0 +1,171 @@
+package redis
+
+import (
+	"bluebell/models"
+	"bluebell/pkg/ranking"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var mr *miniredis.Miniredis
+
+func TestMain(m *testing.M) {
+	mr = miniredis.NewMiniRedis()
+	if err := mr.Start(); err != nil {
+		panic(err)
+	}
+	client = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ranking.Register(models.OrderScore, ranking.Linear{ScorePerVote: 432})
+	code := m.Run()
+	_ = client.Close()
+	mr.Close()
+	os.Exit(code)
+}
+
+// addTestPost 写入帖子的社区、发帖时间和 score 排序的分数
+func addTestPost(t *testing.T, postID, communityID int64, createTime int64, score float64) {
+	t.Helper()
+	id := strconv.FormatInt(postID, 10)
+	require.NoError(t, client.ZAdd(ctx, getRedisKey(KeyPostTimeZSet), redis.Z{Score: float64(createTime), Member: id}).Err())
+	require.NoError(t, client.ZAdd(ctx, getRedisKey(KeyPostScoreZSet), redis.Z{Score: score, Member: id}).Err())
+	require.NoError(t, client.SAdd(ctx, getRedisKey(KeyCommunitySetPF+strconv.FormatInt(communityID, 10)), id).Err())
+}
+
+func TestGetFeedPostIDsInOrder(t *testing.T) {
+	mr.FlushAll()
+	// 社区 1、2 是用户加入的社区，社区 3 的帖子不应出现
+	addTestPost(t, 1, 1, 100, 432)
+	addTestPost(t, 2, 2, 200, 0)
+	addTestPost(t, 3, 1, 300, 864)
+	addTestPost(t, 4, 3, 400, 1296)
+	addTestPost(t, 5, 2, 500, -432)
+	const userID = 42
+	communityIDs := []int64{1, 2}
+
+	ids, err := GetFeedPostIDsInOrder(userID, communityIDs, &models.ParamPostList{Page: 1, Size: 10, Order: models.OrderTime})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"5", "3", "2", "1"}, ids)
+
+	// 分数为负的帖子排在最后，不能被聚合成 0
+	ids, err = GetFeedPostIDsInOrder(userID, communityIDs, &models.ParamPostList{Page: 1, Size: 10, Order: models.OrderScore})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3", "1", "2", "5"}, ids)
+
+	// 未知的排序方式按时间排序
+	ids, err = GetFeedPostIDsInOrder(userID, communityIDs, &models.ParamPostList{Page: 1, Size: 10, Order: "unknown"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"5", "3", "2", "1"}, ids)
+
+	ids, err = GetFeedPostIDsInOrder(userID, nil, &models.ParamPostList{Page: 1, Size: 10})
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestGetFeedPostIDsInOrderPaging(t *testing.T) {
+	mr.FlushAll()
+	for i := int64(1); i <= 5; i++ {
+		addTestPost(t, i, 1, i*100, 0)
+	}
+	const userID = 42
+	page := func(page, size int64) []string {
+		ids, err := GetFeedPostIDsInOrder(userID, []int64{1}, &models.ParamPostList{Page: page, Size: size, Order: models.OrderTime})
+		require.NoError(t, err)
+		return ids
+	}
+	assert.Equal(t, []string{"5", "4"}, page(1, 2))
+	assert.Equal(t, []string{"3", "2"}, page(2, 2))
+	// 最后一页不足 size 篇
+	assert.Equal(t, []string{"1"}, page(3, 2))
+	// 超出范围的页为空
+	assert.Empty(t, page(4, 2))
+	// 刚好整除时最后一页是满的，下一页为空
+	assert.Equal(t, []string{"5", "4", "3", "2", "1"}, page(1, 5))
+	assert.Empty(t, page(2, 5))
+}
+
+func TestGetFeedPostIDsInOrderCache(t *testing.T) {
+	mr.FlushAll()
+	addTestPost(t, 1, 1, 100, 0)
+	const userID = 42
+	p := &models.ParamPostList{Page: 1, Size: 10, Order: models.OrderTime}
+	ids, err := GetFeedPostIDsInOrder(userID, []int64{1}, p)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, ids)
+
+	// 缓存期间新帖子不出现，清除缓存后立即出现
+	addTestPost(t, 2, 1, 200, 0)
+	ids, err = GetFeedPostIDsInOrder(userID, []int64{1}, p)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, ids)
+	require.NoError(t, ClearFeedCache(userID))
+	ids, err = GetFeedPostIDsInOrder(userID, []int64{1}, p)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2", "1"}, ids)
+}
+
+func TestGetIDsAfter(t *testing.T) {
+	mr.FlushAll()
+	key := getRedisKey(KeyPostScoreZSet)
+	// 2、3、4 同分，同分时按成员的字典序从大到小排列
+	for id, score := range map[string]float64{"1": 30, "2": 20, "3": 20, "4": 20, "5": 10} {
+		require.NoError(t, client.ZAdd(ctx, key, redis.Z{Score: score, Member: id}).Err())
+	}
+	ids, scores, err := getIDsAfter(key, 0, "", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "4"}, ids)
+	assert.Equal(t, []float64{30, 20}, scores)
+
+	// 游标落在同分的帖子中间
+	ids, _, err = getIDsAfter(key, 20, "4", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3", "2"}, ids)
+	ids, _, err = getIDsAfter(key, 20, "2", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"5"}, ids)
+
+	// 游标帖子的分数已经变化时按字典序继续，不重复也不遗漏
+	require.NoError(t, client.ZAdd(ctx, key, redis.Z{Score: 40, Member: "4"}).Err())
+	ids, _, err = getIDsAfter(key, 20, "4", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3", "2", "5"}, ids)
+
+	// 最后一篇之后为空
+	ids, _, err = getIDsAfter(key, 10, "5", 2)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestGetCommunityPostIDsPinnedFirst(t *testing.T) {
+	mr.FlushAll()
+	for i := int64(1); i <= 6; i++ {
+		addTestPost(t, i, 1, i*100, 0)
+	}
+	// 按时间排列为 6 5 4 3 2 1，置顶 2 和 5 后为 2 5 6 4 3 1
+	pinned := []string{"2", "5"}
+	page := func(page, size int64) []string {
+		p := &models.ParamPostList{Page: page, Size: size, CommunityID: 1, Order: models.OrderTime}
+		ids, err := GetCommunityPostIDsPinnedFirst(p, pinned)
+		require.NoError(t, err)
+		return ids
+	}
+	assert.Equal(t, []string{"2", "5", "6"}, page(1, 3))
+	assert.Equal(t, []string{"4", "3", "1"}, page(2, 3))
+	assert.Empty(t, page(3, 3))
+	// 置顶的帖子跨页
+	assert.Equal(t, []string{"2"}, page(1, 1))
+	assert.Equal(t, []string{"5"}, page(2, 1))
+	assert.Equal(t, []string{"6"}, page(3, 1))
+	assert.Equal(t, []string{"6", "4"}, page(2, 2))
+	assert.Equal(t, []string{"2", "5", "6", "4", "3", "1"}, page(1, 10))
+
+	// 不在列表中的置顶帖子只出现在置顶部分
+	pinned = []string{"9"}
+	assert.Equal(t, []string{"9", "6"}, page(1, 2))
+	assert.Equal(t, []string{"5", "4"}, page(2, 2))
+}
@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"context"
+	"strings"
+)
+
+// PublishStream 向实时推送频道发布消息，所有实例都会收到
+func PublishStream(channel string, payload []byte) error {
+	return client.Publish(ctx, getRedisKey(KeyPubSubChannelPF+channel), payload).Err()
+}
+
+// ListenStream 订阅所有实时推送频道，收到消息时调用 fn，直到 ctx 结束
+// 连接断开时 go-redis 会自动重连并重新订阅，断开期间的消息会丢失
+func ListenStream(ctx context.Context, fn func(channel string, payload []byte)) error {
+	prefix := getRedisKey(KeyPubSubChannelPF)
+	pubsub := client.PSubscribe(ctx, prefix+"*")
+	defer pubsub.Close()
+	// 等待订阅成功，连接不上 Redis 时直接返回错误
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			fn(strings.TrimPrefix(msg.Channel, prefix), []byte(msg.Payload))
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package redis
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 新账号的发帖配额，计数从窗口内的第一篇帖子开始，窗口结束后清零
+// 发帖前先占用一个名额，帖子未能发布时再归还
+
+var incrQuotaScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// decrQuotaScript 归还名额，计数已经过期时不再减，避免留下没有过期时间的负数
+var decrQuotaScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+return redis.call('DECR', KEYS[1])
+`)
+
+func getPostQuotaKey(userID int64) string {
+	return getRedisKey(KeyPostQuotaPF + strconv.FormatInt(userID, 10))
+}
+
+// IncrPostQuota 为用户占用一个发帖名额，返回窗口内已占用的名额数（包括这一次）
+func IncrPostQuota(userID int64, window time.Duration) (int64, error) {
+	return incrQuotaScript.Run(ctx, client, []string{getPostQuotaKey(userID)}, window.Milliseconds()).Int64()
+}
+
+// DecrPostQuota 归还用户占用的一个发帖名额
+func DecrPostQuota(userID int64) error {
+	return decrQuotaScript.Run(ctx, client, []string{getPostQuotaKey(userID)}).Err()
+}
@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostQuota(t *testing.T) {
+	mr.FlushAll()
+
+	// 并发占用名额时只有 limit 个请求拿到名额
+	const limit = 3
+	var wg sync.WaitGroup
+	var granted atomic.Int64
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := IncrPostQuota(1, time.Hour)
+			assert.NoError(t, err)
+			if n <= limit {
+				granted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(limit), granted.Load())
+
+	require.NoError(t, DecrPostQuota(1))
+	n, err := client.Get(ctx, getPostQuotaKey(1)).Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), n)
+
+	// 计数过期后归还名额不会留下负数
+	mr.FastForward(time.Hour)
+	require.NoError(t, DecrPostQuota(1))
+	assert.False(t, mr.Exists(getPostQuotaKey(1)))
+}
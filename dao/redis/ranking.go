@@ -0,0 +1,135 @@
+package redis
+
+import (
+	"bluebell/pkg/ranking"
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// getRankKey 排序算法的分数对应的 ZSet（不带前缀）
+func getRankKey(name string) string {
+	return KeyPostRankZSetPF + name
+}
+
+// getOrderKey 根据排序方式获取对应的 ZSet（不带前缀），未注册的排序方式按时间排序
+func getOrderKey(order string) string {
+	if _, ok := ranking.Get(order); ok {
+		return getRankKey(order)
+	}
+	return KeyPostTimeZSet
+}
+
+// orderKeys 所有排序方式对应的 ZSet（不带前缀），包括发帖时间
+func orderKeys() []string {
+	names := ranking.Names()
+	keys := make([]string, 0, len(names)+1)
+	keys = append(keys, KeyPostTimeZSet)
+	for _, name := range names {
+		keys = append(keys, getRankKey(name))
+	}
+	return keys
+}
+
+// GetPostRankStats 批量查询计算排序分数需要的数据：发帖时间、赞成票数、反对票数
+// 不在 KeyPostTimeZSet 中的帖子（已删除）和投票已归档的帖子（分数已冻结）不会出现在结果中
+func GetPostRankStats(ctx context.Context, postIDs []string) (map[string]*ranking.PostStats, error) {
+	pipeline := client.Pipeline()
+	timeCmds := make([]*redis.FloatCmd, 0, len(postIDs))
+	upCmds := make([]*redis.IntCmd, 0, len(postIDs))
+	downCmds := make([]*redis.IntCmd, 0, len(postIDs))
+	archivedCmds := make([]*redis.BoolCmd, 0, len(postIDs))
+	for _, id := range postIDs {
+		key := getRedisKey(KeyPostVotedZSetPF + id)
+		timeCmds = append(timeCmds, pipeline.ZScore(ctx, getRedisKey(KeyPostTimeZSet), id))
+		upCmds = append(upCmds, pipeline.ZCount(ctx, key, "1", "1"))
+		downCmds = append(downCmds, pipeline.ZCount(ctx, key, "-1", "-1"))
+		archivedCmds = append(archivedCmds, pipeline.SIsMember(ctx, getRedisKey(KeyPostArchivedSet), id))
+	}
+	// 帖子不存在时 ZScore 返回 redis.Nil，单独判断
+	if _, err := pipeline.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	stats := make(map[string]*ranking.PostStats, len(postIDs))
+	for i, id := range postIDs {
+		if timeCmds[i].Err() != nil || archivedCmds[i].Val() {
+			continue
+		}
+		stats[id] = &ranking.PostStats{
+			CreateTime: int64(timeCmds[i].Val()),
+			Up:         upCmds[i].Val(),
+			Down:       downCmds[i].Val(),
+		}
+	}
+	return stats, nil
+}
+
+// SetPostRanks 批量写入帖子在各个排序方式下的分数，ranks: post id -> order -> score
+func SetPostRanks(ctx context.Context, ranks map[string]map[string]float64) error {
+	if len(ranks) == 0 {
+		return nil
+	}
+	pipeline := client.Pipeline()
+	for postID, scores := range ranks {
+		for name, score := range scores {
+			pipeline.ZAdd(ctx, getRedisKey(getRankKey(name)), redis.Z{Score: score, Member: postID})
+		}
+	}
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+// GetUnrankedPosts 按发帖时间从 offset 开始检查 count 篇帖子，找出缺少任一排序分数的帖子（排序算法上线前发布的帖子）
+// 返回这些帖子计算分数需要的数据，其中投票已归档的帖子的票数需要调用方从 MySQL 补充；
+// n 为本次检查的帖子数，小于 count 表示已经检查到最后一篇
+func GetUnrankedPosts(ctx context.Context, offset, count int64) (stats map[string]*ranking.PostStats, archived []string, n int, err error) {
+	posts, err := client.ZRangeWithScores(ctx, getRedisKey(KeyPostTimeZSet), offset, offset+count-1).Result()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	names := ranking.Names()
+	pipeline := client.Pipeline()
+	rankCmds := make([][]*redis.FloatCmd, len(posts))
+	upCmds := make([]*redis.IntCmd, len(posts))
+	downCmds := make([]*redis.IntCmd, len(posts))
+	archivedCmds := make([]*redis.BoolCmd, len(posts))
+	for i, z := range posts {
+		id := z.Member.(string)
+		for _, name := range names {
+			rankCmds[i] = append(rankCmds[i], pipeline.ZScore(ctx, getRedisKey(getRankKey(name)), id))
+		}
+		key := getRedisKey(KeyPostVotedZSetPF + id)
+		upCmds[i] = pipeline.ZCount(ctx, key, "1", "1")
+		downCmds[i] = pipeline.ZCount(ctx, key, "-1", "-1")
+		archivedCmds[i] = pipeline.SIsMember(ctx, getRedisKey(KeyPostArchivedSet), id)
+	}
+	// 缺少分数时 ZScore 返回 redis.Nil，单独判断
+	if len(posts) > 0 {
+		if _, err = pipeline.Exec(ctx); err != nil && err != redis.Nil {
+			return nil, nil, 0, err
+		}
+	}
+	stats = make(map[string]*ranking.PostStats)
+	for i, z := range posts {
+		ranked := true
+		for _, cmd := range rankCmds[i] {
+			if cmd.Err() == redis.Nil {
+				ranked = false
+				break
+			}
+		}
+		if ranked {
+			continue
+		}
+		id := z.Member.(string)
+		stats[id] = &ranking.PostStats{
+			CreateTime: int64(z.Score),
+			Up:         upCmds[i].Val(),
+			Down:       downCmds[i].Val(),
+		}
+		if archivedCmds[i].Val() {
+			archived = append(archived, id)
+		}
+	}
+	return stats, archived, len(posts), nil
+}
@@ -0,0 +1,64 @@
+package redis
+
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 令牌桶限流，桶的状态保存在 Redis 中，多个实例共享同一份额度
+// 读取、补充、扣减在一个 Lua 脚本中完成，时间取 Redis 服务器时间，避免各实例时钟不一致
+
+var takeTokenScript = redis.NewScript(`
+local key = KEYS[1]
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local bucket = redis.call('HMGET', key, 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) * 1000 / rate)
+end
+local reset = math.ceil((capacity - tokens) * 1000 / rate)
+
+redis.call('HSET', key, 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', key, reset + 1000)
+return {allowed, math.floor(tokens), retry, reset}
+`)
+
+// RateLimitResult 一次取令牌的结果
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int64         // 桶中剩余的令牌数
+	RetryAfter time.Duration // 被拒绝时，距离下一个令牌可用的时间
+	Reset      time.Duration // 距离桶被填满的时间
+}
+
+// TakeToken 从指定的桶中取一个令牌
+// rate 为每秒补充的令牌数，capacity 为桶容量
+func TakeToken(bucket string, rate float64, capacity int64) (*RateLimitResult, error) {
+	vals, err := takeTokenScript.Run(ctx, client, []string{getRedisKey(KeyRateLimitPF + bucket)}, rate, capacity).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	return &RateLimitResult{
+		Allowed:    vals[0] == 1,
+		Remaining:  vals[1],
+		RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+		Reset:      time.Duration(vals[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,158 @@
+package redis
+
+import (
+	"bluebell/models"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tagTrendingHours 热门标签的统计窗口（小时）
+// 按小时分桶计数，查询时合并最近 tagTrendingHours 个桶，窗口随时间每小时向前滑动一次
+const tagTrendingHours = 24
+
+// getTagKey 标签的帖子set
+func getTagKey(tag string) string {
+	return getRedisKey(KeyTagSetPF + tag)
+}
+
+// getTagTrendingKey 某个小时的标签计数
+func getTagTrendingKey(hour int64) string {
+	return getRedisKey(KeyTagTrendingZSetPF + strconv.FormatInt(hour, 10))
+}
+
+// getTagOrderCacheKey 标签（和社区）的帖子按指定顺序排列的缓存key，communityID 为 0 表示不限社区
+func getTagOrderCacheKey(orderKey, tag string, communityID int64) string {
+	key := orderKey + ":tag:" + tag
+	if communityID != 0 {
+		key += ":" + strconv.FormatInt(communityID, 10)
+	}
+	return getRedisKey(key)
+}
+
+// addPostTags 把帖子加入标签的set，createTime 还在统计窗口内时计入热门标签
+func addPostTags(pipeline redis.Pipeliner, postID, createTime int64, tags []string) {
+	for _, tag := range tags {
+		pipeline.SAdd(ctx, getTagKey(tag), postID)
+	}
+	incrTagTrending(pipeline, createTime, tags, 1)
+}
+
+// incrTagTrending 修改帖子发布时所在小时的标签计数，已经滑出统计窗口的不需要修改
+func incrTagTrending(pipeline redis.Pipeliner, createTime int64, tags []string, delta float64) {
+	hour := createTime / 3600
+	if len(tags) == 0 || hour <= time.Now().Unix()/3600-tagTrendingHours {
+		return
+	}
+	key := getTagTrendingKey(hour)
+	for _, tag := range tags {
+		pipeline.ZIncrBy(ctx, key, delta, tag)
+	}
+	// 桶滑出统计窗口后自动删除
+	pipeline.ExpireAt(ctx, key, time.Unix((hour+tagTrendingHours+1)*3600, 0))
+}
+
+// AddPostTags 给已有的帖子添加标签
+func AddPostTags(postID, createTime int64, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	pipeline := client.TxPipeline()
+	addPostTags(pipeline, postID, createTime, tags)
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+// RemovePostTags 从标签的set和列表缓存中移除帖子，帖子删除或去掉标签时调用
+func RemovePostTags(postID, communityID, createTime int64, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	orderKeys := orderKeys()
+	pipeline := client.TxPipeline()
+	for _, tag := range tags {
+		pipeline.SRem(ctx, getTagKey(tag), postID)
+		for _, key := range orderKeys {
+			pipeline.ZRem(ctx, getTagOrderCacheKey(key, tag, 0), postID)
+			pipeline.ZRem(ctx, getTagOrderCacheKey(key, tag, communityID), postID)
+		}
+	}
+	incrTagTrending(pipeline, createTime, tags, -1)
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+// getTagOrderKey 标签的帖子按指定顺序排列的 zset，p.CommunityID 不为 0 时只包含该社区的帖子
+// 与社区帖子列表相同，用 zinterstore 计算后缓存60秒
+func getTagOrderKey(p *models.ParamPostList) (string, error) {
+	orderKey := getOrderKey(p.Order)
+	key := getTagOrderCacheKey(orderKey, p.Tag, p.CommunityID)
+	if client.Exists(ctx, key).Val() > 0 {
+		return key, nil
+	}
+	keys := []string{getTagKey(p.Tag)}
+	weights := []float64{0}
+	if p.CommunityID != 0 {
+		keys = append(keys, getRedisKey(KeyCommunitySetPF+strconv.Itoa(int(p.CommunityID))))
+		weights = append(weights, 0)
+	}
+	// 只保留排序 zset 的分数
+	keys = append(keys, getRedisKey(orderKey))
+	weights = append(weights, 1)
+	pipeline := client.Pipeline()
+	pipeline.ZInterStore(ctx, key, &redis.ZStore{
+		Keys:    keys,
+		Weights: weights,
+	})
+	pipeline.Expire(ctx, key, 60*time.Second)
+	if _, err := pipeline.Exec(ctx); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// GetTagPostIDsInOrder 按指定顺序分页查询标签（和社区）的帖子ID
+func GetTagPostIDsInOrder(p *models.ParamPostList) ([]string, error) {
+	key, err := getTagOrderKey(p)
+	if err != nil {
+		return nil, err
+	}
+	return getIDsFormKey(key, p.Page, p.Size)
+}
+
+// GetTrendingTags 查询统计窗口内新帖子使用次数最多的 size 个标签
+func GetTrendingTags(size int64) ([]*models.TrendingTag, error) {
+	key := getRedisKey(KeyTagTrendingZSet)
+	if client.Exists(ctx, key).Val() < 1 {
+		// 合并最近 tagTrendingHours 个小时的计数，包括当前小时
+		now := time.Now().Unix() / 3600
+		keys := make([]string, 0, tagTrendingHours)
+		for hour := now - tagTrendingHours + 1; hour <= now; hour++ {
+			keys = append(keys, getTagTrendingKey(hour))
+		}
+		pipeline := client.TxPipeline()
+		pipeline.ZUnionStore(ctx, key, &redis.ZStore{Keys: keys})
+		pipeline.Expire(ctx, key, 60*time.Second)
+		if _, err := pipeline.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+	// 帖子删除后计数可能减到 0，不返回
+	res, err := client.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min:   "(0",
+		Max:   "+inf",
+		Count: size,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]*models.TrendingTag, 0, len(res))
+	for _, z := range res {
+		data = append(data, &models.TrendingTag{
+			Tag:     z.Member.(string),
+			PostNum: int64(z.Score),
+		})
+	}
+	return data, nil
+}
@@ -0,0 +1,120 @@
+package redis
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Token 的状态都只在有效期内有意义，所有 key 都带过期时间，到期后自动清理
+
+var (
+	ErrorTokenReused       = errors.New("refresh token 已被使用")
+	ErrorEmailTokenInvalid = errors.New("令牌不存在或已被使用")
+)
+
+// SaveRefreshToken 记录当前有效的 Refresh Token
+func SaveRefreshToken(jti, familyID string, expiration time.Duration) error {
+	return client.Set(ctx, getRedisKey(KeyRefreshTokenPF+jti), familyID, expiration).Err()
+}
+
+// ConsumeRefreshToken 使用 Refresh Token，每个 Refresh Token 只能使用一次
+// GETDEL 保证并发刷新时只有一个请求能成功，其余请求视为重放
+func ConsumeRefreshToken(jti, familyID string) error {
+	fid, err := client.GetDel(ctx, getRedisKey(KeyRefreshTokenPF+jti)).Result()
+	if errors.Is(err, Nil) || (err == nil && fid != familyID) {
+		return ErrorTokenReused
+	}
+	return err
+}
+
+// RevokeTokenFamily 吊销整个 token 家族，该家族签发的所有 Token 都将失效
+func RevokeTokenFamily(familyID string, expiration time.Duration) error {
+	return client.Set(ctx, getRedisKey(KeyTokenFamilyRevoked+familyID), 1, expiration).Err()
+}
+
+func getUserTokenFamilyKey(userID int64) string {
+	return getRedisKey(KeyUserTokenFamilyZSetPF + strconv.FormatInt(userID, 10))
+}
+
+// AddUserTokenFamily 记录用户的 token 家族及其过期时间，刷新时延长过期时间，同时清理已过期的家族
+func AddUserTokenFamily(userID int64, familyID string, expiration time.Duration) error {
+	key := getUserTokenFamilyKey(userID)
+	now := time.Now()
+	pipeline := client.TxPipeline()
+	pipeline.ZAdd(ctx, key, redis.Z{Score: float64(now.Add(expiration).Unix()), Member: familyID})
+	pipeline.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Unix(), 10))
+	pipeline.Expire(ctx, key, expiration)
+	_, err := pipeline.Exec(ctx)
+	return err
+}
+
+// RevokeUserTokenFamilies 吊销用户所有未过期的 token 家族，所有设备上的 Token 都将失效
+func RevokeUserTokenFamilies(userID int64, expiration time.Duration) error {
+	key := getUserTokenFamilyKey(userID)
+	fids, err := client.ZRangeByScore(ctx, key, &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(time.Now().Unix(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil || len(fids) == 0 {
+		return err
+	}
+	members := make([]interface{}, 0, len(fids))
+	pipeline := client.TxPipeline()
+	for _, fid := range fids {
+		pipeline.Set(ctx, getRedisKey(KeyTokenFamilyRevoked+fid), 1, expiration)
+		members = append(members, fid)
+	}
+	// 只移除已吊销的家族，期间新登录开启的家族不受影响
+	pipeline.ZRem(ctx, key, members...)
+	_, err = pipeline.Exec(ctx)
+	return err
+}
+
+// BlacklistAccessToken 将 Access Token 加入黑名单，直到其自然过期
+func BlacklistAccessToken(jti string, expiration time.Duration) error {
+	if expiration <= 0 {
+		return nil
+	}
+	return client.Set(ctx, getRedisKey(KeyTokenBlacklistPF+jti), 1, expiration).Err()
+}
+
+// IsTokenFamilyRevoked 判断 token 家族是否已被吊销
+func IsTokenFamilyRevoked(familyID string) (bool, error) {
+	n, err := client.Exists(ctx, getRedisKey(KeyTokenFamilyRevoked+familyID)).Result()
+	return n > 0, err
+}
+
+// IsAccessTokenRevoked 判断 Access Token 是否已注销或所属家族已被吊销
+func IsAccessTokenRevoked(jti, familyID string) (bool, error) {
+	n, err := client.Exists(ctx,
+		getRedisKey(KeyTokenBlacklistPF+jti),
+		getRedisKey(KeyTokenFamilyRevoked+familyID)).Result()
+	return n > 0, err
+}
+
+// SaveEmailToken 记录邮件中发出的一次性令牌，值为签发给的用户ID和发送的邮箱
+func SaveEmailToken(purpose, nonce string, userID int64, email string, expiration time.Duration) error {
+	value := strconv.FormatInt(userID, 10) + ":" + email
+	return client.Set(ctx, getRedisKey(KeyEmailTokenPF+purpose+":"+nonce), value, expiration).Err()
+}
+
+// ConsumeEmailToken 使用一次性令牌，返回签发时记录的邮箱，每个令牌只能使用一次
+// 令牌中的用户ID必须与签发时记录的一致，不能只信任令牌本身
+func ConsumeEmailToken(purpose, nonce string, userID int64) (string, error) {
+	value, err := client.GetDel(ctx, getRedisKey(KeyEmailTokenPF+purpose+":"+nonce)).Result()
+	if errors.Is(err, Nil) {
+		return "", ErrorEmailTokenInvalid
+	}
+	if err != nil {
+		return "", err
+	}
+	uid, email, ok := strings.Cut(value, ":")
+	if !ok || uid != strconv.FormatInt(userID, 10) {
+		return "", ErrorEmailTokenInvalid
+	}
+	return email, nil
+}
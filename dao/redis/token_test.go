@@ -0,0 +1,38 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumeEmailToken(t *testing.T) {
+	mr.FlushAll()
+	require.NoError(t, SaveEmailToken("reset_password", "n1", 7, "a@example.com", time.Minute))
+	email, err := ConsumeEmailToken("reset_password", "n1", 7)
+	require.NoError(t, err)
+	assert.Equal(t, "a@example.com", email)
+	// 每个令牌只能使用一次
+	_, err = ConsumeEmailToken("reset_password", "n1", 7)
+	assert.ErrorIs(t, err, ErrorEmailTokenInvalid)
+
+	// 令牌中的用户ID与签发时记录的不一致
+	require.NoError(t, SaveEmailToken("reset_password", "n2", 7, "a@example.com", time.Minute))
+	_, err = ConsumeEmailToken("reset_password", "n2", 8)
+	assert.ErrorIs(t, err, ErrorEmailTokenInvalid)
+}
+
+func TestRevokeUserTokenFamilies(t *testing.T) {
+	mr.FlushAll()
+	require.NoError(t, AddUserTokenFamily(1, "f1", time.Hour))
+	require.NoError(t, AddUserTokenFamily(1, "f2", time.Hour))
+	require.NoError(t, AddUserTokenFamily(2, "f3", time.Hour))
+	require.NoError(t, RevokeUserTokenFamilies(1, time.Hour))
+	for fid, want := range map[string]bool{"f1": true, "f2": true, "f3": false} {
+		revoked, err := IsTokenFamilyRevoked(fid)
+		require.NoError(t, err)
+		assert.Equal(t, want, revoked, fid)
+	}
+}
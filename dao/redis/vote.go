@@ -2,8 +2,6 @@ package redis
 
 import (
 	"errors"
-	"fmt"
-	"math"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -19,6 +17,7 @@ import (
 2. direction = 0， 有两种情况：
   - 之前投过赞成票，现在要取消投票  --> 更新分数和投票记录   - 432 * 1
   - 之前投过反对票，现在要取消投票  --> 更新分数和投票记录   + 432 * 1
+  - 投票记录中保留方向为 0 的记录，持久化时把 MySQL 中的投票也改为 0
 
 3. direction = -1， 有两种情况：
   - 之前没有投过票，现在投反对票    --> 更新分数和投票记录   - 432 * 1
@@ -33,7 +32,6 @@ import (
 */
 const (
 	oneWeekInSeconds = 7 * 24 * 3600
-	scorePerVote     = 432 // 每一票的分数
 )
 
 var (
@@ -41,43 +39,52 @@ var (
 	ErrorVoteRepeat     = errors.New("不允许重复投票")
 )
 
-func VoteForPost(userID, postID string, direction float64) error {
-	// 1. 判断投票限制
-	// 获取帖子的发布时间
-	postTime := client.ZScore(ctx, getRedisKey(KeyPostTimeZSet), postID).Val()
-	fmt.Println("postTime: ", postTime)
-	if float64(time.Now().Unix())-postTime > oneWeekInSeconds {
-		// 超过一个星期 --> 不允许投票了
-		return ErrorVoteTimeExpire
-	}
-	// 2 和 3 需要放到同一个事务中进行操作
-	// 2. 更新分数
-	// 查询当前用户给该帖子的投票记录
-	ov := client.ZScore(ctx, getRedisKey(KeyPostVotedZSetPF+postID), userID).Val()
-	if ov == direction {
-		// 这一次投票的值和上一次投票的一致，就提示不允许重复投票
-		return ErrorVoteRepeat
+// postVoteScript 在一个脚本中完成投票的检查和更新，避免并发投票时重复计票
+// 投票变化同时计入作者的全局和社区 karma；取消投票记为 0 而不是删除，持久化时会覆盖 MySQL 中的投票记录
+// score 排序的分数只和票数有关，在脚本中按票数变化累加，与投票记录一起原子地更新；
+// 其它排序的分数由调用方根据投票记录重新计算
+// KEYS: 帖子时间 zset、帖子投票记录 zset、帖子分数 zset、全局 karma zset、社区 karma zset
+// ARGV: 帖子ID、用户ID、投票方向、当前时间、投票有效期（秒）、每票分数、作者ID
+// 返回 {状态, 赞成票数, 反对票数}，状态 0 成功、1 投票时间已过、2 重复投票
+var postVoteScript = redis.NewScript(`
+local postTime = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not postTime or tonumber(ARGV[4]) - tonumber(postTime) > tonumber(ARGV[5]) then
+	return {1, 0, 0}
+end
+local direction = tonumber(ARGV[3])
+local ov = tonumber(redis.call('ZSCORE', KEYS[2], ARGV[2]) or 0)
+if ov == direction then
+	return {2, 0, 0}
+end
+redis.call('ZINCRBY', KEYS[3], (direction - ov) * tonumber(ARGV[6]), ARGV[1])
+redis.call('ZADD', KEYS[2], direction, ARGV[2])
+redis.call('ZINCRBY', KEYS[4], direction - ov, ARGV[7])
+redis.call('ZINCRBY', KEYS[5], direction - ov, ARGV[7])
+return {0, redis.call('ZCOUNT', KEYS[2], 1, 1), redis.call('ZCOUNT', KEYS[2], -1, -1)}
+`)
+
+// VoteForPost 为帖子投票，返回投票后帖子的赞成票数和反对票数
+// authorID 和 communityID 为帖子的作者和所属社区，用于更新作者的 karma；scorePerVote 为 score 排序中每一票的分数
+func VoteForPost(userID, postID string, authorID, communityID int64, direction, scorePerVote float64) (up, down int64, err error) {
+	keys := []string{
+		getRedisKey(KeyPostTimeZSet),
+		getRedisKey(KeyPostVotedZSetPF + postID),
+		getRedisKey(KeyPostScoreZSet),
+		getRedisKey(KeyUserKarmaZSet),
+		getCommunityKarmaKey(communityID),
 	}
-	var op float64
-	if direction > ov {
-		// 现在的值 > 之前的值
-		op = 1
-	} else {
-		op = -1
+	res, err := postVoteScript.Run(ctx, client, keys,
+		postID, userID, direction, time.Now().Unix(), oneWeekInSeconds, scorePerVote, authorID).Int64Slice()
+	if err != nil {
+		return 0, 0, err
 	}
-	diff := op * math.Abs(ov-direction) // 计算两次投票的差值
-
-	pipline := client.TxPipeline() // 开启事务
-	pipline.ZIncrBy(ctx, getRedisKey(KeyPostScoreZSet), diff*scorePerVote, postID)
-	// 3. 记录用户为该帖子投票的记录
-	if direction == 0 { // 移除投票记录
-		client.ZRem(ctx, getRedisKey(KeyPostVotedZSetPF+postID), userID)
-	} else {
-		pipline.ZAdd(ctx, getRedisKey(KeyPostVotedZSetPF+postID), redis.Z{
-			Score:  direction, // 分数 -- 赞成/反对
-			Member: userID,
-		})
+	switch res[0] {
+	case 1:
+		// 超过一个星期 --> 不允许投票了
+		return 0, 0, ErrorVoteTimeExpire
+	case 2:
+		// 这一次投票的值和上一次投票的一致，就提示不允许重复投票
+		return 0, 0, ErrorVoteRepeat
 	}
-	_, err := pipline.Exec(ctx)
-	return err
+	return res[1], res[2], nil
 }
@@ -0,0 +1,48 @@
+package redis
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVoteForPostScore(t *testing.T) {
+	mr.FlushAll()
+	addTestPost(t, 1, 1, time.Now().Unix(), 0)
+
+	// 并发投票时 score 与投票记录一起更新，不会丢失
+	var wg sync.WaitGroup
+	for i := 1; i <= 30; i++ {
+		wg.Add(1)
+		go func(uid int) {
+			defer wg.Done()
+			direction := 1.0
+			if uid%3 == 0 {
+				direction = -1
+			}
+			_, _, err := VoteForPost(strconv.Itoa(uid), "1", 100, 1, direction, 432)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+	score, err := client.ZScore(ctx, getRedisKey(KeyPostScoreZSet), "1").Result()
+	require.NoError(t, err)
+	assert.Equal(t, float64((20-10)*432), score)
+
+	// 改投和取消投票按票数变化调整分数
+	up, down, err := VoteForPost("1", "1", 100, 1, -1, 432)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{19, 11}, []int64{up, down})
+	_, _, err = VoteForPost("3", "1", 100, 1, 0, 432)
+	require.NoError(t, err)
+	score, err = client.ZScore(ctx, getRedisKey(KeyPostScoreZSet), "1").Result()
+	require.NoError(t, err)
+	assert.Equal(t, float64((19-10)*432), score)
+
+	_, _, err = VoteForPost("3", "1", 100, 1, 0, 432)
+	assert.ErrorIs(t, err, ErrorVoteRepeat)
+}
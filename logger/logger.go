@@ -5,6 +5,7 @@ import (
 	"net"
 	"net/http"
 	"net/http/httputil"
+	"net/url"
 	"os"
 	"runtime/debug"
 	"strings"
@@ -72,7 +73,7 @@ func GinLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
+		query := redactQuery(c.Request.URL.RawQuery)
 		c.Next()
 
 		cost := time.Since(start)
@@ -89,6 +90,19 @@ func GinLogger() gin.HandlerFunc {
 	}
 }
 
+// redactQuery 隐藏查询参数中的 Access Token（实时推送接口允许放在 URL 中），避免写入日志
+func redactQuery(raw string) string {
+	if !strings.Contains(raw, "access_token=") {
+		return raw
+	}
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return ""
+	}
+	values.Set("access_token", "***")
+	return values.Encode()
+}
+
 // GinRecovery recover掉项目可能出现的panic，并使用zap记录相关日志
 func GinRecovery(stack bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
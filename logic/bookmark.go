@@ -0,0 +1,149 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 收藏
+// 收藏和取消收藏只写 Redis，由持久化任务定期写入 MySQL。
+
+const (
+	bookmarkCursorOrder = "bookmark" // 收藏列表游标中的排序方式
+	maxBookmarkPageSize = 50
+)
+
+// ensureBookmarksLoaded Redis 中没有用户的收藏时从 MySQL 加载
+func ensureBookmarksLoaded(userID int64) error {
+	loaded, err := redis.BookmarksLoaded(userID)
+	if err != nil {
+		zap.L().Error("redis.BookmarksLoaded failed", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	if loaded {
+		return nil
+	}
+	data, err := mysql.GetBookmarks(userID)
+	if err != nil {
+		zap.L().Error("mysql.GetBookmarks failed", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	if err = redis.LoadBookmarks(userID, data); err != nil {
+		zap.L().Error("redis.LoadBookmarks failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+	return err
+}
+
+// BookmarkPost 收藏帖子，重复收藏不修改收藏时间
+func BookmarkPost(userID, postID int64) error {
+	post, err := mysql.GetPostByID(postID)
+	if err != nil {
+		zap.L().Error("mysql.GetPostByID failed", zap.Int64("id", postID), zap.Error(err))
+		return err
+	}
+	if post.ID == 0 {
+		return mysql.ErrorInvalidID
+	}
+	if err = ensureBookmarksLoaded(userID); err != nil {
+		return err
+	}
+	if _, err = redis.AddBookmark(userID, postID, time.Now().UnixMilli()); err != nil {
+		zap.L().Error("redis.AddBookmark failed", zap.Int64("user_id", userID), zap.Int64("post_id", postID), zap.Error(err))
+	}
+	return err
+}
+
+// UnbookmarkPost 取消收藏，没有收藏过也返回成功
+func UnbookmarkPost(userID, postID int64) error {
+	if err := ensureBookmarksLoaded(userID); err != nil {
+		return err
+	}
+	if _, err := redis.RemoveBookmark(userID, postID); err != nil {
+		zap.L().Error("redis.RemoveBookmark failed", zap.Int64("user_id", userID), zap.Int64("post_id", postID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetBookmarks 按收藏时间从新到旧分页查询收藏的帖子，已删除的帖子不返回
+func GetBookmarks(userID int64, p *models.ParamBookmarkList) (page *models.ApiPostPage, err error) {
+	if p.Size <= 0 || p.Size > maxBookmarkPageSize {
+		p.Size = maxBookmarkPageSize
+	}
+	cursor := &postCursor{Order: bookmarkCursorOrder}
+	if p.Cursor != "" {
+		if cursor, err = decodePostCursor(p.Cursor, bookmarkCursorOrder); err != nil {
+			return nil, err
+		}
+	}
+	if err = ensureBookmarksLoaded(userID); err != nil {
+		return nil, err
+	}
+	// 多查一个，用来判断是否还有下一页
+	ids, saveTimes, err := redis.GetBookmarkIDsAfter(userID, cursor.Score, cursor.PostID, p.Size+1)
+	if err != nil {
+		zap.L().Error("redis.GetBookmarkIDsAfter failed", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	page = &models.ApiPostPage{List: make([]*models.ApiPostDetail, 0)}
+	if int64(len(ids)) > p.Size {
+		page.HasMore = true
+		ids, saveTimes = ids[:p.Size], saveTimes[:p.Size]
+	}
+	if len(ids) == 0 {
+		return page, nil
+	}
+	if page.HasMore {
+		last := len(ids) - 1
+		page.NextCursor = (&postCursor{Order: bookmarkCursorOrder, Score: saveTimes[last], PostID: ids[last]}).encode()
+	}
+	data, err := getPostListByIDs(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		page.List = data
+	}
+	return page, nil
+}
+
+// persistBookmarks 把收藏有变化的用户的收藏写入 MySQL，每批最多 batchSize 个用户
+func persistBookmarks(batchSize int, timeout time.Duration) error {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		n, err := persistBookmarkBatch(ctx, batchSize)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if n < batchSize {
+			return nil
+		}
+	}
+}
+
+// persistBookmarkBatch 持久化一批用户的收藏，返回处理的用户数
+// 写入 MySQL 成功后才把这批用户标记为完成，失败时下次持久化重新处理
+func persistBookmarkBatch(ctx context.Context, batchSize int) (n int, err error) {
+	userIDs, err := redis.ClaimDirtyBookmarkUsers(ctx, batchSize)
+	if err != nil || len(userIDs) == 0 {
+		return 0, err
+	}
+	data, err := redis.FetchBookmarks(ctx, userIDs)
+	if err != nil {
+		return 0, err
+	}
+	if err = mysql.PersistBookmarks(data); err != nil {
+		return 0, err
+	}
+	if err = redis.FinishBookmarkUsers(ctx, userIDs); err != nil {
+		zap.L().Error("redis.FinishBookmarkUsers failed", zap.Int64s("user_ids", userIDs), zap.Error(err))
+		return 0, err
+	}
+	return len(userIDs), nil
+}
@@ -0,0 +1,150 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"bluebell/pkg/snowflake"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// CreateComment 发表评论
+func CreateComment(c *models.Comment) (err error) {
+	// 1. 校验帖子是否存在
+	post, err := mysql.GetPostByID(c.PostID)
+	if err != nil {
+		zap.L().Error("mysql.GetPostByID failed", zap.Int64("post_id", c.PostID), zap.Error(err))
+		return
+	}
+	if post.ID == 0 {
+		return mysql.ErrorInvalidID
+	}
+	// 锁定的帖子不能评论，被封禁的用户不能在社区中评论
+	if post.Locked {
+		return ErrorPostLocked
+	}
+	if err = checkUserNotBanned(post.CommunityID, c.AuthorID); err != nil {
+		return
+	}
+	// 2. 校验父评论：必须属于同一个帖子，回复挂在父评论所在的楼层下
+	var parentAuthorID int64
+	if c.ParentID != 0 {
+		parent, err := mysql.GetCommentByID(c.ParentID)
+		if err != nil {
+			zap.L().Error("mysql.GetCommentByID failed", zap.Int64("parent_id", c.ParentID), zap.Error(err))
+			return err
+		}
+		if parent.PostID != c.PostID {
+			return mysql.ErrorInvalidID
+		}
+		c.RootID = parent.RootID
+		if c.RootID == 0 {
+			c.RootID = parent.ID
+		}
+		parentAuthorID = parent.AuthorID
+	}
+	// 3. 生成评论ID并保存
+	c.ID = snowflake.GenID()
+	if err = mysql.CreateComment(c); err != nil {
+		zap.L().Error("mysql.CreateComment failed", zap.Any("comment", c), zap.Error(err))
+		return
+	}
+	// 评论已保存，之后的步骤失败只记录日志，避免客户端重试产生重复评论
+	if err := redis.CreateComment(c.ID); err != nil {
+		zap.L().Error("redis.CreateComment failed", zap.Any("comment", c), zap.Error(err))
+	}
+	events.Publish(CommentCreatedEvent{Comment: c, Post: post, ParentAuthorID: parentAuthorID})
+	return
+}
+
+// GetCommentList 分页获取帖子下的评论，每个顶层评论带上其楼层内的所有回复
+func GetCommentList(postID int64, p *models.ParamCommentList) (data []*models.ApiCommentDetail, err error) {
+	// 1. 查询当前页的顶层评论
+	roots, err := mysql.GetRootCommentList(postID, p.Page, p.Size)
+	if err != nil {
+		zap.L().Error("mysql.GetRootCommentList failed", zap.Int64("post_id", postID), zap.Error(err))
+		return
+	}
+	data = make([]*models.ApiCommentDetail, 0, len(roots))
+	if len(roots) == 0 {
+		return
+	}
+	// 2. 批量查询这些楼层下的回复
+	rootIDs := make([]int64, 0, len(roots))
+	for _, root := range roots {
+		rootIDs = append(rootIDs, root.ID)
+	}
+	replies, err := mysql.GetCommentsByRootIDs(rootIDs)
+	if err != nil {
+		zap.L().Error("mysql.GetCommentsByRootIDs failed", zap.Error(err))
+		return
+	}
+	comments := make([]*models.Comment, 0, len(roots)+len(replies))
+	comments = append(comments, roots...)
+	comments = append(comments, replies...)
+
+	// 3. 查询投票数据
+	ids := make([]string, 0, len(comments))
+	for _, c := range comments {
+		ids = append(ids, strconv.FormatInt(c.ID, 10))
+	}
+	up, down, err := redis.GetCommentVoteData(ids)
+	if err != nil {
+		zap.L().Error("redis.GetCommentVoteData failed", zap.Error(err))
+		return
+	}
+
+	// 4. 批量查询评论作者
+	authorIDs := make([]int64, 0, len(comments))
+	seen := make(map[int64]struct{}, len(comments))
+	for _, c := range comments {
+		if _, ok := seen[c.AuthorID]; ok {
+			continue
+		}
+		seen[c.AuthorID] = struct{}{}
+		authorIDs = append(authorIDs, c.AuthorID)
+	}
+	users, err := mysql.GetUsersByIDs(authorIDs)
+	if err != nil {
+		zap.L().Error("mysql.GetUsersByIDs failed", zap.Error(err))
+		return
+	}
+
+	// 5. 组装评论详情，按 parent_id 挂到父评论下
+	details := make(map[int64]*models.ApiCommentDetail, len(comments))
+	for idx, c := range comments {
+		var name string
+		if user, ok := users[c.AuthorID]; ok {
+			name = user.Username
+		}
+		details[c.ID] = &models.ApiCommentDetail{
+			AuthorName: name,
+			UpVotes:    up[idx],
+			DownVotes:  down[idx],
+			Comment:    c,
+			Children:   make([]*models.ApiCommentDetail, 0),
+		}
+	}
+	for _, c := range replies {
+		parent, ok := details[c.ParentID]
+		if !ok {
+			parent = details[c.RootID]
+		}
+		parent.Children = append(parent.Children, details[c.ID])
+	}
+	for _, root := range roots {
+		data = append(data, details[root.ID])
+	}
+	return
+}
+
+// VoteForComment 为评论投票
+func VoteForComment(userID int64, p *models.ParamCommentVoteData) error {
+	zap.L().Debug("logic.VoteForComment: ",
+		zap.Int64("userID", userID),
+		zap.String("commentID", p.CommentID),
+		zap.Int8("direction", p.Direction))
+	return redis.VoteForComment(strconv.FormatInt(userID, 10), p.CommentID, float64(p.Direction))
+}
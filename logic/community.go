@@ -2,9 +2,30 @@ package logic
 
 import (
 	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
 	"bluebell/models"
+	"database/sql"
+	"errors"
+	"regexp"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// communityCacheTTL 社区详情缓存的有效期，社区信息很少变化
+const communityCacheTTL = 5 * time.Minute
+
+var communities = newCommunityCache(communityCacheTTL, mysql.GetCommunityDetailsByIDs)
+
+var (
+	ErrorCommunityArchived = errors.New("社区已归档")
+	ErrorInvalidSlug       = errors.New("社区短名称只能包含小写字母、数字和连字符")
 )
 
+// slugPattern 社区短名称：小写字母或数字开头，只包含小写字母、数字和连字符
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
 // GetCommunityList 查询所有的社区（community_id, community_name）列表
 func GetCommunityList() (data []*models.Community, err error) {
 	// 查询所有的社区（community_id, community_name）列表
@@ -12,5 +33,221 @@ func GetCommunityList() (data []*models.Community, err error) {
 }
 
 func GetCommunityDetail(id int64) (*models.CommunityDetail, error) {
-	return mysql.GetCommunityDetailByID(id)
+	data, err := communities.GetMany([]int64{id})
+	if err != nil {
+		return nil, err
+	}
+	cd, ok := data[id]
+	if !ok {
+		return nil, mysql.ErrorInvalidID
+	}
+	return cd, nil
+}
+
+// CreateCommunity 创建社区，创建者自动成为社区的管理者
+func CreateCommunity(userID int64, p *models.ParamCreateCommunity) (data *models.CommunityDetail, err error) {
+	if !slugPattern.MatchString(p.Slug) {
+		return nil, ErrorInvalidSlug
+	}
+	data = &models.CommunityDetail{
+		Name:      p.Name,
+		Slug:      p.Slug,
+		Intro:     p.Introduction,
+		IconURL:   p.IconURL,
+		Rules:     p.Rules,
+		CreatorID: userID,
+	}
+	if err = mysql.CreateCommunity(data); err != nil {
+		zap.L().Error("mysql.CreateCommunity failed", zap.Any("community", p), zap.Error(err))
+		return nil, err
+	}
+	return data, nil
+}
+
+// UpdateCommunity 修改社区信息，创建者和版主可以修改
+func UpdateCommunity(userID, id int64, p *models.ParamUpdateCommunity) (err error) {
+	cd, err := getManagedCommunity(userID, id, models.CommunityRoleModerator)
+	if err != nil {
+		return
+	}
+	cd.Name, cd.Intro, cd.IconURL, cd.Rules = p.Name, p.Introduction, p.IconURL, p.Rules
+	if err = mysql.UpdateCommunity(cd); err != nil {
+		zap.L().Error("mysql.UpdateCommunity failed", zap.Int64("id", id), zap.Error(err))
+		return
+	}
+	communities.Invalidate(id)
+	return
+}
+
+// ArchiveCommunity 归档社区，只有创建者可以归档；归档后社区不再出现在列表中，也不能再发帖
+func ArchiveCommunity(userID, id int64) (err error) {
+	if _, err = getManagedCommunity(userID, id, models.CommunityRoleCreator); err != nil {
+		return
+	}
+	if err = mysql.ArchiveCommunity(id); err != nil {
+		zap.L().Error("mysql.ArchiveCommunity failed", zap.Int64("id", id), zap.Error(err))
+		return
+	}
+	communities.Invalidate(id)
+	return
+}
+
+// GetCommunityMembers 查询社区的创建者和版主
+func GetCommunityMembers(id int64) ([]*models.CommunityMember, error) {
+	if _, err := GetCommunityDetail(id); err != nil {
+		return nil, err
+	}
+	return mysql.GetCommunityMembers(id)
+}
+
+// AddCommunityModerator 设置版主，只有创建者可以设置
+func AddCommunityModerator(userID, id, moderatorID int64) (err error) {
+	if _, err = getManagedCommunity(userID, id, models.CommunityRoleCreator); err != nil {
+		return
+	}
+	if _, err = mysql.GetUserByID(moderatorID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return mysql.ErrorUserNotExist
+		}
+		return
+	}
+	return mysql.AddCommunityModerator(id, moderatorID)
+}
+
+// RemoveCommunityModerator 取消版主，只有创建者可以取消
+func RemoveCommunityModerator(userID, id, moderatorID int64) (err error) {
+	if _, err = getManagedCommunity(userID, id, models.CommunityRoleCreator); err != nil {
+		return
+	}
+	return mysql.RemoveCommunityModerator(id, moderatorID)
+}
+
+// JoinCommunity 加入社区，已归档的社区不能加入
+func JoinCommunity(userID, id int64) (err error) {
+	if err = checkCommunityWritable(id); err != nil {
+		return
+	}
+	if err = mysql.JoinCommunity(userID, id); err != nil {
+		zap.L().Error("mysql.JoinCommunity failed", zap.Int64("id", id), zap.Error(err))
+		return
+	}
+	clearFeedCache(userID)
+	return
+}
+
+// LeaveCommunity 退出社区
+func LeaveCommunity(userID, id int64) (err error) {
+	if _, err = GetCommunityDetail(id); err != nil {
+		return
+	}
+	if err = mysql.LeaveCommunity(userID, id); err != nil {
+		zap.L().Error("mysql.LeaveCommunity failed", zap.Int64("id", id), zap.Error(err))
+		return
+	}
+	clearFeedCache(userID)
+	return
+}
+
+// GetSubscribedCommunities 查询用户加入的社区
+func GetSubscribedCommunities(userID int64) ([]*models.Community, error) {
+	return mysql.GetSubscribedCommunities(userID)
+}
+
+// clearFeedCache 加入或退出社区后首页立即生效，删除失败时等缓存自然过期
+func clearFeedCache(userID int64) {
+	if err := redis.ClearFeedCache(userID); err != nil {
+		zap.L().Error("redis.ClearFeedCache failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+}
+
+// getManagedCommunity 查询未归档的社区，并检查用户在社区中的角色不低于 minRole
+func getManagedCommunity(userID, id int64, minRole int8) (*models.CommunityDetail, error) {
+	cd, err := mysql.GetCommunityDetailByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if cd.Status == models.CommunityStatusArchived {
+		return nil, ErrorCommunityArchived
+	}
+	role, err := mysql.GetCommunityMemberRole(id, userID)
+	if err != nil {
+		zap.L().Error("mysql.GetCommunityMemberRole failed", zap.Int64("id", id), zap.Error(err))
+		return nil, err
+	}
+	if role < minRole {
+		return nil, ErrorPermissionDenied
+	}
+	return cd, nil
+}
+
+// checkCommunityWritable 发帖前检查社区存在且未归档
+func checkCommunityWritable(id int64) error {
+	cd, err := GetCommunityDetail(id)
+	if err != nil {
+		return err
+	}
+	if cd.Status == models.CommunityStatusArchived {
+		return ErrorCommunityArchived
+	}
+	return nil
+}
+
+// communityCache 社区详情的进程内只读缓存，未命中时批量回源并写入缓存
+type communityCache struct {
+	mu    sync.RWMutex
+	ttl   time.Duration
+	items map[int64]*communityCacheItem
+	load  func(ids []int64) (map[int64]*models.CommunityDetail, error)
+}
+
+type communityCacheItem struct {
+	data     *models.CommunityDetail
+	expireAt time.Time
+}
+
+func newCommunityCache(ttl time.Duration,
+	load func(ids []int64) (map[int64]*models.CommunityDetail, error)) *communityCache {
+	return &communityCache{
+		ttl:   ttl,
+		items: make(map[int64]*communityCacheItem),
+		load:  load,
+	}
+}
+
+// GetMany 批量查询社区详情，不存在的社区不会出现在结果中
+func (c *communityCache) GetMany(ids []int64) (map[int64]*models.CommunityDetail, error) {
+	data := make(map[int64]*models.CommunityDetail, len(ids))
+	misses := make([]int64, 0)
+	now := time.Now()
+	c.mu.RLock()
+	for _, id := range ids {
+		if item, ok := c.items[id]; ok && now.Before(item.expireAt) {
+			data[id] = item.data
+			continue
+		}
+		misses = append(misses, id)
+	}
+	c.mu.RUnlock()
+	if len(misses) == 0 {
+		return data, nil
+	}
+
+	loaded, err := c.load(misses)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	for id, cd := range loaded {
+		c.items[id] = &communityCacheItem{data: cd, expireAt: now.Add(c.ttl)}
+		data[id] = cd
+	}
+	c.mu.Unlock()
+	return data, nil
+}
+
+// Invalidate 社区信息修改后使缓存失效
+func (c *communityCache) Invalidate(id int64) {
+	c.mu.Lock()
+	delete(c.items, id)
+	c.mu.Unlock()
 }
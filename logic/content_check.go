@@ -0,0 +1,436 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"bluebell/pkg/sensitive"
+	"bluebell/setting"
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap"
+)
+
+// 发帖内容检查
+// 帖子保存前（发帖和编辑）依次经过注册的检查，每个检查可以放行、屏蔽敏感词（直接修改帖子）、转人工审核或拒绝。
+// 拒绝时立即停止；转人工审核时继续执行后面的检查，以便屏蔽敏感词和累计原因。
+// 检查本身出错（如 Redis 不可用）时只记录日志并跳过该检查，不影响发帖；
+// 实现了 failClosedCheck 的检查（如新账号发帖配额）出错时按 OnError 的结果处理，不能因为依赖不可用而失效。
+// 检查时可以预占资源（如发帖配额），帖子被拒绝、转人工审核或保存失败时通过 Release 归还。
+
+// ContentAction 内容检查的处理方式，值越大越严格
+type ContentAction int
+
+const (
+	ContentPass   ContentAction = iota // 放行
+	ContentMask                        // 屏蔽敏感词后放行
+	ContentHold                        // 转人工审核，审核通过前不公开
+	ContentReject                      // 拒绝
+)
+
+const (
+	maskRune         = '*'
+	maxReviewReasons = 256            // 保存的审核原因的最大长度（字符数）
+	postQuotaWindow  = 24 * time.Hour // 新账号发帖配额的统计窗口
+)
+
+var ErrorContentRejected = errors.New("内容未通过审核")
+
+// ContentVerdict 一次检查的结果，Reason 为转人工审核或拒绝的原因
+// Reserved 表示检查预占了资源，帖子未能发布时需要调用检查的 Release 归还
+type ContentVerdict struct {
+	Action   ContentAction
+	Reason   string
+	Reserved bool
+}
+
+// ContentCheck 发帖内容检查，屏蔽敏感词时直接修改 p 的标题和内容
+type ContentCheck interface {
+	Name() string
+	Check(p *models.Post) (ContentVerdict, error)
+}
+
+// newPostOnlyCheck 只对新帖子执行的检查，编辑帖子时跳过
+type newPostOnlyCheck interface {
+	newPostOnly()
+}
+
+// failClosedCheck 出错时不跳过的检查，返回出错时的处理方式
+type failClosedCheck interface {
+	OnError(err error) ContentVerdict
+}
+
+// reservingCheck 检查时预占资源的检查，帖子未能发布时归还
+type reservingCheck interface {
+	ContentCheck
+	Release(p *models.Post) error
+}
+
+// contentReservations 一次检查中预占了资源的检查
+type contentReservations []reservingCheck
+
+// release 归还预占的资源，失败只记录日志
+func (rs contentReservations) release(p *models.Post) {
+	for _, r := range rs {
+		if err := r.Release(p); err != nil {
+			zap.L().Error("content check release failed",
+				zap.String("check", r.Name()),
+				zap.Int64("author_id", p.AuthorID),
+				zap.Error(err))
+		}
+	}
+}
+
+type contentPipeline struct {
+	checks []ContentCheck
+}
+
+var contentChecks = new(contentPipeline)
+
+// RegisterContentCheck 注册内容检查，按注册顺序执行，需要在启动时调用
+func RegisterContentCheck(c ContentCheck) {
+	contentChecks.checks = append(contentChecks.checks, c)
+}
+
+// run 依次执行检查，返回最严格的处理方式和预占了资源的检查；转人工审核时 Reason 为所有检查的原因，
+// edit 表示检查的是编辑后的帖子。拒绝时已经归还预占的资源
+func (pl *contentPipeline) run(p *models.Post, edit bool) (ContentVerdict, contentReservations) {
+	res := ContentVerdict{Action: ContentPass}
+	var reasons []string
+	var reserved contentReservations
+	for _, c := range pl.checks {
+		if _, ok := c.(newPostOnlyCheck); ok && edit {
+			continue
+		}
+		v, err := c.Check(p)
+		if err != nil {
+			zap.L().Error("content check failed",
+				zap.String("check", c.Name()),
+				zap.Int64("author_id", p.AuthorID),
+				zap.Error(err))
+			fc, ok := c.(failClosedCheck)
+			if !ok {
+				continue
+			}
+			v = fc.OnError(err)
+		}
+		if r, ok := c.(reservingCheck); ok && v.Reserved {
+			reserved = append(reserved, r)
+		}
+		if v.Action == ContentReject {
+			reserved.release(p)
+			v.Reserved = false
+			return v, nil
+		}
+		if v.Action == ContentHold && v.Reason != "" {
+			reasons = append(reasons, v.Reason)
+		}
+		res.Action = max(res.Action, v.Action)
+	}
+	if res.Action == ContentHold {
+		res.Reason = strings.Join(reasons, "; ")
+	}
+	return res, reserved
+}
+
+// moderatePost 对新帖子或编辑后的帖子执行内容检查，需要转人工审核时返回审核记录并把帖子设为待审核
+// 放行时返回预占了资源的检查，帖子保存失败时由调用方归还；拒绝或转人工审核时已经归还
+func moderatePost(p *models.Post, edit bool) (review *models.PostReview, reserved contentReservations, err error) {
+	p.Status = models.PostStatusNormal
+	v, reserved := contentChecks.run(p, edit)
+	switch v.Action {
+	case ContentReject:
+		zap.L().Info("post rejected by content check",
+			zap.Int64("author_id", p.AuthorID),
+			zap.String("reason", v.Reason))
+		return nil, nil, fmt.Errorf("%w：%s", ErrorContentRejected, v.Reason)
+	case ContentHold:
+		reserved.release(p)
+		p.Status = models.PostStatusPending
+		reason := v.Reason
+		if r := []rune(reason); len(r) > maxReviewReasons {
+			reason = string(r[:maxReviewReasons])
+		}
+		kind := models.ReviewKindCreate
+		if edit {
+			kind = models.ReviewKindEdit
+		}
+		return &models.PostReview{
+			PostID:      p.ID,
+			CommunityID: p.CommunityID,
+			AuthorID:    p.AuthorID,
+			Kind:        kind,
+			Reasons:     reason,
+		}, nil, nil
+	}
+	return nil, reserved, nil
+}
+
+// InitModeration 根据配置注册内置的内容检查：敏感词、链接、重复字符、新账号发帖配额
+func InitModeration(cfg *setting.ModerationConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	defaultAction := ContentMask
+	if cfg.DefaultAction != "" {
+		action, err := parseContentAction(cfg.DefaultAction)
+		if err != nil {
+			return err
+		}
+		defaultAction = action
+	}
+	words, actions, err := loadLexicon(cfg.WordFiles, defaultAction)
+	if err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		RegisterContentCheck(&sensitiveWordCheck{matcher: sensitive.New(words), actions: actions})
+	}
+	if cfg.MaxLinks > 0 || len(cfg.BlockedDomains) > 0 {
+		RegisterContentCheck(newLinkCheck(cfg.MaxLinks, cfg.BlockedDomains))
+	}
+	if cfg.MaxRepeat > 0 {
+		RegisterContentCheck(&repeatCheck{maxRepeat: cfg.MaxRepeat})
+	}
+	if cfg.NewAccountDays > 0 && cfg.NewAccountDailyPosts > 0 {
+		RegisterContentCheck(&newAccountQuotaCheck{
+			age:   time.Duration(cfg.NewAccountDays) * 24 * time.Hour,
+			limit: cfg.NewAccountDailyPosts,
+		})
+	}
+	zap.L().Info("init content moderation success",
+		zap.Int("words", len(words)),
+		zap.Int("checks", len(contentChecks.checks)))
+	return nil
+}
+
+func parseContentAction(s string) (ContentAction, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "mask":
+		return ContentMask, nil
+	case "hold":
+		return ContentHold, nil
+	case "reject":
+		return ContentReject, nil
+	}
+	return ContentPass, fmt.Errorf("unknown content action: %q", s)
+}
+
+// loadLexicon 读取敏感词库，每行一个词，可以写成 "词,动作"；空行和 # 开头的行被忽略
+func loadLexicon(files []string, defaultAction ContentAction) (words []string, actions []ContentAction, err error) {
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		line := 0
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			action := defaultAction
+			if word, a, ok := strings.Cut(text, ","); ok {
+				if action, err = parseContentAction(a); err != nil {
+					_ = f.Close()
+					return nil, nil, fmt.Errorf("%s:%d: %w", name, line, err)
+				}
+				text = strings.TrimSpace(word)
+			}
+			words = append(words, text)
+			actions = append(actions, action)
+		}
+		err = scanner.Err()
+		_ = f.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return words, actions, nil
+}
+
+// sensitiveWordCheck 敏感词过滤，按词库中每个词的动作处理，需要屏蔽的词替换成 *
+type sensitiveWordCheck struct {
+	matcher *sensitive.Matcher
+	actions []ContentAction
+}
+
+func (c *sensitiveWordCheck) Name() string { return "sensitive_word" }
+
+func (c *sensitiveWordCheck) Check(p *models.Post) (ContentVerdict, error) {
+	res := ContentVerdict{Action: ContentPass}
+	found := make(map[ContentAction][]string)
+	seen := make(map[int]struct{})
+	mask := func(text string) string {
+		matches := c.matcher.FindAll(text)
+		masked := matches[:0]
+		for _, m := range matches {
+			action := c.actions[m.Index]
+			res.Action = max(res.Action, action)
+			if action == ContentMask {
+				masked = append(masked, m)
+			}
+			if _, ok := seen[m.Index]; !ok {
+				seen[m.Index] = struct{}{}
+				found[action] = append(found[action], c.matcher.Word(m.Index))
+			}
+		}
+		return sensitive.Mask(text, masked, maskRune)
+	}
+	p.Title = mask(p.Title)
+	p.Content = mask(p.Content)
+	switch res.Action {
+	case ContentReject:
+		res.Reason = "包含违禁词：" + strings.Join(found[ContentReject], "、")
+	case ContentHold:
+		res.Reason = "包含敏感词：" + strings.Join(found[ContentHold], "、")
+	}
+	return res, nil
+}
+
+// linkPattern 文本中的链接，以 http(s):// 或 www. 开头，到空白或中文标点为止
+var linkPattern = regexp.MustCompile(`(?i)(?:https?://|www\.)[^\s<>"'，。；！？、（）【】]+`)
+
+// linkCheck 链接检查：包含被禁止的域名时拒绝，链接过多时转人工审核
+type linkCheck struct {
+	maxLinks int
+	blocked  []string
+}
+
+func newLinkCheck(maxLinks int, blocked []string) *linkCheck {
+	c := &linkCheck{maxLinks: maxLinks}
+	for _, d := range blocked {
+		if d = strings.Trim(strings.ToLower(strings.TrimSpace(d)), "."); d != "" {
+			c.blocked = append(c.blocked, d)
+		}
+	}
+	return c
+}
+
+func (c *linkCheck) Name() string { return "link" }
+
+func (c *linkCheck) Check(p *models.Post) (ContentVerdict, error) {
+	links := linkPattern.FindAllString(p.Title+"\n"+p.Content, -1)
+	for _, link := range links {
+		host := linkHost(link)
+		for _, d := range c.blocked {
+			if host == d || strings.HasSuffix(host, "."+d) {
+				return ContentVerdict{Action: ContentReject, Reason: "包含被禁止的链接：" + host}, nil
+			}
+		}
+	}
+	if c.maxLinks > 0 && len(links) > c.maxLinks {
+		return ContentVerdict{Action: ContentHold, Reason: fmt.Sprintf("链接过多（%d 个）", len(links))}, nil
+	}
+	return ContentVerdict{Action: ContentPass}, nil
+}
+
+// linkHost 提取链接的域名（小写，不含端口）
+func linkHost(link string) string {
+	host := strings.ToLower(link)
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.IndexAny(host, "/?#"); i >= 0 {
+		host = host[:i]
+	}
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	if i := strings.Index(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// repeatCheck 刷屏检查：同一个字符连续重复过多时转人工审核，空白不计
+type repeatCheck struct {
+	maxRepeat int
+}
+
+func (c *repeatCheck) Name() string { return "repeat" }
+
+func (c *repeatCheck) Check(p *models.Post) (ContentVerdict, error) {
+	if longestRun(p.Title) > c.maxRepeat || longestRun(p.Content) > c.maxRepeat {
+		return ContentVerdict{Action: ContentHold, Reason: "包含大量重复字符"}, nil
+	}
+	return ContentVerdict{Action: ContentPass}, nil
+}
+
+// longestRun 同一个非空白字符连续出现的最大次数
+func longestRun(s string) int {
+	var prev rune
+	longest, run := 0, 0
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		if r == prev {
+			run++
+		} else {
+			prev, run = r, 1
+		}
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+// newAccountQuotaCheck 注册不满 age 的账号在 24 小时内最多发布 limit 篇帖子
+// 检查时原子地占用一个名额，超出配额时立即归还并拒绝；帖子被拒绝、转人工审核或保存失败时归还名额
+type newAccountQuotaCheck struct {
+	age   time.Duration
+	limit int64
+}
+
+func (c *newAccountQuotaCheck) Name() string { return "new_account_quota" }
+
+func (c *newAccountQuotaCheck) newPostOnly() {}
+
+// isNewAccount 用户是否是注册不满 age 的新账号
+func (c *newAccountQuotaCheck) isNewAccount(userID int64) (bool, error) {
+	createTime, err := mysql.GetUserCreateTime(userID)
+	if err != nil {
+		return false, err
+	}
+	return !createTime.IsZero() && time.Since(createTime) < c.age, nil
+}
+
+func (c *newAccountQuotaCheck) Check(p *models.Post) (ContentVerdict, error) {
+	isNew, err := c.isNewAccount(p.AuthorID)
+	if err != nil || !isNew {
+		return ContentVerdict{Action: ContentPass}, err
+	}
+	n, err := redis.IncrPostQuota(p.AuthorID, postQuotaWindow)
+	if err != nil {
+		return ContentVerdict{}, err
+	}
+	if n > c.limit {
+		if err := redis.DecrPostQuota(p.AuthorID); err != nil {
+			zap.L().Error("redis.DecrPostQuota failed", zap.Int64("author_id", p.AuthorID), zap.Error(err))
+		}
+		return ContentVerdict{
+			Action: ContentReject,
+			Reason: fmt.Sprintf("新账号 24 小时内最多发布 %d 篇帖子", c.limit),
+		}, nil
+	}
+	return ContentVerdict{Action: ContentPass, Reserved: true}, nil
+}
+
+// OnError 无法检查配额时转人工审核，Redis 不可用时新账号也不能绕过配额
+func (c *newAccountQuotaCheck) OnError(error) ContentVerdict {
+	return ContentVerdict{Action: ContentHold, Reason: "无法检查新账号发帖配额"}
+}
+
+// Release 归还 Check 中占用的名额
+func (c *newAccountQuotaCheck) Release(p *models.Post) error {
+	return redis.DecrPostQuota(p.AuthorID)
+}
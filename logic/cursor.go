@@ -0,0 +1,46 @@
+package logic
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// 帖子列表的游标
+// 游标记录上一页最后一篇帖子在排序 zset 中的 (分数, 帖子ID)，下一页从它之后开始查询，
+// 翻页期间有新帖子或新的投票也不会出现重复或遗漏。对客户端来说游标是不透明的字符串。
+
+var ErrorInvalidCursor = errors.New("无效的游标")
+
+type postCursor struct {
+	Order  string
+	Score  float64
+	PostID string
+}
+
+// encode 编码为 base64(order|score|post_id)
+func (c *postCursor) encode() string {
+	raw := c.Order + "|" + strconv.FormatFloat(c.Score, 'g', -1, 64) + "|" + c.PostID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodePostCursor 解析游标，order 与游标生成时的排序方式不一致时返回 ErrorInvalidCursor
+func decodePostCursor(s, order string) (*postCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrorInvalidCursor
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 || parts[0] != order {
+		return nil, ErrorInvalidCursor
+	}
+	score, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return nil, ErrorInvalidCursor
+	}
+	if _, err := strconv.ParseInt(parts[2], 10, 64); err != nil {
+		return nil, ErrorInvalidCursor
+	}
+	return &postCursor{Order: order, Score: score, PostID: parts[2]}, nil
+}
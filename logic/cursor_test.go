@@ -0,0 +1,23 @@
+package logic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostCursor(t *testing.T) {
+	c := &postCursor{Order: "score", Score: -864.5, PostID: "123456789"}
+	got, err := decodePostCursor(c.encode(), "score")
+	assert.NoError(t, err)
+	assert.Equal(t, c, got)
+
+	// 排序方式不一致
+	_, err = decodePostCursor(c.encode(), "time")
+	assert.ErrorIs(t, err, ErrorInvalidCursor)
+
+	for _, s := range []string{"", "!!!", "c2NvcmV8MXw", "c2NvcmV8eHwx"} {
+		_, err = decodePostCursor(s, "score")
+		assert.ErrorIs(t, err, ErrorInvalidCursor, s)
+	}
+}
@@ -0,0 +1,206 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"bluebell/pkg/mailer"
+	"bluebell/pkg/onetime"
+	"bluebell/setting"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 邮箱验证和找回密码
+// 邮件中的链接携带签名的一次性令牌，令牌的 nonce 在 Redis 中记录并设置有效期，使用一次后删除
+
+const (
+	emailPurposeVerify = "verify_email"
+	emailPurposeReset  = "reset_password"
+
+	defaultVerifyTTL = 24 * time.Hour
+	defaultResetTTL  = 30 * time.Minute
+)
+
+var (
+	ErrorInvalidEmailToken = errors.New("链接无效或已过期")
+	ErrorEmailNotSet       = errors.New("还没有设置邮箱")
+
+	errEmailTokenSecretMissing = errors.New("mail token secret is not loaded")
+)
+
+// emailTokenSecret 一次性令牌的签名密钥，启动时由 InitEmailToken 加载
+var emailTokenSecret []byte
+
+// InitEmailToken 加载一次性令牌的签名密钥，密钥缺失、是占位符或太短时返回错误，拒绝启动
+func InitEmailToken(cfg *setting.MailConfig) error {
+	if cfg == nil {
+		cfg = new(setting.MailConfig)
+	}
+	secret, err := setting.ResolveSecret("mail token secret", cfg.TokenSecret, cfg.TokenSecretEnv, cfg.TokenSecretFile)
+	if err != nil {
+		return err
+	}
+	emailTokenSecret = []byte(secret)
+	return nil
+}
+
+// mailConfig 邮件配置，未配置时使用默认值
+func mailConfig() *setting.MailConfig {
+	if cfg := setting.Conf.MailConfig; cfg != nil {
+		return cfg
+	}
+	return new(setting.MailConfig)
+}
+
+// ttlOrDefault 配置的有效期（秒），未配置时使用默认值
+func ttlOrDefault(seconds int, def time.Duration) time.Duration {
+	if seconds <= 0 {
+		return def
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// sendEmailToken 签发一次性令牌并发送包含链接的邮件
+// 邮件在后台发送，请求不会等待 SMTP 服务器，也不会因为发送耗时暴露邮箱是否存在
+func sendEmailToken(purpose string, userID int64, email string, ttl time.Duration, path, subject, body string) error {
+	if len(emailTokenSecret) == 0 {
+		return errEmailTokenSecretMissing
+	}
+	cfg := mailConfig()
+	token, claims, err := onetime.New(emailTokenSecret, purpose, userID)
+	if err != nil {
+		return err
+	}
+	if err = redis.SaveEmailToken(purpose, claims.Nonce, userID, email, ttl); err != nil {
+		zap.L().Error("redis.SaveEmailToken failed", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	link := strings.TrimRight(cfg.BaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+	msg := &mailer.Message{
+		To:      email,
+		Subject: subject,
+		Body:    fmt.Sprintf(body, link, ttl),
+	}
+	go func() {
+		if err := mailer.Send(msg); err != nil {
+			zap.L().Error("mailer.Send failed", zap.String("purpose", purpose), zap.Int64("user_id", userID), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// sendVerifyEmail 发送邮箱验证邮件
+func sendVerifyEmail(userID int64, email string) error {
+	return sendEmailToken(emailPurposeVerify, userID, email,
+		ttlOrDefault(mailConfig().VerifyTTL, defaultVerifyTTL),
+		"/verify-email", "验证你的 bluebell 邮箱",
+		"请打开下面的链接验证邮箱：\n%s\n\n链接在 %s 内有效。如果不是你本人操作，请忽略这封邮件。")
+}
+
+// consumeEmailToken 校验令牌并标记为已使用，返回令牌中的用户ID和签发时的邮箱
+func consumeEmailToken(purpose, token string) (userID int64, email string, err error) {
+	if len(emailTokenSecret) == 0 {
+		return 0, "", errEmailTokenSecretMissing
+	}
+	claims, err := onetime.Parse(emailTokenSecret, purpose, token)
+	if err != nil {
+		return 0, "", ErrorInvalidEmailToken
+	}
+	email, err = redis.ConsumeEmailToken(purpose, claims.Nonce, claims.UserID)
+	if err != nil {
+		if errors.Is(err, redis.ErrorEmailTokenInvalid) {
+			return 0, "", ErrorInvalidEmailToken
+		}
+		zap.L().Error("redis.ConsumeEmailToken failed", zap.Error(err))
+		return 0, "", err
+	}
+	return claims.UserID, email, nil
+}
+
+// VerifyEmail 使用邮件中的令牌验证邮箱，令牌签发后邮箱被修改过时验证失败
+func VerifyEmail(p *models.ParamEmailToken) error {
+	userID, email, err := consumeEmailToken(emailPurposeVerify, p.Token)
+	if err != nil {
+		return err
+	}
+	if err = mysql.SetEmailVerified(userID, email); err != nil {
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			return ErrorInvalidEmailToken
+		}
+		zap.L().Error("mysql.SetEmailVerified failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+	return err
+}
+
+// ResendVerifyEmail 重新发送邮箱验证邮件，邮箱已验证时不发送
+func ResendVerifyEmail(userID int64) error {
+	profile, err := mysql.GetUserProfile(userID)
+	if err != nil {
+		return err
+	}
+	if profile.Email == "" {
+		return ErrorEmailNotSet
+	}
+	if profile.EmailVerified {
+		return nil
+	}
+	return sendVerifyEmail(userID, profile.Email)
+}
+
+// ForgotPassword 发送重置密码邮件
+// 邮箱未注册时同样返回成功，避免通过这个接口枚举邮箱
+func ForgotPassword(p *models.ParamForgotPassword) error {
+	user, err := mysql.GetUserByEmail(p.Email)
+	if err != nil {
+		if errors.Is(err, mysql.ErrorUserNotExist) {
+			zap.L().Info("forgot password for unknown email")
+			return nil
+		}
+		zap.L().Error("mysql.GetUserByEmail failed", zap.Error(err))
+		return err
+	}
+	return sendEmailToken(emailPurposeReset, user.UserID, user.Email,
+		ttlOrDefault(mailConfig().ResetTTL, defaultResetTTL),
+		"/reset-password", "重置你的 bluebell 密码",
+		"请打开下面的链接重置密码：\n%s\n\n链接在 %s 内有效。如果不是你本人操作，请忽略这封邮件，你的密码不会被修改。")
+}
+
+// ResetPassword 使用邮件中的令牌重置密码
+// 能收到邮件说明用户拥有该邮箱，重置成功后同时把邮箱标记为已验证；
+// 重置密码通常是因为密码泄露，重置后吊销用户的所有 token 家族，并解除用户名的登录锁定
+func ResetPassword(p *models.ParamResetPassword) error {
+	userID, email, err := consumeEmailToken(emailPurposeReset, p.Token)
+	if err != nil {
+		return err
+	}
+	if err = mysql.SetEmailVerified(userID, email); err != nil {
+		if errors.Is(err, mysql.ErrorInvalidID) {
+			// 令牌签发后邮箱已被修改
+			return ErrorInvalidEmailToken
+		}
+		zap.L().Error("mysql.SetEmailVerified failed", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	if err = mysql.UpdatePassword(userID, p.Password); err != nil {
+		zap.L().Error("mysql.UpdatePassword failed", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	if err = revokeUserTokens(userID); err != nil {
+		return err
+	}
+	user, err := mysql.GetUserByID(userID)
+	if err != nil {
+		zap.L().Error("mysql.GetUserByID failed", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	if err = redis.UnlockLogin(user.Username, ""); err != nil {
+		zap.L().Error("redis.UnlockLogin failed", zap.String("username", user.Username), zap.Error(err))
+	}
+	return err
+}
@@ -0,0 +1,62 @@
+package logic
+
+import (
+	"bluebell/models"
+	"bluebell/pkg/eventbus"
+)
+
+// 业务事件
+// 发帖、投票、评论等操作完成后发布事件，通知等功能订阅事件，不需要修改产生事件的代码
+
+const (
+	TopicPostCreated    = "post.created"
+	TopicPostVoted      = "post.voted"
+	TopicCommentCreated = "comment.created"
+)
+
+// PostCreatedEvent 发布了新帖子
+type PostCreatedEvent struct {
+	Post *models.Post
+}
+
+func (PostCreatedEvent) Topic() string { return TopicPostCreated }
+
+// PostVotedEvent 帖子的投票发生了变化
+type PostVotedEvent struct {
+	Post      *models.Post
+	VoterID   int64
+	Direction int8
+	UpVotes   int64
+	DownVotes int64
+}
+
+func (PostVotedEvent) Topic() string { return TopicPostVoted }
+
+// CommentCreatedEvent 发布了新评论，ParentAuthorID 为被回复的评论的作者，直接回复帖子时为 0
+type CommentCreatedEvent struct {
+	Comment        *models.Comment
+	Post           *models.Post
+	ParentAuthorID int64
+}
+
+func (CommentCreatedEvent) Topic() string { return TopicCommentCreated }
+
+// eventBufferSize 事件队列长度，eventWorkers 处理事件的 worker 数
+const (
+	eventBufferSize = 4096
+	eventWorkers    = 4
+)
+
+var events = eventbus.New(eventBufferSize)
+
+// StartEvents 注册事件的订阅者并开始处理事件
+func StartEvents() {
+	subscribeNotifications(events)
+	subscribeStream(events)
+	events.Start(eventWorkers)
+}
+
+// StopEvents 停止处理事件，等待已发布的事件处理完
+func StopEvents() {
+	events.Close()
+}
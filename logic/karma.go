@@ -0,0 +1,72 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// 用户 karma：用户的帖子收到的赞成票减反对票
+
+const maxLeaderboardSize = 100
+
+// GetKarmaLeaderboard 查询 karma 排行榜，p.CommunityID 为 0 时查询全局排行榜
+func GetKarmaLeaderboard(p *models.ParamKarmaLeaderboard) (data []*models.KarmaRank, err error) {
+	if p.Size <= 0 || p.Size > maxLeaderboardSize {
+		p.Size = maxLeaderboardSize
+	}
+	if p.CommunityID != 0 {
+		if _, err = GetCommunityDetail(p.CommunityID); err != nil {
+			return nil, err
+		}
+	}
+	members, err := redis.GetKarmaLeaderboard(p.CommunityID, p.Size)
+	if err != nil {
+		zap.L().Error("redis.GetKarmaLeaderboard failed", zap.Int64("community_id", p.CommunityID), zap.Error(err))
+		return nil, err
+	}
+	data = make([]*models.KarmaRank, 0, len(members))
+	ids := make([]int64, 0, len(members))
+	for idx, m := range members {
+		uid, err := strconv.ParseInt(m.Member.(string), 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uid)
+		data = append(data, &models.KarmaRank{
+			Rank:   int64(idx + 1),
+			UserID: uid,
+			Karma:  int64(m.Score),
+		})
+	}
+	users, err := mysql.GetUsersByIDs(ids)
+	if err != nil {
+		zap.L().Error("mysql.GetUsersByIDs failed", zap.Error(err))
+		return nil, err
+	}
+	for _, r := range data {
+		if u, ok := users[r.UserID]; ok {
+			r.Username = u.Username
+			r.AvatarURL = u.AvatarURL
+		}
+	}
+	return data, nil
+}
+
+// reconcileKarma 用 MySQL 中持久化的投票记录重建 Redis 中的 karma，修正 Redis 数据丢失等原因造成的偏差
+func reconcileKarma() error {
+	data, err := mysql.GetUserKarma()
+	if err != nil {
+		zap.L().Error("mysql.GetUserKarma failed", zap.Error(err))
+		return err
+	}
+	if err = redis.ReplaceKarma(data); err != nil {
+		zap.L().Error("redis.ReplaceKarma failed", zap.Error(err))
+		return err
+	}
+	zap.L().Info("karma reconciled", zap.Int("rows", len(data)))
+	return nil
+}
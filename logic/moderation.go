@@ -0,0 +1,356 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"bluebell/pkg/rbac"
+	"database/sql"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 版主工具：删除、锁定、置顶帖子，在社区中封禁用户
+// 权限检查使用 MySQL 中当前的角色和版主身份（由 RequirePermission 中间件通过 GetPrincipal 加载），
+// 角色被撤销后立即失效，不需要等 Token 过期；每次操作都会写入管理日志
+
+// maxModerationPageSize 管理日志和审核队列每页的最大条数
+const maxModerationPageSize = 100
+
+var (
+	ErrorPostLocked  = errors.New("帖子已被锁定")
+	ErrorUserBanned  = errors.New("用户已被禁止在该社区发言")
+	ErrorInvalidRole = errors.New("无效的角色")
+)
+
+// GetPrincipal 从 MySQL 查询用户当前的全局角色和担任版主的社区
+func GetPrincipal(userID int64) (*rbac.Principal, error) {
+	user, err := mysql.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, mysql.ErrorUserNotExist
+		}
+		return nil, err
+	}
+	mods, err := mysql.GetModeratedCommunityIDs(userID)
+	if err != nil {
+		zap.L().Error("mysql.GetModeratedCommunityIDs failed", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	return &rbac.Principal{UserID: userID, Role: user.Role, Communities: mods}, nil
+}
+
+// clampModerationPage 修正分页参数，避免一次查询过多数据
+func clampModerationPage(page, size *int64) {
+	if *page < 1 {
+		*page = 1
+	}
+	if *size <= 0 || *size > maxModerationPageSize {
+		*size = maxModerationPageSize
+	}
+}
+
+// getModeratedPost 查询帖子并检查当前用户是否有管理该帖子所在社区的权限
+func getModeratedPost(actor *rbac.Principal, postID int64) (post *models.Post, err error) {
+	post, err = mysql.GetPostByID(postID)
+	if err != nil {
+		zap.L().Error("mysql.GetPostByID failed", zap.Int64("id", postID), zap.Error(err))
+		return nil, err
+	}
+	if post.ID == 0 {
+		return nil, mysql.ErrorInvalidID
+	}
+	if !actor.CanIn(rbac.PermPostModerate, post.CommunityID) {
+		return nil, ErrorPermissionDenied
+	}
+	return post, nil
+}
+
+// newPostModerationLog 帖子相关的管理日志
+func newPostModerationLog(actor *rbac.Principal, post *models.Post, action, reason string) *models.ModerationLog {
+	return &models.ModerationLog{
+		ActorID:     actor.UserID,
+		Action:      action,
+		CommunityID: post.CommunityID,
+		TargetType:  models.ModTargetPost,
+		TargetID:    post.ID,
+		Reason:      reason,
+	}
+}
+
+// RemovePost 版主删除帖子
+func RemovePost(actor *rbac.Principal, postID int64, p *models.ParamModeratePost) (err error) {
+	post, err := getModeratedPost(actor, postID)
+	if err != nil {
+		return
+	}
+	if err = mysql.RemovePost(newPostModerationLog(actor, post, models.ModActionPostRemove, p.Reason)); err != nil {
+		zap.L().Error("mysql.RemovePost failed", zap.Int64("id", postID), zap.Error(err))
+		return
+	}
+	removePostIndex(postID)
+	if err = redis.RemovePost(postID, post.CommunityID, post.AuthorID); err != nil {
+		zap.L().Error("redis.RemovePost failed", zap.Int64("id", postID), zap.Error(err))
+	}
+	removePostTags(post)
+	return
+}
+
+// LockPost 锁定或解锁帖子，锁定的帖子不能编辑和评论
+func LockPost(actor *rbac.Principal, postID int64, p *models.ParamLockPost) (err error) {
+	post, err := getModeratedPost(actor, postID)
+	if err != nil {
+		return
+	}
+	action := models.ModActionPostUnlock
+	if p.Locked {
+		action = models.ModActionPostLock
+	}
+	if err = mysql.SetPostLocked(newPostModerationLog(actor, post, action, p.Reason), p.Locked); err != nil {
+		zap.L().Error("mysql.SetPostLocked failed", zap.Int64("id", postID), zap.Error(err))
+	}
+	return
+}
+
+// PinPost 在社区中置顶或取消置顶帖子
+func PinPost(actor *rbac.Principal, postID int64, p *models.ParamPinPost) (err error) {
+	post, err := getModeratedPost(actor, postID)
+	if err != nil {
+		return
+	}
+	action := models.ModActionPostUnpin
+	if p.Pinned {
+		action = models.ModActionPostPin
+	}
+	if err = mysql.SetPostPinned(newPostModerationLog(actor, post, action, p.Reason), p.Pinned); err != nil {
+		zap.L().Error("mysql.SetPostPinned failed", zap.Int64("id", postID), zap.Error(err))
+		return
+	}
+	if err = redis.SetPostPinned(postID, post.CommunityID, p.Pinned); err != nil {
+		zap.L().Error("redis.SetPostPinned failed", zap.Int64("id", postID), zap.Error(err))
+	}
+	return
+}
+
+// BanUser 在社区中封禁用户，被封禁的用户不能在该社区发帖和评论
+func BanUser(actor *rbac.Principal, communityID int64, p *models.ParamBanUser) (err error) {
+	if !actor.CanIn(rbac.PermUserBan, communityID) {
+		return ErrorPermissionDenied
+	}
+	if _, err = GetCommunityDetail(communityID); err != nil {
+		return
+	}
+	if _, err = mysql.GetUserByID(p.UserID); err != nil {
+		return mysql.ErrorUserNotExist
+	}
+	var expire *time.Time
+	if p.Duration > 0 {
+		t := time.Now().Add(time.Duration(p.Duration) * time.Hour)
+		expire = &t
+	}
+	l := &models.ModerationLog{
+		ActorID:     actor.UserID,
+		Action:      models.ModActionUserBan,
+		CommunityID: communityID,
+		TargetType:  models.ModTargetUser,
+		TargetID:    p.UserID,
+		Reason:      p.Reason,
+	}
+	if err = mysql.BanUser(l, expire); err != nil {
+		zap.L().Error("mysql.BanUser failed",
+			zap.Int64("community_id", communityID),
+			zap.Int64("user_id", p.UserID),
+			zap.Error(err))
+	}
+	return
+}
+
+// UnbanUser 解除社区封禁
+func UnbanUser(actor *rbac.Principal, communityID, userID int64) (err error) {
+	if !actor.CanIn(rbac.PermUserBan, communityID) {
+		return ErrorPermissionDenied
+	}
+	l := &models.ModerationLog{
+		ActorID:     actor.UserID,
+		Action:      models.ModActionUserUnban,
+		CommunityID: communityID,
+		TargetType:  models.ModTargetUser,
+		TargetID:    userID,
+	}
+	if err = mysql.UnbanUser(l); err != nil && !errors.Is(err, mysql.ErrorInvalidID) {
+		zap.L().Error("mysql.UnbanUser failed",
+			zap.Int64("community_id", communityID),
+			zap.Int64("user_id", userID),
+			zap.Error(err))
+	}
+	return
+}
+
+// checkUserNotBanned 检查用户是否被禁止在社区中发言
+func checkUserNotBanned(communityID, userID int64) error {
+	banned, err := mysql.IsUserBanned(communityID, userID)
+	if err != nil {
+		zap.L().Error("mysql.IsUserBanned failed",
+			zap.Int64("community_id", communityID),
+			zap.Int64("user_id", userID),
+			zap.Error(err))
+		return err
+	}
+	if banned {
+		return ErrorUserBanned
+	}
+	return nil
+}
+
+// SetUserRole 修改用户的全局角色，需要权限的接口立即使用新角色，Token 中的角色在下次刷新后更新
+func SetUserRole(actor *rbac.Principal, userID int64, p *models.ParamSetRole) (err error) {
+	if !actor.Global(rbac.PermRoleManage) {
+		return ErrorPermissionDenied
+	}
+	if !rbac.ValidRole(p.Role) {
+		return ErrorInvalidRole
+	}
+	l := &models.ModerationLog{
+		ActorID:    actor.UserID,
+		Action:     models.ModActionUserRole,
+		TargetType: models.ModTargetUser,
+		TargetID:   userID,
+		Reason:     p.Role + ": " + p.Reason,
+	}
+	if err = mysql.SetUserRole(l, p.Role); err != nil && !errors.Is(err, mysql.ErrorUserNotExist) {
+		zap.L().Error("mysql.SetUserRole failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+	return
+}
+
+// BootstrapAdmins 启动时把配置中的用户设为管理员，已经是管理员的用户不变，不存在的用户被忽略
+// 操作写入管理日志，操作人为 0 表示系统
+func BootstrapAdmins(userIDs []int64) error {
+	for _, userID := range userIDs {
+		user, err := mysql.GetUserByID(userID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				zap.L().Warn("admin user not found", zap.Int64("user_id", userID))
+				continue
+			}
+			return err
+		}
+		if user.Role == rbac.RoleAdmin {
+			continue
+		}
+		l := &models.ModerationLog{
+			Action:     models.ModActionUserRole,
+			TargetType: models.ModTargetUser,
+			TargetID:   userID,
+			Reason:     rbac.RoleAdmin + ": admin_user_ids",
+		}
+		if err = mysql.SetUserRole(l, rbac.RoleAdmin); err != nil {
+			return err
+		}
+		zap.L().Info("grant admin role", zap.Int64("user_id", userID))
+	}
+	return nil
+}
+
+// GetModerationLogs 查询管理日志
+// 拥有全局权限的用户可以查询全部或指定社区的日志，社区版主只能查询自己管理的社区
+func GetModerationLogs(actor *rbac.Principal, p *models.ParamModerationLogList) (data []*models.ModerationLog, err error) {
+	var communityIDs []int64
+	switch {
+	case p.CommunityID != 0:
+		if !actor.CanIn(rbac.PermAuditRead, p.CommunityID) {
+			return nil, ErrorPermissionDenied
+		}
+		communityIDs = []int64{p.CommunityID}
+	case actor.Global(rbac.PermAuditRead):
+		// 不限社区
+	case actor.Can(rbac.PermAuditRead):
+		communityIDs = actor.Communities
+	default:
+		return nil, ErrorPermissionDenied
+	}
+	clampModerationPage(&p.Page, &p.Size)
+	data, err = mysql.GetModerationLogs(communityIDs, p.Page, p.Size)
+	if err != nil {
+		zap.L().Error("mysql.GetModerationLogs failed", zap.Error(err))
+	}
+	return
+}
+
+// GetPostReviews 查询审核队列中待审核的帖子
+// 拥有全局权限的用户可以查询全部或指定社区，社区版主只能查询自己管理的社区
+func GetPostReviews(actor *rbac.Principal, p *models.ParamPostReviewList) (data []*models.PostReview, err error) {
+	var communityIDs []int64
+	switch {
+	case p.CommunityID != 0:
+		if !actor.CanIn(rbac.PermPostModerate, p.CommunityID) {
+			return nil, ErrorPermissionDenied
+		}
+		communityIDs = []int64{p.CommunityID}
+	case actor.Global(rbac.PermPostModerate):
+		// 不限社区
+	case actor.Can(rbac.PermPostModerate):
+		communityIDs = actor.Communities
+	default:
+		return nil, ErrorPermissionDenied
+	}
+	clampModerationPage(&p.Page, &p.Size)
+	data, err = mysql.GetPostReviews(communityIDs, p.Page, p.Size)
+	if err != nil {
+		zap.L().Error("mysql.GetPostReviews failed", zap.Error(err))
+	}
+	return
+}
+
+// ReviewPost 审核帖子
+// 新帖子通过后公开并按审核通过的时间发布，拒绝后帖子被删除；编辑后的帖子通过后恢复公开，拒绝后恢复为编辑前的版本
+func ReviewPost(actor *rbac.Principal, postID int64, approved bool, p *models.ParamModeratePost) (err error) {
+	review, err := mysql.GetPostReview(postID)
+	if err != nil {
+		if !errors.Is(err, mysql.ErrorInvalidID) {
+			zap.L().Error("mysql.GetPostReview failed", zap.Int64("id", postID), zap.Error(err))
+		}
+		return
+	}
+	if !actor.CanIn(rbac.PermPostModerate, review.CommunityID) {
+		return ErrorPermissionDenied
+	}
+	action := models.ModActionPostReject
+	if approved {
+		action = models.ModActionPostApprove
+	}
+	post := &models.Post{ID: postID, CommunityID: review.CommunityID, AuthorID: review.AuthorID}
+	if err = mysql.ReviewPost(newPostModerationLog(actor, post, action, p.Reason), review.Kind, approved); err != nil {
+		zap.L().Error("mysql.ReviewPost failed", zap.Int64("id", postID), zap.Error(err))
+		return
+	}
+	if review.Kind == models.ReviewKindEdit {
+		// 帖子在 Redis 中的排序数据一直保留，只需要用审核后的内容更新搜索索引
+		if post, err = mysql.GetPostByID(postID); err != nil {
+			zap.L().Error("mysql.GetPostByID failed", zap.Int64("id", postID), zap.Error(err))
+			return
+		}
+		if post.ID != 0 {
+			indexPost(post)
+		}
+		return
+	}
+	if !approved {
+		return
+	}
+	if post, err = mysql.GetPostByID(postID); err != nil {
+		zap.L().Error("mysql.GetPostByID failed", zap.Int64("id", postID), zap.Error(err))
+		return
+	}
+	if post.ID == 0 {
+		return mysql.ErrorInvalidID
+	}
+	if post.Tags, err = mysql.GetPostTags(postID); err != nil {
+		zap.L().Error("mysql.GetPostTags failed", zap.Int64("id", postID), zap.Error(err))
+		return
+	}
+	publishPost(post)
+	return
+}
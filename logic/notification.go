@@ -0,0 +1,189 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"bluebell/pkg/eventbus"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// 通知中心
+// 订阅投票、发帖、评论事件生成通知，未读数保存在 Redis 中
+
+const (
+	maxMentions         = 10 // 一个帖子最多通知的 @ 用户数
+	notificationExcerpt = 64 // 通知中保存的摘要长度（字符数）
+)
+
+// mentionPattern @用户名，用户名到空白或常见标点为止；@ 前面是字母或数字时（如邮箱地址）不算提及
+var mentionPattern = regexp.MustCompile(`(?:^|[^0-9A-Za-z_@])@([^\s@,，.。:：;；!！?？()（）"'“”]+)`)
+
+// subscribeNotifications 订阅生成通知的事件
+func subscribeNotifications(bus *eventbus.Bus) {
+	bus.Subscribe(TopicPostVoted, func(e eventbus.Event) { notifyPostVoted(e.(PostVotedEvent)) })
+	bus.Subscribe(TopicPostCreated, func(e eventbus.Event) { notifyMentions(e.(PostCreatedEvent)) })
+	bus.Subscribe(TopicCommentCreated, func(e eventbus.Event) { notifyReply(e.(CommentCreatedEvent)) })
+}
+
+// notifyPostVoted 帖子收到赞成票时通知作者，同一个用户的投票只通知一次
+func notifyPostVoted(e PostVotedEvent) {
+	if e.Direction != 1 {
+		return
+	}
+	sendNotification(&models.Notification{
+		UserID:  e.Post.AuthorID,
+		ActorID: e.VoterID,
+		Type:    models.NotificationVote,
+		PostID:  e.Post.ID,
+		Content: excerpt(e.Post.Title),
+	})
+}
+
+// notifyMentions 通知帖子中 @ 的用户
+func notifyMentions(e PostCreatedEvent) {
+	names := extractMentions(e.Post.Title + "\n" + e.Post.Content)
+	if len(names) == 0 {
+		return
+	}
+	users, err := mysql.GetUsersByUsernames(names)
+	if err != nil {
+		zap.L().Error("mysql.GetUsersByUsernames failed", zap.Error(err))
+		return
+	}
+	for _, u := range users {
+		sendNotification(&models.Notification{
+			UserID:  u.UserID,
+			ActorID: e.Post.AuthorID,
+			Type:    models.NotificationMention,
+			PostID:  e.Post.ID,
+			Content: excerpt(e.Post.Title),
+		})
+	}
+}
+
+// notifyReply 通知被回复的评论的作者，直接回复帖子时通知帖子的作者
+func notifyReply(e CommentCreatedEvent) {
+	userID := e.Post.AuthorID
+	if e.ParentAuthorID != 0 {
+		userID = e.ParentAuthorID
+	}
+	sendNotification(&models.Notification{
+		UserID:    userID,
+		ActorID:   e.Comment.AuthorID,
+		Type:      models.NotificationReply,
+		PostID:    e.Post.ID,
+		CommentID: e.Comment.ID,
+		Content:   excerpt(e.Comment.Content),
+	})
+}
+
+// sendNotification 保存通知并增加未读数，不通知用户自己的操作
+func sendNotification(n *models.Notification) {
+	if n.UserID == 0 || n.UserID == n.ActorID {
+		return
+	}
+	created, err := mysql.CreateNotification(n)
+	if err != nil {
+		zap.L().Error("mysql.CreateNotification failed", zap.Any("notification", n), zap.Error(err))
+		return
+	}
+	if !created {
+		return
+	}
+	if err = redis.IncrUnreadCount(n.UserID, 1); err != nil {
+		zap.L().Error("redis.IncrUnreadCount failed", zap.Int64("user_id", n.UserID), zap.Error(err))
+	}
+}
+
+// extractMentions 提取文本中 @ 的用户名，去重并最多返回 maxMentions 个
+func extractMentions(text string) []string {
+	matches := mentionPattern.FindAllStringSubmatch(text, -1)
+	names := make([]string, 0, len(matches))
+	seen := make(map[string]struct{}, len(matches))
+	for _, m := range matches {
+		name := m[1]
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+		if len(names) == maxMentions {
+			break
+		}
+	}
+	return names
+}
+
+// excerpt 截取前 notificationExcerpt 个字符作为摘要
+func excerpt(s string) string {
+	s = strings.TrimSpace(s)
+	if utf8.RuneCountInString(s) <= notificationExcerpt {
+		return s
+	}
+	return string([]rune(s)[:notificationExcerpt]) + "…"
+}
+
+// GetUnreadCount 查询未读通知数，Redis 中没有计数时从 MySQL 统计
+func GetUnreadCount(userID int64) (int64, error) {
+	count, ok, err := redis.GetUnreadCount(userID)
+	if err != nil {
+		zap.L().Error("redis.GetUnreadCount failed", zap.Int64("user_id", userID), zap.Error(err))
+		return 0, err
+	}
+	if ok {
+		return count, nil
+	}
+	if count, err = mysql.CountUnreadNotifications(userID); err != nil {
+		zap.L().Error("mysql.CountUnreadNotifications failed", zap.Int64("user_id", userID), zap.Error(err))
+		return 0, err
+	}
+	if err = redis.SetUnreadCount(userID, count); err != nil {
+		zap.L().Error("redis.SetUnreadCount failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+	return count, nil
+}
+
+// GetNotifications 分页查询通知和未读数
+func GetNotifications(userID int64, p *models.ParamNotificationList) (data *models.ApiNotificationList, err error) {
+	data = new(models.ApiNotificationList)
+	if data.Unread, err = GetUnreadCount(userID); err != nil {
+		return nil, err
+	}
+	if data.List, err = mysql.GetNotifications(userID, p.Unread, p.Page, p.Size); err != nil {
+		zap.L().Error("mysql.GetNotifications failed", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	return data, nil
+}
+
+// ReadNotifications 把通知标记为已读，返回剩余的未读数
+func ReadNotifications(userID int64, p *models.ParamReadNotifications) (unread int64, err error) {
+	ids := make([]int64, 0, len(p.IDs))
+	for _, s := range p.IDs {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, mysql.ErrorInvalidID
+		}
+		ids = append(ids, id)
+	}
+	n, err := mysql.MarkNotificationsRead(userID, ids)
+	if err != nil {
+		zap.L().Error("mysql.MarkNotificationsRead failed", zap.Int64("user_id", userID), zap.Error(err))
+		return 0, err
+	}
+	if len(ids) == 0 {
+		err = redis.SetUnreadCount(userID, 0)
+	} else if n > 0 {
+		err = redis.IncrUnreadCount(userID, -n)
+	}
+	if err != nil {
+		zap.L().Error("redis update unread count failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+	return GetUnreadCount(userID)
+}
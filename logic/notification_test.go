@@ -0,0 +1,26 @@
+package logic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractMentions(t *testing.T) {
+	names := extractMentions("感谢 @alice 和@小明，还有 @bob。@alice 再次出现\nemail a@b 不算提及吗？")
+	assert.Equal(t, []string{"alice", "小明", "bob"}, names)
+
+	var b strings.Builder
+	for i := 0; i < maxMentions+5; i++ {
+		b.WriteString(" @user")
+		b.WriteByte(byte('a' + i))
+	}
+	assert.Len(t, extractMentions(b.String()), maxMentions)
+}
+
+func TestExcerpt(t *testing.T) {
+	assert.Equal(t, "短标题", excerpt("  短标题 "))
+	long := strings.Repeat("长", notificationExcerpt+1)
+	assert.Equal(t, strings.Repeat("长", notificationExcerpt)+"…", excerpt(long))
+}
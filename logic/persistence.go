@@ -3,10 +3,14 @@ package logic
 import (
 	"bluebell/dao/mysql"
 	"bluebell/dao/redis"
+	"bluebell/models"
 	"bluebell/setting"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -85,6 +89,7 @@ func (p *Persistence) Stop() {
 }
 
 // persistData 执行数据持久化的具体逻辑
+// 投票、归档和收藏三个步骤互不依赖，某一步失败不影响其他步骤，返回所有步骤的错误
 func (p *Persistence) persistData() error {
 	// 从 Redis 中获取需要持久化的数据，并持久化到MySQL中
 	/*
@@ -93,6 +98,33 @@ func (p *Persistence) persistData() error {
 		p.cfg.RetryCount // 持久化失败时的重试次数
 		p.cfg.ScoreFixedDays //保留多少天的数据，超过这个天数，Score这个表中该数据就不再进行维护
 	*/
+	var errs []error
+	// 1. 持久化帖子分数和投票数据，并重建 karma
+	if err := p.persistVotes(); err != nil {
+		errs = append(errs, err)
+	}
+
+	// 2. 归档投票期已结束的帖子
+	if err := p.archiveExpiredPosts(); err != nil {
+		zap.L().Error("failed to archive expired posts", zap.Error(err))
+		errs = append(errs, err)
+	}
+
+	// 3. 持久化用户的收藏
+	if err := persistBookmarks(p.cfg.BatchSize, time.Duration(p.cfg.Timeout)*time.Second); err != nil {
+		zap.L().Error("failed to persist bookmarks", zap.Error(err))
+		errs = append(errs, err)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	zap.L().Info("data persisted successfully")
+	return nil
+}
+
+// persistVotes 把 Redis 中的帖子分数和投票数据写入 MySQL，写入成功后用 MySQL 中的投票记录重建 karma
+func (p *Persistence) persistVotes() error {
 	// 1. 从 Redis 中获取帖子分数数据
 	postScores, err := redis.FetchPostScores(p.cfg.ScoreFixedDays, p.cfg.RetryCount, p.cfg.Timeout)
 	if err != nil {
@@ -116,7 +148,89 @@ func (p *Persistence) persistData() error {
 		return err
 	}
 
-	zap.L().Info("data persisted successfully")
+	// 4. 用刚持久化的投票记录重建 karma，此时 MySQL 与 Redis 中的投票一致，不会抹掉最近的投票
+	if err := reconcileKarma(); err != nil {
+		zap.L().Error("failed to reconcile karma", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// archiveExpiredPosts 归档投票期（一周）已结束的帖子
+// 投票记录写入 post_votes，最终分数和票数冻结在 post_scores 中，
+// MySQL 写入成功后才在 Redis 中标记为已归档，开启 cleanup_after_persist 时同时删除 Redis 中的投票记录
+func (p *Persistence) archiveExpiredPosts() error {
+	timeout := time.Duration(p.cfg.Timeout) * time.Second
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := p.archiveBatch(ctx)
+		cancel()
+		if errors.Is(err, errNothingToArchive) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+var errNothingToArchive = errors.New("nothing to archive")
+
+// archiveBatch 归档一批过期帖子，没有需要归档的帖子时返回 errNothingToArchive
+func (p *Persistence) archiveBatch(ctx context.Context) error {
+	postIDs, err := redis.GetExpiredPostIDs(ctx, p.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+	if len(postIDs) == 0 {
+		return errNothingToArchive
+	}
+	votes, err := redis.FetchPostVotesByIDs(ctx, postIDs)
+	if err != nil {
+		return err
+	}
+	scoreMap, err := redis.GetPostScoreByIDs(ctx, postIDs)
+	if err != nil {
+		return err
+	}
+
+	// 根据投票记录统计最终的票数
+	scores := make(map[int64]*models.ArchivedPostScore, len(postIDs))
+	for _, id := range postIDs {
+		postID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			continue
+		}
+		scores[postID] = &models.ArchivedPostScore{ID: postID, Score: scoreMap[id]}
+	}
+	for _, v := range votes {
+		s, ok := scores[v.PostID]
+		if !ok {
+			continue
+		}
+		switch {
+		case v.Direction > 0:
+			s.UpVotes++
+		case v.Direction < 0:
+			s.DownVotes++
+		}
+	}
+	data := make([]*models.ArchivedPostScore, 0, len(scores))
+	for _, s := range scores {
+		data = append(data, s)
+	}
+
+	if err := mysql.ArchivePosts(data, votes); err != nil {
+		return err
+	}
+	if err := redis.MarkPostsArchived(ctx, postIDs, p.cfg.CleanAfterPersist); err != nil {
+		return err
+	}
+	zap.L().Info("archive expired posts success",
+		zap.Int("posts", len(postIDs)),
+		zap.Int("votes", len(votes)),
+		zap.Bool("clean", p.cfg.CleanAfterPersist))
 	return nil
 }
 
@@ -5,32 +5,66 @@ import (
 	"bluebell/dao/redis"
 	"bluebell/models"
 	"bluebell/pkg/snowflake"
+	"errors"
+	"time"
 
 	"go.uber.org/zap"
 )
 
+var ErrorPermissionDenied = errors.New("没有操作权限")
+
 // CreatePost 发帖
 func CreatePost(p *models.Post) (err error) {
+	// 社区必须存在且未归档
+	if err = checkCommunityWritable(p.CommunityID); err != nil {
+		return
+	}
+	if err = checkUserNotBanned(p.CommunityID, p.AuthorID); err != nil {
+		return
+	}
+	if p.Tags, err = normalizeTags(p.Tags); err != nil {
+		return
+	}
+	// 内容检查，未通过的帖子进入审核队列
+	review, reserved, err := moderatePost(p, false)
+	if err != nil {
+		return
+	}
 	// 1. 生成post id
 	p.ID = snowflake.GenID()
 	// 2. 保存到数据库
-	if err = mysql.CreatePost(p); err != nil {
+	if err = mysql.CreatePost(p, review); err != nil {
 		zap.L().Error("mysql.CreatePost failed",
 			zap.Any("post", p),
 			zap.Error(err))
+		// 保存失败的帖子不占用发帖配额
+		reserved.release(p)
 		return
 	}
-	if err = redis.CreatePost(p.ID, p.CommunityID); err != nil {
+	p.CreateTime = time.Now().Truncate(time.Second)
+	if review != nil {
+		// 审核通过后再发布
+		return
+	}
+	publishPost(p)
+	// 3. 返回
+	return
+}
+
+// publishPost 帖子公开后写入 Redis 和搜索索引并发布事件，失败只记录日志
+func publishPost(p *models.Post) {
+	createTime := p.CreateTime.Unix()
+	if err := redis.CreatePost(p.ID, p.CommunityID, p.AuthorID, createTime, newPostRanks(createTime), p.Tags); err != nil {
 		zap.L().Error("redis.CreatePost failed",
 			zap.Any("post", p),
 			zap.Error(err))
 	}
-	// 3. 返回
-	return
+	indexPost(p)
+	events.Publish(PostCreatedEvent{Post: p})
 }
 
-// GetPostByID 根据帖子ID查询帖子数据
-func GetPostByID(id int64) (data *models.ApiPostDetail, err error) {
+// GetPostByID 根据帖子ID查询帖子数据，userID 为当前用户，用于返回其投票
+func GetPostByID(userID, id int64) (data *models.ApiPostDetail, err error) {
 	// 查询并组合我们需要的数据
 	postData, err := mysql.GetPostByID(id)
 	if err != nil {
@@ -39,30 +73,106 @@ func GetPostByID(id int64) (data *models.ApiPostDetail, err error) {
 			zap.Error(err))
 		return
 	}
-	// 根据用户ID查询用户信息
-	user, err := mysql.GetUserByID(postData.AuthorID)
+	if postData.ID == 0 {
+		// 帖子不存在或已被删除
+		return nil, mysql.ErrorInvalidID
+	}
+	details, err := postAssembler.assemble(userID, []*models.Post{postData})
 	if err != nil {
-		zap.L().Error("mysql.GetUserByID failed",
-			zap.Int64("author_id", postData.AuthorID),
+		zap.L().Error("logic.postAssembler.assemble failed",
+			zap.Int64("id", id),
 			zap.Error(err))
 		return
 	}
-	// 根据社区ID查询社区信息
-	community, err := mysql.GetCommunityDetailByID(postData.CommunityID)
+	if len(details) == 0 {
+		// 作者或社区不存在
+		return nil, mysql.ErrorInvalidID
+	}
+	return details[0], nil
+}
+
+// UpdatePost 编辑帖子，只有作者本人可以编辑，p.Tags 为 nil 时不修改标签
+func UpdatePost(userID int64, p *models.Post) (err error) {
+	if p.Tags, err = normalizeTags(p.Tags); err != nil {
+		return
+	}
+	post, err := mysql.GetPostByID(p.ID)
 	if err != nil {
-		zap.L().Error("mysql.GetCommunityDetailByID failed",
-			zap.Int64("community_id", postData.CommunityID),
-			zap.Error(err))
+		zap.L().Error("mysql.GetPostByID failed", zap.Int64("id", p.ID), zap.Error(err))
 		return
 	}
-	data = &models.ApiPostDetail{
-		AuthorName:      user.Username,
-		Post:            postData,
-		CommunityDetail: community,
+	if post.ID == 0 {
+		return mysql.ErrorInvalidID
 	}
+	if post.AuthorID != userID {
+		return ErrorPermissionDenied
+	}
+	if post.Locked {
+		return ErrorPostLocked
+	}
+	// 编辑后的内容同样要经过内容检查，未通过时帖子重新进入审核队列，审核通过前不公开
+	p.AuthorID, p.CommunityID = post.AuthorID, post.CommunityID
+	// 编辑时不执行预占配额的检查，不需要归还
+	review, _, err := moderatePost(p, true)
+	if err != nil {
+		return
+	}
+	oldTags, err := mysql.UpdatePost(p, userID, review)
+	if err != nil {
+		zap.L().Error("mysql.UpdatePost failed", zap.Any("post", p), zap.Error(err))
+		return
+	}
+	if p.Tags != nil {
+		updatePostTags(post, oldTags, p.Tags)
+	}
+	if review != nil {
+		// 审核通过后再更新搜索索引
+		return
+	}
+	post.Title, post.Content = p.Title, p.Content
+	indexPost(post)
+	return
+}
+
+// DeletePost 删除帖子，只有作者本人可以删除
+// MySQL 中只做软删除，Redis 中的排序数据直接移除，列表接口不再返回该帖子
+func DeletePost(userID, postID int64) (err error) {
+	post, err := mysql.GetPostByID(postID)
+	if err != nil {
+		zap.L().Error("mysql.GetPostByID failed", zap.Int64("id", postID), zap.Error(err))
+		return
+	}
+	if post.ID == 0 {
+		return mysql.ErrorInvalidID
+	}
+	if post.AuthorID != userID {
+		return ErrorPermissionDenied
+	}
+	if err = mysql.DeletePost(postID); err != nil {
+		zap.L().Error("mysql.DeletePost failed", zap.Int64("id", postID), zap.Error(err))
+		return
+	}
+	removePostIndex(postID)
+	if err = redis.RemovePost(postID, post.CommunityID, post.AuthorID); err != nil {
+		zap.L().Error("redis.RemovePost failed", zap.Int64("id", postID), zap.Error(err))
+	}
+	removePostTags(post)
 	return
 }
 
+// GetPostRevisions 查询帖子的修订历史
+func GetPostRevisions(postID int64) (data []*models.PostRevision, err error) {
+	post, err := mysql.GetPostByID(postID)
+	if err != nil {
+		zap.L().Error("mysql.GetPostByID failed", zap.Int64("id", postID), zap.Error(err))
+		return
+	}
+	if post.ID == 0 {
+		return nil, mysql.ErrorInvalidID
+	}
+	return mysql.GetPostRevisions(postID)
+}
+
 // GetPostList 获取帖子列表
 func GetPostList(page, size int64) (data []*models.ApiPostDetail, err error) {
 	// 查询并组合我们需要的数据
@@ -70,37 +180,13 @@ func GetPostList(page, size int64) (data []*models.ApiPostDetail, err error) {
 	if err != nil {
 		zap.L().Error("mysql.GetPostList failed",
 			zap.Error(err))
+		return
 	}
-	data = make([]*models.ApiPostDetail, 0, len(postData))
-	for _, post := range postData {
-		// 根据用户ID查询用户信息
-		user, err := mysql.GetUserByID(post.AuthorID)
-		if err != nil {
-			zap.L().Error("mysql.GetUserByID failed",
-				zap.Int64("author_id", post.AuthorID),
-				zap.Error(err))
-			continue
-		}
-		// 根据社区ID查询社区信息
-		community, err := mysql.GetCommunityDetailByID(post.CommunityID)
-		if err != nil {
-			zap.L().Error("mysql.GetCommunityDetailByID failed",
-				zap.Int64("community_id", post.CommunityID),
-				zap.Error(err))
-			continue
-		}
-		postDetail := &models.ApiPostDetail{
-			AuthorName:      user.Username,
-			Post:            post,
-			CommunityDetail: community,
-		}
-		data = append(data, postDetail)
-	}
-	return
+	return postAssembler.assemble(0, postData)
 }
 
 // GetPostList2 获取帖子列表2
-func GetPostList2(p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
+func GetPostList2(userID int64, p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
 	// 1. 去 Redis 查询 ID 列表
 	ids, err := redis.GetPostIDInOrder(p)
 	if err != nil {
@@ -111,110 +197,79 @@ func GetPostList2(p *models.ParamPostList) (data []*models.ApiPostDetail, err er
 		zap.L().Warn("redis.GetPostIDInorder success, return 0 data.")
 		return
 	}
-	// 2. 根据 ID 去 mysql 查询帖子详细信息
-	posts, err := mysql.GetPostListByIDs(ids)
+	return getPostListByIDs(userID, ids)
+}
+
+// GetCommunityPostList 获取社区帖子列表
+func GetCommunityPostList(userID int64, p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
+	// 1. 查询置顶的帖子，置顶的帖子排在列表的最前面
+	pinned, err := redis.GetPinnedPostIDs(p.CommunityID)
 	if err != nil {
-		zap.L().Error("mysql.GetPostListByIDs failed", zap.Error(err))
+		zap.L().Error("redis.GetPinnedPostIDs failed", zap.Int64("community_id", p.CommunityID), zap.Error(err))
 		return
 	}
-	// 提前查询好每篇帖子的投票数
-	voteData, err := redis.GetPostVoteData(ids)
+	// 2. 去 Redis 查询 ID 列表
+	ids, err := redis.GetCommunityPostIDsPinnedFirst(p, pinned)
 	if err != nil {
-		zap.L().Error("redis.GetPostVoteData failed", zap.Error(err))
+		zap.L().Error("redis.GetCommunityPostIDsPinnedFirst failed", zap.Error(err))
 		return
 	}
-	// 3. 根据用户 ID 查询用户信息
-	data = make([]*models.ApiPostDetail, 0, len(posts))
-	for idx, post := range posts {
-		// 根据用户ID查询用户信息
-		user, err := mysql.GetUserByID(post.AuthorID)
-		if err != nil {
-			zap.L().Error("mysql.GetUserByID failed",
-				zap.Int64("author_id", post.AuthorID),
-				zap.Error(err))
-			continue
-		}
-		// 根据社区ID查询社区信息
-		community, err := mysql.GetCommunityDetailByID(post.CommunityID)
-		if err != nil {
-			zap.L().Error("mysql.GetCommunityDetailByID failed",
-				zap.Int64("community_id", post.CommunityID),
-				zap.Error(err))
-			continue
-		}
-		postDetail := &models.ApiPostDetail{
-			AuthorName:      user.Username,
-			VoteNum:         voteData[idx],
-			Post:            post,
-			CommunityDetail: community,
-		}
-		data = append(data, postDetail)
+	if len(ids) == 0 {
+		zap.L().Warn("redis.GetCommunityPostIDsPinnedFirst success, return 0 data.")
+		return
 	}
-	return
+	return getPostListByIDs(userID, ids)
 }
 
-// GetCommunityPostList 获取社区帖子列表
-func GetCommunityPostList(p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
-	// 1. 去 Redis 查询 ID 列表
-	ids, err := redis.GetCommunityPostIDsInOrder(p)
+// GetFeed 获取用户的首页帖子列表：用户加入的所有社区的帖子
+func GetFeed(userID int64, p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
+	communityIDs, err := mysql.GetSubscribedCommunityIDs(userID)
+	if err != nil {
+		zap.L().Error("mysql.GetSubscribedCommunityIDs failed", zap.Int64("user_id", userID), zap.Error(err))
+		return
+	}
+	ids, err := redis.GetFeedPostIDsInOrder(userID, communityIDs, p)
 	if err != nil {
-		zap.L().Error("redis.GetCommunityPostIDsInOrder failed", zap.Error(err))
+		zap.L().Error("redis.GetFeedPostIDsInOrder failed", zap.Error(err))
 		return
 	}
 	if len(ids) == 0 {
-		zap.L().Warn("redis.GetCommunityPostIDsInOrder success, return 0 data.")
 		return
 	}
-	// 2. 根据 ID 去 mysql 查询帖子详细信息
+	return getPostListByIDs(userID, ids)
+}
+
+// getPostListByIDs 按给定的顺序查询帖子并组装详情
+func getPostListByIDs(userID int64, ids []string) (data []*models.ApiPostDetail, err error) {
+	// 根据 ID 去 mysql 查询帖子详细信息
 	posts, err := mysql.GetPostListByIDs(ids)
 	if err != nil {
 		zap.L().Error("mysql.GetPostListByIDs failed", zap.Error(err))
 		return
 	}
-	// 提前查询好每篇帖子的投票数
-	voteData, err := redis.GetPostVoteData(ids)
-	if err != nil {
-		zap.L().Error("redis.GetPostVoteData failed", zap.Error(err))
-		return
-	}
-	// 3. 根据用户 ID 查询用户信息
-	data = make([]*models.ApiPostDetail, 0, len(posts))
-	for idx, post := range posts {
-		// 根据用户ID查询用户信息
-		user, err := mysql.GetUserByID(post.AuthorID)
-		if err != nil {
-			zap.L().Error("mysql.GetUserByID failed",
-				zap.Int64("author_id", post.AuthorID),
-				zap.Error(err))
-			continue
-		}
-		// 根据社区ID查询社区信息
-		community, err := mysql.GetCommunityDetailByID(post.CommunityID)
-		if err != nil {
-			zap.L().Error("mysql.GetCommunityDetailByID failed",
-				zap.Int64("community_id", post.CommunityID),
-				zap.Error(err))
-			continue
-		}
-		postDetail := &models.ApiPostDetail{
-			AuthorName:      user.Username,
-			VoteNum:         voteData[idx],
-			Post:            post,
-			CommunityDetail: community,
-		}
-		data = append(data, postDetail)
+	if data, err = postAssembler.assemble(userID, posts); err != nil {
+		zap.L().Error("logic.postAssembler.assemble failed", zap.Error(err))
 	}
 	return
 }
 
 // GetPostListNew 获取帖子列表 New
-func GetPostListNew(p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
-	if p.CommunityID == 0 {
+func GetPostListNew(userID int64, p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
+	if p.Tag != "" {
+		if p.Tag, err = normalizeTag(p.Tag); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case p.Tag != "":
+		// 查询标签（和社区）的帖子
+		data, err = GetTagPostList(userID, p)
+	case p.CommunityID == 0:
 		// 查询所有社区的帖子
-		data, err = GetPostList2(p)
-	} else {
+		data, err = GetPostList2(userID, p)
+	default:
 		// 查询指定社区的帖子
-		data, err = GetCommunityPostList(p)
+		data, err = GetCommunityPostList(userID, p)
 	}
 	if err != nil {
 		zap.L().Error("logic.GetPostListNew failed", zap.Error(err))
@@ -222,3 +277,44 @@ func GetPostListNew(p *models.ParamPostList) (data []*models.ApiPostDetail, err
 	}
 	return data, err
 }
+
+// GetPostListByCursor 按游标分页获取帖子列表，p.CommunityID 不为 0 时只查询该社区的帖子，p.Tag 不为空时只查询该标签的帖子
+func GetPostListByCursor(userID int64, p *models.ParamPostList) (page *models.ApiPostPage, err error) {
+	if p.Tag != "" {
+		if p.Tag, err = normalizeTag(p.Tag); err != nil {
+			return nil, err
+		}
+	}
+	cursor := &postCursor{Order: p.Order}
+	if p.Cursor != "" {
+		if cursor, err = decodePostCursor(p.Cursor, p.Order); err != nil {
+			return nil, err
+		}
+	}
+	// 多查一篇，用来判断是否还有下一页
+	ids, scores, err := redis.GetPostIDsAfter(p, cursor.Score, cursor.PostID, p.Size+1)
+	if err != nil {
+		zap.L().Error("redis.GetPostIDsAfter failed", zap.Error(err))
+		return
+	}
+	page = &models.ApiPostPage{List: make([]*models.ApiPostDetail, 0)}
+	if int64(len(ids)) > p.Size {
+		page.HasMore = true
+		ids, scores = ids[:p.Size], scores[:p.Size]
+	}
+	if len(ids) == 0 {
+		return page, nil
+	}
+	if page.HasMore {
+		last := len(ids) - 1
+		page.NextCursor = (&postCursor{Order: p.Order, Score: scores[last], PostID: ids[last]}).encode()
+	}
+	data, err := getPostListByIDs(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		page.List = data
+	}
+	return page, nil
+}
@@ -0,0 +1,169 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// 帖子详情的组装
+// 一页帖子的作者、社区、评论数、投票统计、标签各用一次批量查询取回，查询次数与每页的帖子数无关；
+// 社区详情很少变化，先查进程内缓存，未命中的再批量回源。
+
+// postDetailAssembler 把帖子组装成帖子详情，各个字段的数据源可以替换，便于测试
+type postDetailAssembler struct {
+	users       func(ids []int64) (map[int64]*models.User, error)
+	communities func(ids []int64) (map[int64]*models.CommunityDetail, error)
+	commentNums func(postIDs []int64) (map[int64]int64, error)
+	voteStats   func(userID int64, ids []string) (map[int64]*models.PostVoteStats, error)
+	tags        func(postIDs []int64) (map[int64][]string, error)
+}
+
+var postAssembler = &postDetailAssembler{
+	users:       mysql.GetUsersByIDs,
+	communities: communities.GetMany,
+	commentNums: mysql.GetCommentNumByPostIDs,
+	voteStats:   getPostVoteStats,
+	tags:        mysql.GetTagsByPostIDs,
+}
+
+// assemble 按 posts 的顺序组装帖子详情，userID 为当前用户，用于返回其投票
+// 作者或社区不存在的帖子会被跳过
+func (a *postDetailAssembler) assemble(userID int64, posts []*models.Post) (data []*models.ApiPostDetail, err error) {
+	if len(posts) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(posts))
+	postIDs := make([]int64, 0, len(posts))
+	authorIDs := make([]int64, 0, len(posts))
+	communityIDs := make([]int64, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, strconv.FormatInt(post.ID, 10))
+		postIDs = append(postIDs, post.ID)
+		authorIDs = append(authorIDs, post.AuthorID)
+		communityIDs = append(communityIDs, post.CommunityID)
+	}
+
+	users, err := a.users(uniqueIDs(authorIDs))
+	if err != nil {
+		zap.L().Error("load post authors failed", zap.Error(err))
+		return
+	}
+	communityDetails, err := a.communities(uniqueIDs(communityIDs))
+	if err != nil {
+		zap.L().Error("load post communities failed", zap.Error(err))
+		return
+	}
+	commentNums, err := a.commentNums(postIDs)
+	if err != nil {
+		zap.L().Error("load post comment nums failed", zap.Error(err))
+		return
+	}
+	voteStats, err := a.voteStats(userID, ids)
+	if err != nil {
+		zap.L().Error("load post vote stats failed", zap.Error(err))
+		return
+	}
+	tags, err := a.tags(postIDs)
+	if err != nil {
+		zap.L().Error("load post tags failed", zap.Error(err))
+		return
+	}
+
+	data = make([]*models.ApiPostDetail, 0, len(posts))
+	for _, post := range posts {
+		user, ok := users[post.AuthorID]
+		if !ok {
+			zap.L().Warn("post author not found",
+				zap.Int64("post_id", post.ID),
+				zap.Int64("author_id", post.AuthorID))
+			continue
+		}
+		community, ok := communityDetails[post.CommunityID]
+		if !ok {
+			zap.L().Warn("post community not found",
+				zap.Int64("post_id", post.ID),
+				zap.Int64("community_id", post.CommunityID))
+			continue
+		}
+		post.Tags = tags[post.ID]
+		if post.Tags == nil {
+			post.Tags = []string{}
+		}
+		detail := &models.ApiPostDetail{
+			AuthorName:      user.Username,
+			CommentNum:      commentNums[post.ID],
+			Post:            post,
+			CommunityDetail: community,
+		}
+		if stats, ok := voteStats[post.ID]; ok {
+			detail.PostVoteStats = *stats
+			detail.VoteNum = stats.UpVotes
+		}
+		data = append(data, detail)
+	}
+	return
+}
+
+// uniqueIDs 去掉重复的ID
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]struct{}, len(ids))
+	res := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		res = append(res, id)
+	}
+	return res
+}
+
+// getPostVoteStats 查询每篇帖子的投票统计以及用户 userID 的投票，投票已归档的帖子从 MySQL 中读取
+func getPostVoteStats(userID int64, ids []string) (data map[int64]*models.PostVoteStats, err error) {
+	stats, archived, err := redis.GetPostVoteData(ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	data = make(map[int64]*models.PostVoteStats, len(ids))
+	archivedIDs := make([]int64, 0)
+	for idx, id := range ids {
+		postID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			continue
+		}
+		data[postID] = stats[idx]
+		if archived[idx] {
+			archivedIDs = append(archivedIDs, postID)
+		}
+	}
+	if len(archivedIDs) == 0 {
+		return data, nil
+	}
+	scores, err := mysql.GetArchivedPostScores(archivedIDs)
+	if err != nil {
+		return nil, err
+	}
+	var myVotes map[int64]int8
+	if userID != 0 {
+		if myVotes, err = mysql.GetUserPostVotes(userID, archivedIDs); err != nil {
+			return nil, err
+		}
+	}
+	for _, postID := range archivedIDs {
+		s, ok := scores[postID]
+		if !ok {
+			continue
+		}
+		data[postID] = &models.PostVoteStats{
+			UpVotes:   s.UpVotes,
+			DownVotes: s.DownVotes,
+			Score:     s.UpVotes - s.DownVotes,
+			MyVote:    myVotes[postID],
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,117 @@
+package logic
+
+import (
+	"bluebell/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingAssembler 使用假的数据源组装帖子详情，并统计查询次数
+func countingAssembler(queries *int) *postDetailAssembler {
+	cache := newCommunityCache(time.Minute, func(ids []int64) (map[int64]*models.CommunityDetail, error) {
+		*queries++
+		data := make(map[int64]*models.CommunityDetail, len(ids))
+		for _, id := range ids {
+			data[id] = &models.CommunityDetail{ID: id, Name: "community"}
+		}
+		return data, nil
+	})
+	return &postDetailAssembler{
+		users: func(ids []int64) (map[int64]*models.User, error) {
+			*queries++
+			data := make(map[int64]*models.User, len(ids))
+			for _, id := range ids {
+				data[id] = &models.User{UserID: id, Username: "user"}
+			}
+			return data, nil
+		},
+		communities: cache.GetMany,
+		commentNums: func(postIDs []int64) (map[int64]int64, error) {
+			*queries++
+			return map[int64]int64{postIDs[0]: 3}, nil
+		},
+		voteStats: func(userID int64, ids []string) (map[int64]*models.PostVoteStats, error) {
+			*queries++
+			return map[int64]*models.PostVoteStats{1: {UpVotes: 2, DownVotes: 1, Score: 1, MyVote: 1}}, nil
+		},
+		tags: func(postIDs []int64) (map[int64][]string, error) {
+			*queries++
+			return map[int64][]string{postIDs[0]: {"go"}}, nil
+		},
+	}
+}
+
+func testPosts(n int) []*models.Post {
+	posts := make([]*models.Post, 0, n)
+	for i := 1; i <= n; i++ {
+		posts = append(posts, &models.Post{
+			ID:          int64(i),
+			AuthorID:    int64(i%3 + 1),
+			CommunityID: int64(i%2 + 1),
+		})
+	}
+	return posts
+}
+
+func TestPostAssemblerQueryCount(t *testing.T) {
+	var queries int
+	a := countingAssembler(&queries)
+	posts := testPosts(10)
+
+	data, err := a.assemble(1, posts)
+	assert.NoError(t, err)
+	assert.Len(t, data, 10)
+	// 作者、社区、评论数、投票、标签各一次
+	assert.Equal(t, 5, queries)
+	assert.Equal(t, int64(3), data[0].CommentNum)
+	assert.Equal(t, int64(2), data[0].UpVotes)
+	assert.Equal(t, int64(2), data[0].VoteNum)
+	assert.Equal(t, int8(1), data[0].MyVote)
+	assert.Equal(t, []string{"go"}, data[0].Tags)
+	assert.Equal(t, []string{}, data[1].Tags)
+
+	// 社区详情命中缓存
+	queries = 0
+	_, err = a.assemble(1, posts)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, queries)
+}
+
+func TestCommunityCache(t *testing.T) {
+	var loads int
+	c := newCommunityCache(time.Minute, func(ids []int64) (map[int64]*models.CommunityDetail, error) {
+		loads++
+		// 社区 2 不存在
+		return map[int64]*models.CommunityDetail{1: {ID: 1}}, nil
+	})
+	data, err := c.GetMany([]int64{1, 2})
+	assert.NoError(t, err)
+	assert.Len(t, data, 1)
+
+	_, _ = c.GetMany([]int64{1})
+	assert.Equal(t, 1, loads)
+
+	c.Invalidate(1)
+	_, _ = c.GetMany([]int64{1})
+	assert.Equal(t, 2, loads)
+}
+
+func BenchmarkPostAssembler(b *testing.B) {
+	for _, size := range []int{10, 50} {
+		b.Run("size="+strconv.Itoa(size), func(b *testing.B) {
+			var queries int
+			a := countingAssembler(&queries)
+			posts := testPosts(size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := a.assemble(1, posts); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(queries)/float64(b.N), "queries/page")
+		})
+	}
+}
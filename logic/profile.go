@@ -0,0 +1,136 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// 用户主页和账号设置
+
+var ErrorInvalidAvatarURL = errors.New("头像地址必须以 http:// 或 https:// 开头")
+
+// GetUserProfile 查询用户主页：公开资料、收到的投票和按 p.Order 排序的帖子
+// viewerID 为当前登录用户，查看自己的主页时返回邮箱
+func GetUserProfile(viewerID, userID int64, p *models.ParamPostList) (data *models.ApiUserProfile, err error) {
+	profile, err := getUserProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+	if viewerID != userID {
+		profile.Email = ""
+	}
+	ids, err := redis.GetUserPostIDsInOrder(userID, p)
+	if err != nil {
+		zap.L().Error("redis.GetUserPostIDsInOrder failed", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	data = &models.ApiUserProfile{UserProfile: profile, Posts: []*models.ApiPostDetail{}}
+	if len(ids) == 0 {
+		return data, nil
+	}
+	if data.Posts, err = getPostListByIDs(viewerID, ids); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetMyProfile 查询当前用户的资料
+func GetMyProfile(userID int64) (*models.UserProfile, error) {
+	return getUserProfile(userID)
+}
+
+// getUserProfile 查询用户资料并统计帖子数和收到的投票
+func getUserProfile(userID int64) (profile *models.UserProfile, err error) {
+	profile, err = mysql.GetUserProfile(userID)
+	if err != nil {
+		if !errors.Is(err, mysql.ErrorUserNotExist) {
+			zap.L().Error("mysql.GetUserProfile failed", zap.Int64("user_id", userID), zap.Error(err))
+		}
+		return nil, err
+	}
+	if err = loadUserPosts(userID); err != nil {
+		return nil, err
+	}
+	ids, err := redis.GetUserPostIDs(userID)
+	if err != nil {
+		zap.L().Error("redis.GetUserPostIDs failed", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	profile.PostNum = int64(len(ids))
+	if profile.Karma, err = redis.GetUserKarma(userID); err != nil {
+		zap.L().Error("redis.GetUserKarma failed", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	return profile, nil
+}
+
+// loadUserPosts 用户的帖子还没有加载过时从 MySQL 加载，兼容升级前发布的帖子
+func loadUserPosts(userID int64) error {
+	ok, err := redis.UserPostsLoaded(userID)
+	if err != nil {
+		zap.L().Error("redis.UserPostsLoaded failed", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	if ok {
+		return nil
+	}
+	ids, err := mysql.GetPostIDsByAuthor(userID)
+	if err != nil {
+		zap.L().Error("mysql.GetPostIDsByAuthor failed", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	return redis.LoadUserPosts(userID, ids)
+}
+
+// UpdateProfile 修改当前用户的资料，返回修改后的资料
+func UpdateProfile(userID int64, p *models.ParamUpdateProfile) (data *models.UserProfile, err error) {
+	if p.AvatarURL != nil && *p.AvatarURL != "" &&
+		!strings.HasPrefix(*p.AvatarURL, "http://") && !strings.HasPrefix(*p.AvatarURL, "https://") {
+		return nil, ErrorInvalidAvatarURL
+	}
+	if p.Email != nil && *p.Email != "" {
+		if err = mysql.CheckEmailExist(*p.Email, userID); err != nil {
+			return nil, err
+		}
+	}
+	if err = mysql.UpdateUserProfile(userID, p); err != nil {
+		if errors.Is(err, mysql.ErrorEmailExist) {
+			return nil, err
+		}
+		zap.L().Error("mysql.UpdateUserProfile failed", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	if data, err = GetMyProfile(userID); err != nil {
+		return nil, err
+	}
+	// 修改了邮箱，需要重新验证；清除邮箱时不发送
+	if p.Email != nil && data.Email != "" && !data.EmailVerified {
+		if err := sendVerifyEmail(userID, data.Email); err != nil {
+			zap.L().Error("logic.sendVerifyEmail failed", zap.Int64("user_id", userID), zap.Error(err))
+		}
+	}
+	return data, nil
+}
+
+// ChangePassword 修改密码，ip 为客户端IP
+// 旧密码的校验与登录共用失败计数和锁定，避免通过修改密码接口猜测密码；
+// 修改成功后吊销用户的所有 token 家族，所有设备（包括当前设备）都需要重新登录
+func ChangePassword(userID int64, p *models.ParamChangePassword, ip string) (err error) {
+	user, err := mysql.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if _, err = checkPassword(user.Username, p.OldPassword, ip); err != nil {
+		return err
+	}
+	if err = mysql.UpdatePassword(userID, p.Password); err != nil {
+		zap.L().Error("mysql.UpdatePassword failed", zap.Int64("user_id", userID), zap.Error(err))
+		return
+	}
+	return revokeUserTokens(userID)
+}
@@ -0,0 +1,206 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"bluebell/pkg/ranking"
+	"bluebell/setting"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// 帖子排序
+// 每种排序方式（/posts2 的 order 参数）对应一个排序算法和一个 Redis ZSet。
+// score 排序的分数只和票数有关，由投票脚本原子地累加；其它分数在投票后立即重新计算，
+// 分数随时间衰减的算法再由 RankingJob 定期重新计算。
+// RankingJob 启动时还会为缺少分数的帖子（新增排序算法前发布的帖子）补算一次分数。
+
+// scorePerVote 每一票的分数，投票时由 Redis 脚本直接累加到 score 排序的分数中
+const scorePerVote = 432
+
+func init() {
+	ranking.Register(models.OrderScore, ranking.Linear{ScorePerVote: scorePerVote})
+	ranking.Register(models.OrderHot, ranking.RedditHot{})
+	ranking.Register(models.OrderGravity, ranking.HNGravity{Gravity: 1.8})
+	ranking.Register(models.OrderBest, ranking.Wilson{Z: 1.96})
+}
+
+// newPostRanks 新帖子在各个排序方式下的初始分数
+func newPostRanks(createTime int64) map[string]float64 {
+	return ranking.ScoreAll(&ranking.PostStats{CreateTime: createTime}, time.Now())
+}
+
+// recomputedRanks 计算帖子在 score 以外各个排序方式下的分数
+// score 的分数由投票脚本累加，读取票数后再写回会覆盖并发投票的结果，所以这里不写入
+func recomputedRanks(s *ranking.PostStats, now time.Time) map[string]float64 {
+	ranks := ranking.ScoreAll(s, now)
+	delete(ranks, models.OrderScore)
+	return ranks
+}
+
+// updatePostRanks 根据最新的投票数据重新计算帖子在 score 以外各个排序方式下的分数
+func updatePostRanks(postID string) error {
+	stats, err := redis.GetPostRankStats(context.Background(), []string{postID})
+	if err != nil {
+		return err
+	}
+	s, ok := stats[postID]
+	if !ok {
+		return nil
+	}
+	return redis.SetPostRanks(context.Background(), map[string]map[string]float64{
+		postID: recomputedRanks(s, time.Now()),
+	})
+}
+
+// RankingJob 定期重新计算最近帖子的排序分数，score 的分数不随时间变化，不参与重算
+type RankingJob struct {
+	cfg  *setting.RankingConfig
+	cron *cron.Cron
+}
+
+// NewRankingJob 初始化排序分数重算任务
+func NewRankingJob(cfg *setting.RankingConfig) (*RankingJob, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("ranking config is required")
+	}
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("interval must be greater than 0, got %d", cfg.Interval)
+	}
+	if cfg.WindowDays <= 0 {
+		return nil, fmt.Errorf("window_days must be greater than 0, got %d", cfg.WindowDays)
+	}
+	if cfg.BatchSize <= 0 {
+		return nil, fmt.Errorf("batch_size must be greater than 0, got %d", cfg.BatchSize)
+	}
+	if cfg.Timeout <= 0 {
+		return nil, fmt.Errorf("timeout must be greater than 0, got %d", cfg.Timeout)
+	}
+	return &RankingJob{
+		cfg:  cfg,
+		cron: cron.New(cron.WithSeconds()),
+	}, nil
+}
+
+// Start 启动重算任务
+func (j *RankingJob) Start() (err error) {
+	spec := fmt.Sprintf("@every %ds", j.cfg.Interval)
+	_, err = j.cron.AddFunc(spec, func() {
+		if err := j.recompute(); err != nil {
+			zap.L().Error("recompute post ranks failed", zap.Error(err))
+		}
+	})
+	if err != nil {
+		return
+	}
+	go func() {
+		if err := j.backfill(); err != nil {
+			zap.L().Error("backfill post ranks failed", zap.Error(err))
+		}
+	}()
+	j.cron.Start()
+	zap.L().Info("start ranking cron job success")
+	return
+}
+
+// Stop 停止重算任务
+func (j *RankingJob) Stop() {
+	j.cron.Stop()
+	zap.L().Info("stop ranking cron job success")
+}
+
+// recompute 分批重新计算窗口期内帖子的分数
+func (j *RankingJob) recompute() error {
+	timeout := time.Duration(j.cfg.Timeout) * time.Second
+	listCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	postIDs, err := redis.GetPostIDsByTimeRange(listCtx, j.cfg.WindowDays)
+	cancel()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for start := 0; start < len(postIDs); start += j.cfg.BatchSize {
+		end := min(start+j.cfg.BatchSize, len(postIDs))
+		if err := j.recomputeBatch(postIDs[start:end], now, timeout); err != nil {
+			return err
+		}
+	}
+	zap.L().Debug("recompute post ranks success", zap.Int("posts", len(postIDs)))
+	return nil
+}
+
+func (j *RankingJob) recomputeBatch(postIDs []string, now time.Time, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	stats, err := redis.GetPostRankStats(ctx, postIDs)
+	if err != nil {
+		return err
+	}
+	ranks := make(map[string]map[string]float64, len(stats))
+	for id, s := range stats {
+		ranks[id] = recomputedRanks(s, now)
+	}
+	return redis.SetPostRanks(ctx, ranks)
+}
+
+// backfill 按发帖时间分批检查所有帖子，为缺少排序分数的帖子补算分数
+// 投票已归档的帖子使用 MySQL 中归档的票数计算
+func (j *RankingJob) backfill() error {
+	timeout := time.Duration(j.cfg.Timeout) * time.Second
+	batch := int64(j.cfg.BatchSize)
+	total := 0
+	for offset := int64(0); ; offset += batch {
+		n, err := j.backfillBatch(offset, batch, timeout)
+		if err != nil {
+			return err
+		}
+		total += n
+		if int64(n) < batch {
+			break
+		}
+	}
+	zap.L().Info("backfill post ranks success", zap.Int("checked", total))
+	return nil
+}
+
+// backfillBatch 检查一批帖子并补算缺少的分数，返回本次检查的帖子数
+func (j *RankingJob) backfillBatch(offset, count int64, timeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	stats, archived, n, err := redis.GetUnrankedPosts(ctx, offset, count)
+	if err != nil {
+		return 0, err
+	}
+	if len(archived) > 0 {
+		ids := make([]int64, 0, len(archived))
+		for _, id := range archived {
+			postID, _ := strconv.ParseInt(id, 10, 64)
+			ids = append(ids, postID)
+		}
+		scores, err := mysql.GetArchivedPostScores(ids)
+		if err != nil {
+			return 0, err
+		}
+		for _, id := range ids {
+			if s, ok := scores[id]; ok {
+				st := stats[strconv.FormatInt(id, 10)]
+				st.Up, st.Down = s.UpVotes, s.DownVotes
+			}
+		}
+	}
+	now := time.Now()
+	ranks := make(map[string]map[string]float64, len(stats))
+	for id, s := range stats {
+		ranks[id] = ranking.ScoreAll(s, now)
+	}
+	if err := redis.SetPostRanks(ctx, ranks); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
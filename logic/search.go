@@ -0,0 +1,96 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/models"
+	"bluebell/pkg/search"
+	"bluebell/setting"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	SearchEngineMySQL  = "mysql"
+	SearchEngineMemory = "memory"
+)
+
+var searchIndex search.Index = mysql.NewFulltextIndex()
+
+// InitSearch 根据配置选择搜索索引的实现
+func InitSearch(cfg *setting.SearchConfig) error {
+	engine := SearchEngineMySQL
+	if cfg != nil && cfg.Engine != "" {
+		engine = cfg.Engine
+	}
+	switch engine {
+	case SearchEngineMySQL:
+		searchIndex = mysql.NewFulltextIndex()
+	case SearchEngineMemory:
+		searchIndex = search.NewMemoryIndex()
+	default:
+		return fmt.Errorf("unknown search engine: %s", engine)
+	}
+	return nil
+}
+
+// indexPost 帖子发布或编辑后更新搜索索引，失败只记录日志，不影响帖子本身的写入
+func indexPost(p *models.Post) {
+	err := searchIndex.Index(&search.Document{
+		ID:          p.ID,
+		CommunityID: p.CommunityID,
+		Title:       p.Title,
+		Content:     p.Content,
+		CreateTime:  p.CreateTime,
+	})
+	if err != nil {
+		zap.L().Error("searchIndex.Index failed", zap.Int64("id", p.ID), zap.Error(err))
+	}
+}
+
+// removePostIndex 帖子删除后从搜索索引中移除
+func removePostIndex(id int64) {
+	if err := searchIndex.Remove(id); err != nil {
+		zap.L().Error("searchIndex.Remove failed", zap.Int64("id", id), zap.Error(err))
+	}
+}
+
+// SearchPosts 按关键词搜索帖子，结果按相关度排序
+func SearchPosts(userID int64, p *models.ParamSearch) (data *models.ApiSearchResult, err error) {
+	q := &search.Query{
+		Keyword:     p.Keyword,
+		CommunityID: p.CommunityID,
+		Offset:      (p.Page - 1) * p.Size,
+		Limit:       p.Size,
+	}
+	if p.Start > 0 {
+		q.Start = time.Unix(p.Start, 0)
+	}
+	if p.End > 0 {
+		q.End = time.Unix(p.End, 0)
+	}
+	res, err := searchIndex.Search(q)
+	if err != nil {
+		zap.L().Error("searchIndex.Search failed", zap.String("q", p.Keyword), zap.Error(err))
+		return nil, err
+	}
+	data = &models.ApiSearchResult{Total: res.Total, List: make([]*models.ApiPostDetail, 0)}
+	if len(res.IDs) == 0 {
+		return data, nil
+	}
+	ids := make([]string, 0, len(res.IDs))
+	for _, id := range res.IDs {
+		ids = append(ids, strconv.FormatInt(id, 10))
+	}
+	// 帖子按 ids 的顺序返回，保持相关度排序
+	list, err := getPostListByIDs(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	if list != nil {
+		data.List = list
+	}
+	return data, nil
+}
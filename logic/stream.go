@@ -0,0 +1,144 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"bluebell/pkg/eventbus"
+	"bluebell/pkg/stream"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 实时推送
+// 发帖、投票事件发布到 Redis Pub/Sub，每个实例订阅一次后再分发给本实例上的 SSE/WebSocket 连接，
+// 因此连接落在哪个实例上都能收到所有实例产生的事件。
+
+const (
+	// streamBufferSize 每个连接的消息缓冲区长度
+	streamBufferSize = 64
+	// streamRetryInterval 订阅 Redis 失败后重试的间隔
+	streamRetryInterval = 3 * time.Second
+)
+
+var ErrorEmptyStream = errors.New("没有订阅任何社区或帖子")
+
+var (
+	streamHub    = stream.NewHub(streamBufferSize)
+	streamCancel context.CancelFunc
+	streamDone   chan struct{}
+)
+
+// communityChannel 社区的新帖子频道
+func communityChannel(communityID int64) string {
+	return "community:" + strconv.FormatInt(communityID, 10)
+}
+
+// postChannel 帖子的票数频道
+func postChannel(postID int64) string {
+	return "post:" + strconv.FormatInt(postID, 10)
+}
+
+// subscribeStream 订阅需要实时推送的事件
+func subscribeStream(bus *eventbus.Bus) {
+	bus.Subscribe(TopicPostCreated, func(e eventbus.Event) {
+		p := e.(PostCreatedEvent).Post
+		publishStream(communityChannel(p.CommunityID), TopicPostCreated, p)
+	})
+	bus.Subscribe(TopicPostVoted, func(e eventbus.Event) {
+		ev := e.(PostVotedEvent)
+		publishStream(postChannel(ev.Post.ID), TopicPostVoted, &models.PostVoteResult{
+			PostID:    strconv.FormatInt(ev.Post.ID, 10),
+			UpVotes:   ev.UpVotes,
+			DownVotes: ev.DownVotes,
+		})
+	})
+}
+
+// publishStream 把消息发布到 Redis，由各个实例推送给订阅了该频道的连接
+func publishStream(channel, typ string, data interface{}) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		zap.L().Error("json.Marshal stream data failed", zap.String("type", typ), zap.Error(err))
+		return
+	}
+	payload, err := json.Marshal(&stream.Message{Type: typ, Data: b})
+	if err != nil {
+		zap.L().Error("json.Marshal stream message failed", zap.String("type", typ), zap.Error(err))
+		return
+	}
+	if err = redis.PublishStream(channel, payload); err != nil {
+		zap.L().Error("redis.PublishStream failed", zap.String("channel", channel), zap.Error(err))
+	}
+}
+
+// StartStream 订阅 Redis 中的实时推送频道，并分发给本实例上的连接
+func StartStream() {
+	var ctx context.Context
+	ctx, streamCancel = context.WithCancel(context.Background())
+	streamDone = make(chan struct{})
+	go func() {
+		defer close(streamDone)
+		for {
+			err := redis.ListenStream(ctx, dispatchStream)
+			if ctx.Err() != nil {
+				return
+			}
+			zap.L().Error("redis.ListenStream failed, retrying", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(streamRetryInterval):
+			}
+		}
+	}()
+}
+
+// StopStream 停止订阅 Redis
+func StopStream() {
+	if streamCancel == nil {
+		return
+	}
+	streamCancel()
+	<-streamDone
+}
+
+// dispatchStream 把 Redis 中收到的消息分发给本实例上订阅了该频道的连接
+func dispatchStream(channel string, payload []byte) {
+	var m stream.Message
+	if err := json.Unmarshal(payload, &m); err != nil {
+		zap.L().Error("json.Unmarshal stream message failed", zap.String("channel", channel), zap.Error(err))
+		return
+	}
+	m.Channel = channel
+	streamHub.Dispatch(m)
+}
+
+// SubscribeStream 订阅社区的新帖子和帖子的票数变化，连接断开时需要调用 UnsubscribeStream
+func SubscribeStream(p *models.ParamStream) (*stream.Subscriber, error) {
+	channels := make([]string, 0, len(p.CommunityIDs)+len(p.PostIDs))
+	for _, id := range p.CommunityIDs {
+		channels = append(channels, communityChannel(id))
+	}
+	for _, s := range p.PostIDs {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, mysql.ErrorInvalidID
+		}
+		channels = append(channels, postChannel(id))
+	}
+	if len(channels) == 0 {
+		return nil, ErrorEmptyStream
+	}
+	return streamHub.Subscribe(channels...), nil
+}
+
+// UnsubscribeStream 取消订阅
+func UnsubscribeStream(s *stream.Subscriber) {
+	streamHub.Unsubscribe(s)
+}
@@ -0,0 +1,140 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// 帖子标签
+// 标签是自由填写的，保存前统一规范化：去掉开头的 #，转为小写，空白替换为连字符，只保留文字、数字、连字符和下划线。
+
+const (
+	maxTagsPerPost  = 5  // 每个帖子最多的标签数
+	maxTagLength    = 32 // 标签最多的字符数
+	maxTrendingTags = 50 // 热门标签最多返回的个数
+)
+
+var (
+	ErrorTooManyTags = errors.New("每个帖子最多 5 个标签")
+	ErrorInvalidTag  = errors.New("标签只能包含文字、数字、连字符和下划线，最多 32 个字符")
+)
+
+// normalizeTag 规范化一个标签
+func normalizeTag(tag string) (string, error) {
+	tag = strings.TrimLeft(strings.TrimSpace(tag), "#")
+	tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+	tag = strings.Trim(tag, "-")
+	if tag == "" || utf8.RuneCountInString(tag) > maxTagLength {
+		return "", ErrorInvalidTag
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return "", ErrorInvalidTag
+		}
+	}
+	return tag, nil
+}
+
+// normalizeTags 规范化帖子的标签，去重后按字典序排列
+func normalizeTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+	res := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		res = append(res, tag)
+	}
+	if len(res) > maxTagsPerPost {
+		return nil, ErrorTooManyTags
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// diffTags 比较修改前后的标签，返回新增和去掉的标签
+func diffTags(old, tags []string) (added, removed []string) {
+	oldSet := make(map[string]struct{}, len(old))
+	for _, tag := range old {
+		oldSet[tag] = struct{}{}
+	}
+	newSet := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		newSet[tag] = struct{}{}
+		if _, ok := oldSet[tag]; !ok {
+			added = append(added, tag)
+		}
+	}
+	for _, tag := range old {
+		if _, ok := newSet[tag]; !ok {
+			removed = append(removed, tag)
+		}
+	}
+	return
+}
+
+// updatePostTags 帖子的标签修改后同步 Redis
+func updatePostTags(post *models.Post, old, tags []string) {
+	added, removed := diffTags(old, tags)
+	createTime := post.CreateTime.Unix()
+	if err := redis.RemovePostTags(post.ID, post.CommunityID, createTime, removed); err != nil {
+		zap.L().Error("redis.RemovePostTags failed", zap.Int64("id", post.ID), zap.Error(err))
+	}
+	if err := redis.AddPostTags(post.ID, createTime, added); err != nil {
+		zap.L().Error("redis.AddPostTags failed", zap.Int64("id", post.ID), zap.Error(err))
+	}
+}
+
+// removePostTags 帖子删除后从标签中移除
+func removePostTags(post *models.Post) {
+	tags, err := mysql.GetPostTags(post.ID)
+	if err != nil {
+		zap.L().Error("mysql.GetPostTags failed", zap.Int64("id", post.ID), zap.Error(err))
+		return
+	}
+	if err = redis.RemovePostTags(post.ID, post.CommunityID, post.CreateTime.Unix(), tags); err != nil {
+		zap.L().Error("redis.RemovePostTags failed", zap.Int64("id", post.ID), zap.Error(err))
+	}
+}
+
+// GetTagPostList 获取标签的帖子列表，p.CommunityID 不为 0 时只查询该社区的帖子
+func GetTagPostList(userID int64, p *models.ParamPostList) (data []*models.ApiPostDetail, err error) {
+	ids, err := redis.GetTagPostIDsInOrder(p)
+	if err != nil {
+		zap.L().Error("redis.GetTagPostIDsInOrder failed", zap.String("tag", p.Tag), zap.Error(err))
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+	return getPostListByIDs(userID, ids)
+}
+
+// GetTrendingTags 查询最近一段时间新帖子中使用最多的标签
+func GetTrendingTags(p *models.ParamTrendingTags) ([]*models.TrendingTag, error) {
+	if p.Size <= 0 || p.Size > maxTrendingTags {
+		p.Size = maxTrendingTags
+	}
+	data, err := redis.GetTrendingTags(p.Size)
+	if err != nil {
+		zap.L().Error("redis.GetTrendingTags failed", zap.Error(err))
+		return nil, err
+	}
+	return data, nil
+}
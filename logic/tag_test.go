@@ -0,0 +1,35 @@
+package logic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := normalizeTags([]string{" #Go ", "go", "Web  Dev", "数据库", "c_plus-plus"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c_plus-plus", "go", "web-dev", "数据库"}, tags)
+
+	tags, err = normalizeTags(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, tags)
+
+	for _, tag := range []string{"", "#", "a/b", "a:b", strings.Repeat("a", 33)} {
+		_, err = normalizeTags([]string{tag})
+		assert.ErrorIs(t, err, ErrorInvalidTag, tag)
+	}
+
+	_, err = normalizeTags([]string{"a", "b", "c", "d", "e", "f"})
+	assert.ErrorIs(t, err, ErrorTooManyTags)
+	// 去重后不超过上限
+	_, err = normalizeTags([]string{"a", "b", "c", "d", "e", "E"})
+	assert.NoError(t, err)
+}
+
+func TestDiffTags(t *testing.T) {
+	added, removed := diffTags([]string{"a", "b"}, []string{"b", "c"})
+	assert.Equal(t, []string{"c"}, added)
+	assert.Equal(t, []string{"a"}, removed)
+}
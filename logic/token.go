@@ -0,0 +1,117 @@
+package logic
+
+import (
+	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
+	"bluebell/models"
+	jwt2 "bluebell/pkg/jwt"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Token 的刷新与注销
+// 每次登录开启一个 token 家族（fid），刷新时旧的 Refresh Token 作废并签发同一家族的新 Token。
+// 已作废的 Refresh Token 再次出现说明可能被盗用，此时吊销整个家族，攻击者和用户都需要重新登录。
+
+var ErrorTokenRevoked = errors.New("token 已失效")
+
+// issueToken 签发一对 Token 并记录 Refresh Token
+// 用户的全局角色和担任版主的社区写入 Access Token，变更在下次刷新后生效
+func issueToken(user *models.User, familyID string) (pair *jwt2.TokenPair, err error) {
+	mods, err := mysql.GetModeratedCommunityIDs(user.UserID)
+	if err != nil {
+		zap.L().Error("mysql.GetModeratedCommunityIDs failed", zap.Int64("user_id", user.UserID), zap.Error(err))
+		return nil, err
+	}
+	pair, err = jwt2.GenToken(&jwt2.Subject{
+		UserID:      user.UserID,
+		Username:    user.Username,
+		Role:        user.Role,
+		Communities: mods,
+	}, familyID)
+	if err != nil {
+		return nil, err
+	}
+	if err = redis.SaveRefreshToken(pair.RefreshID, pair.FamilyID, pair.RefreshExpire); err != nil {
+		zap.L().Error("redis.SaveRefreshToken failed", zap.Int64("user_id", user.UserID), zap.Error(err))
+		return nil, err
+	}
+	if err = redis.AddUserTokenFamily(user.UserID, pair.FamilyID, pair.RefreshExpire); err != nil {
+		zap.L().Error("redis.AddUserTokenFamily failed", zap.Int64("user_id", user.UserID), zap.Error(err))
+		return nil, err
+	}
+	return pair, nil
+}
+
+// revokeUserTokens 吊销用户的所有 token 家族，修改或重置密码后所有设备都需要重新登录
+func revokeUserTokens(userID int64) error {
+	if err := redis.RevokeUserTokenFamilies(userID, jwt2.RefreshExpire()); err != nil {
+		zap.L().Error("redis.RevokeUserTokenFamilies failed", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// RefreshToken 使用 Refresh Token 换取一对新的 Token
+func RefreshToken(p *models.ParamRefreshToken) (user *models.User, err error) {
+	claims, err := jwt2.ParseRefreshToken(p.RefreshToken)
+	if err != nil {
+		return nil, ErrorTokenRevoked
+	}
+	// 1. 家族已被吊销（注销或检测到重放）
+	revoked, err := redis.IsTokenFamilyRevoked(claims.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrorTokenRevoked
+	}
+	// 2. 作废当前 Refresh Token，重复使用则吊销整个家族
+	if err = redis.ConsumeRefreshToken(claims.ID, claims.FamilyID); err != nil {
+		if errors.Is(err, redis.ErrorTokenReused) {
+			zap.L().Warn("refresh token reused, revoke token family",
+				zap.Int64("user_id", claims.UserID),
+				zap.String("fid", claims.FamilyID))
+			if err := redis.RevokeTokenFamily(claims.FamilyID, jwt2.RefreshExpire()); err != nil {
+				zap.L().Error("redis.RevokeTokenFamily failed", zap.Error(err))
+			}
+			return nil, ErrorTokenRevoked
+		}
+		return nil, err
+	}
+	// 3. 签发同一家族的新 Token
+	user, err = mysql.GetUserByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	pair, err := issueToken(user, claims.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	user.AccessToken = pair.AccessToken
+	user.RefreshToken = pair.RefreshToken
+	return user, nil
+}
+
+// IsAccessTokenRevoked 判断 Access Token 是否已注销或所属家族已被吊销
+func IsAccessTokenRevoked(claims *jwt2.MyClaims) (bool, error) {
+	return redis.IsAccessTokenRevoked(claims.ID, claims.FamilyID)
+}
+
+// Logout 注销当前 Access Token，并吊销其所属的 token 家族，使对应的 Refresh Token 一并失效
+func Logout(claims *jwt2.MyClaims) (err error) {
+	var ttl time.Duration
+	if claims.ExpiresAt != nil {
+		ttl = time.Until(claims.ExpiresAt.Time)
+	}
+	if err = redis.BlacklistAccessToken(claims.ID, ttl); err != nil {
+		zap.L().Error("redis.BlacklistAccessToken failed", zap.Error(err))
+		return
+	}
+	if err = redis.RevokeTokenFamily(claims.FamilyID, jwt2.RefreshExpire()); err != nil {
+		zap.L().Error("redis.RevokeTokenFamily failed", zap.Error(err))
+	}
+	return
+}
@@ -2,16 +2,25 @@ package logic
 
 import (
 	"bluebell/dao/mysql"
+	"bluebell/dao/redis"
 	"bluebell/models"
-	jwt2 "bluebell/pkg/jwt"
 	"bluebell/pkg/snowflake"
+	"bluebell/setting"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
 )
 
 func SignUp(p *models.ParamSignUp) (err error) {
-	// 0. 判断用户是否存在
+	// 0. 判断用户名和邮箱是否已被使用
 	if err := mysql.CheckUserExist(p.Username); err != nil {
 		return err
 	}
+	if err := mysql.CheckEmailExist(p.Email, 0); err != nil {
+		return err
+	}
 	// 1. 生成ID
 	userID := snowflake.GenID()
 	// 构造一个User实例
@@ -19,29 +28,110 @@ func SignUp(p *models.ParamSignUp) (err error) {
 		UserID:   userID,
 		Username: p.Username,
 		Password: p.Password,
+		Email:    p.Email,
 	}
 
 	// 2. 保存用户信息
 	if err := mysql.InsertUser(user); err != nil {
 		return err
 	}
+	// 3. 发送邮箱验证邮件，发送失败不影响注册，用户可以稍后重新发送
+	if err := sendVerifyEmail(userID, p.Email); err != nil {
+		zap.L().Error("logic.sendVerifyEmail failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
 	return
 }
 
-func Login(p *models.ParamLogin) (user *models.User, err error) {
+// LoginLockedError 登录被锁定，RetryAfter 为剩余锁定时间
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("登录尝试次数过多，请在 %s 后重试", e.RetryAfter)
+}
+
+// Login 登录，ip 为客户端IP，用于失败计数
+// 用户名不存在和密码错误同样计入失败次数，调用方也应返回相同的错误，避免枚举用户名
+func Login(p *models.ParamLogin, ip string) (user *models.User, err error) {
+	if user, err = checkPassword(p.Username, p.Password, ip); err != nil {
+		return nil, err
+	}
+	// 登录成功，生成JWT，开启一个新的 token 家族
+	pair, err := issueToken(user, "")
+	if err != nil {
+		return nil, err
+	}
+	user.AccessToken = pair.AccessToken
+	user.RefreshToken = pair.RefreshToken
+	return
+}
+
+// checkPassword 校验用户名和密码，登录和修改密码共用同一套失败计数和锁定
+func checkPassword(username, password, ip string) (user *models.User, err error) {
+	// 1. 用户名或IP处于锁定期，直接拒绝，不再校验密码
+	ttl, err := redis.GetLoginLockTTL(username, ip)
+	if err != nil {
+		zap.L().Error("redis.GetLoginLockTTL failed", zap.Error(err))
+		return nil, err
+	}
+	if ttl > 0 {
+		return nil, &LoginLockedError{RetryAfter: ttl}
+	}
+
 	user = &models.User{
-		Username: p.Username,
-		Password: p.Password,
+		Username: username,
+		Password: password,
 	}
 	if err = mysql.Login(user); err != nil {
+		if errors.Is(err, mysql.ErrorUserNotExist) || errors.Is(err, mysql.ErrorInvalidPassword) {
+			userRule, ipRule := loginGuardRules()
+			lock, rerr := redis.RecordLoginFailure(username, ip, userRule, ipRule)
+			if rerr != nil {
+				zap.L().Error("redis.RecordLoginFailure failed", zap.Error(rerr))
+			}
+			if lock > 0 {
+				return nil, &LoginLockedError{RetryAfter: lock}
+			}
+		}
 		return nil, err
 	}
-	// 登录成功，生成JWT
-	atoken, rtoken, err := jwt2.GenToken(user.UserID, user.Username)
-	if err != nil {
-		return
+	if err := redis.ResetLoginFailures(username); err != nil {
+		zap.L().Error("redis.ResetLoginFailures failed", zap.Error(err))
+	}
+	return user, nil
+}
+
+// UnlockLogin 管理员解除用户名和/或IP的登录锁定
+func UnlockLogin(p *models.ParamUnlockLogin) error {
+	return redis.UnlockLogin(p.Username, p.IP)
+}
+
+// loginGuardRules 根据配置生成用户名和IP的失败计数规则
+func loginGuardRules() (userRule, ipRule *redis.LoginGuardRule) {
+	cfg := setting.Conf.LoginGuardConfig
+	if cfg == nil {
+		cfg = new(setting.LoginGuardConfig)
+	}
+	window := time.Duration(cfg.Window) * time.Second
+	if window <= 0 {
+		window = 15 * time.Minute
+	}
+	base := time.Duration(cfg.BaseLockout) * time.Second
+	if base <= 0 {
+		base = time.Minute
+	}
+	maxLock := time.Duration(cfg.MaxLockout) * time.Second
+	if maxLock < base {
+		maxLock = time.Hour
+	}
+	userRule = &redis.LoginGuardRule{MaxAttempts: cfg.MaxAttempts, Window: window, BaseLockout: base, MaxLockout: maxLock}
+	if userRule.MaxAttempts <= 0 {
+		userRule.MaxAttempts = 5
+	}
+	ipRule = &redis.LoginGuardRule{MaxAttempts: cfg.IPMaxAttempts, Window: window, BaseLockout: base, MaxLockout: maxLock}
+	if ipRule.MaxAttempts <= 0 {
+		ipRule.MaxAttempts = 20
 	}
-	user.AccessToken = atoken
-	user.RefreshToken = rtoken
 	return
 }
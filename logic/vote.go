@@ -1,6 +1,7 @@
 package logic
 
 import (
+	"bluebell/dao/mysql"
 	"bluebell/dao/redis"
 	"bluebell/models"
 	"strconv"
@@ -33,12 +34,44 @@ import (
 	- 如果用户之前投过赞成票，现在又要投反对票，应该取消之前的赞成票，只留下反对票
 */
 
-// VoteForPost 为帖子投票
-func VoteForPost(userID int64, p *models.ParamVoteData) (err error) {
+// VoteForPost 为帖子投票，返回投票后帖子的票数
+func VoteForPost(userID int64, p *models.ParamVoteData) (res *models.PostVoteResult, err error) {
 	zap.L().Debug("logic.VoteForPost: ",
 		zap.Int64("userID", userID),
 		zap.String("postID", p.PostID),
-		zap.Int8("direction", p.Direction),
-		zap.Error(err))
-	return redis.VoteForPost(strconv.Itoa(int(userID)), p.PostID, float64(p.Direction))
+		zap.Int8("direction", p.Direction))
+	// 查询帖子的作者和社区，投票同时计入作者的 karma
+	postID, err := strconv.ParseInt(p.PostID, 10, 64)
+	if err != nil {
+		return nil, mysql.ErrorInvalidID
+	}
+	post, err := mysql.GetPostByID(postID)
+	if err != nil {
+		zap.L().Error("mysql.GetPostByID failed", zap.Int64("post_id", postID), zap.Error(err))
+		return nil, err
+	}
+	if post.ID == 0 {
+		return nil, mysql.ErrorInvalidID
+	}
+	up, down, err := redis.VoteForPost(strconv.Itoa(int(userID)), p.PostID, post.AuthorID, post.CommunityID,
+		float64(p.Direction), scorePerVote)
+	if err != nil {
+		return nil, err
+	}
+	// score 排序的分数已经在投票脚本中更新，这里重新计算其它排序方式下的分数
+	if err := updatePostRanks(p.PostID); err != nil {
+		zap.L().Error("logic.updatePostRanks failed", zap.String("postID", p.PostID), zap.Error(err))
+	}
+	events.Publish(PostVotedEvent{
+		Post:      post,
+		VoterID:   userID,
+		Direction: p.Direction,
+		UpVotes:   up,
+		DownVotes: down,
+	})
+	return &models.PostVoteResult{
+		PostID:    p.PostID,
+		UpVotes:   up,
+		DownVotes: down,
+	}, nil
 }
@@ -6,6 +6,9 @@ import (
 	"bluebell/dao/redis"
 	"bluebell/logger"
 	"bluebell/logic"
+	"bluebell/pkg/jwt"
+	"bluebell/pkg/mailer"
+	"bluebell/pkg/password"
 	"bluebell/pkg/snowflake"
 	"bluebell/router"
 	"bluebell/setting"
@@ -65,12 +68,72 @@ func main() {
 	}
 	defer persistenceManager.Stop() // 确保退出时停止任务
 
+	// 启动排序分数重算任务
+	rankingJob, err := logic.NewRankingJob(setting.Conf.RankingConfig)
+	if err != nil {
+		fmt.Printf("create ranking job failed, err:%v\n", err)
+		return
+	}
+	if err := rankingJob.Start(); err != nil {
+		fmt.Printf("start ranking cron job failed, err:%v\n", err)
+		return
+	}
+	defer rankingJob.Stop()
+
 	// 初始化雪花算法
 	if err := snowflake.Init(setting.Conf.StartTime, setting.Conf.MachineID); err != nil {
 		fmt.Printf("init snowflake failed, err:%v\n", err)
 		return
 	}
 
+	// 加载 JWT 签名密钥
+	if err := jwt.Init(setting.Conf.AuthConfig); err != nil {
+		fmt.Printf("init jwt keys failed, err:%v\n", err)
+		return
+	}
+
+	// 初始化密码哈希算法
+	if err := password.Init(setting.Conf.PasswordConfig); err != nil {
+		fmt.Printf("init password hasher failed, err:%v\n", err)
+		return
+	}
+
+	// 初始化邮件发送
+	if err := mailer.Init(setting.Conf.MailConfig); err != nil {
+		fmt.Printf("init mailer failed, err:%v\n", err)
+		return
+	}
+	if err := logic.InitEmailToken(setting.Conf.MailConfig); err != nil {
+		fmt.Printf("init mail token secret failed, err:%v\n", err)
+		return
+	}
+
+	// 初始化帖子搜索索引
+	if err := logic.InitSearch(setting.Conf.SearchConfig); err != nil {
+		fmt.Printf("init search index failed, err:%v\n", err)
+		return
+	}
+
+	// 加载发帖内容检查（敏感词库等）
+	if err := logic.InitModeration(setting.Conf.ModerationConfig); err != nil {
+		fmt.Printf("init content moderation failed, err:%v\n", err)
+		return
+	}
+
+	// 授予配置中的用户管理员角色
+	if err := logic.BootstrapAdmins(setting.Conf.AdminUserIDs); err != nil {
+		fmt.Printf("bootstrap admin users failed, err:%v\n", err)
+		return
+	}
+
+	// 启动事件处理（通知、实时推送等）
+	logic.StartEvents()
+	defer logic.StopEvents()
+
+	// 订阅实时推送频道
+	logic.StartStream()
+	defer logic.StopStream()
+
 	// 初始化gin框架内置的校验器使用的翻译器
 	if err := controller.InitTrans("zh"); err != nil {
 		zap.L().Fatal("Init validator trans failed, err: ", zap.Error(err))
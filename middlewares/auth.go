@@ -2,20 +2,37 @@ package middlewares
 
 import (
 	"bluebell/controller"
+	"bluebell/dao/redis"
 	"bluebell/pkg/jwt"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // JWTAuthMiddleware JWT认证中间件
 func JWTAuthMiddleware() func(ctx *gin.Context) {
+	return jwtAuth(false)
+}
+
+// JWTStreamAuthMiddleware 实时推送接口的JWT认证中间件
+// 浏览器的 EventSource 和 WebSocket 不能设置请求头，没有 Authorization 时允许通过 access_token 查询参数携带同一个 Access Token
+func JWTStreamAuthMiddleware() func(ctx *gin.Context) {
+	return jwtAuth(true)
+}
+
+func jwtAuth(allowQuery bool) func(ctx *gin.Context) {
 	return func(ctx *gin.Context) {
 		// 客户端携带Token有三种方式 1.放在请求头 2.放在请求体 3.放在URL
 		// 这里假设Token放在Header的Authorization中，并使用Bearer开头
 		// Authorization: Bearer xxx.xxx.xxx
 		// 这里的具体实现方式要依据你的实际业务情况而定
 		authHeader := ctx.Request.Header.Get("Authorization")
+		if authHeader == "" && allowQuery {
+			if token := ctx.Query("access_token"); token != "" {
+				authHeader = "Bearer " + token
+			}
+		}
 		if authHeader == "" {
 			controller.ResponseError(ctx, controller.CodeNeedLogin)
 			ctx.Abort()
@@ -35,8 +52,22 @@ func JWTAuthMiddleware() func(ctx *gin.Context) {
 			ctx.Abort()
 			return
 		}
+		// 已注销的 token 或已吊销家族中的 token 不再有效
+		revoked, err := redis.IsAccessTokenRevoked(mc.ID, mc.FamilyID)
+		if err != nil {
+			zap.L().Error("redis.IsAccessTokenRevoked failed", zap.Error(err))
+			controller.ResponseError(ctx, controller.CodeServerBusy)
+			ctx.Abort()
+			return
+		}
+		if revoked {
+			controller.ResponseError(ctx, controller.CodeInvalidToken)
+			ctx.Abort()
+			return
+		}
 		// 将当前请求的userID信息保存到请求的上下文ctx上
 		ctx.Set(controller.CtxUserIDKey, mc.UserID)
+		ctx.Set(controller.CtxClaimsKey, mc)
 		ctx.Next() // 后续的处理函数可以用 ctx.Get(CtxUserIDKey) 来获取当前请求的用户信息
 	}
 }
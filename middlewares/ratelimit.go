@@ -1,21 +1,56 @@
 package middlewares
 
 import (
+	"bluebell/controller"
+	"bluebell/dao/redis"
+	"bluebell/pkg/jwt"
+	"bluebell/setting"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/juju/ratelimit"
-
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
-// 限流中间件
-func RateLimitMiddleware(fillInterval time.Duration, cap int64) func(ctx *gin.Context) {
-	// 令牌桶算法实现限流
-	bucket := ratelimit.NewBucket(fillInterval, cap)
+// RateLimitMiddleware 限流中间件
+// 每个用户（未登录时为客户端IP）在每条单独配置的路由上各有一个令牌桶，其余路由共用默认的桶。
+// 桶保存在 Redis 中，所有实例共享同一份额度；Redis 不可用时放行，避免限流拖垮整个服务。
+func RateLimitMiddleware(cfg *setting.RateLimitConfig) func(ctx *gin.Context) {
+	routes := make(map[string]*setting.RateLimitRule)
+	var defaultRule *setting.RateLimitRule
+	if cfg != nil {
+		defaultRule = cfg.Default
+		for _, rule := range cfg.Routes {
+			routes[strings.ToUpper(rule.Method)+" "+rule.Path] = rule
+		}
+	}
 	return func(ctx *gin.Context) {
+		route := ctx.Request.Method + " " + ctx.FullPath()
+		rule, ok := routes[route]
+		if !ok {
+			route, rule = "default", defaultRule
+		}
+		if rule == nil || rule.Rate <= 0 || rule.Capacity <= 0 {
+			ctx.Next()
+			return
+		}
+
+		res, err := redis.TakeToken(route+":"+rateLimitSubject(ctx), rule.Rate, rule.Capacity)
+		if err != nil {
+			zap.L().Error("redis.TakeToken failed", zap.String("route", route), zap.Error(err))
+			ctx.Next()
+			return
+		}
+		// https://datatracker.ietf.org/doc/draft-ietf-httpapi-ratelimit-headers/
+		ctx.Header("RateLimit-Limit", strconv.FormatInt(rule.Capacity, 10))
+		ctx.Header("RateLimit-Remaining", strconv.FormatInt(res.Remaining, 10))
+		ctx.Header("RateLimit-Reset", ceilSeconds(res.Reset))
 		// 如果取不到令牌，就返回限流提示
-		if bucket.TakeAvailable(1) == 0 {
+		if !res.Allowed {
+			ctx.Header("Retry-After", ceilSeconds(res.RetryAfter))
 			ctx.JSON(http.StatusTooManyRequests, gin.H{
 				"msg": "too many requests",
 			})
@@ -25,3 +60,25 @@ func RateLimitMiddleware(fillInterval time.Duration, cap int64) func(ctx *gin.Co
 		ctx.Next()
 	}
 }
+
+// rateLimitSubject 限流的计数对象：登录用户按用户ID，未登录按客户端IP
+// 限流在认证之前执行，这里只解析 Token 取用户ID，Token 是否已注销交给认证中间件判断
+func rateLimitSubject(ctx *gin.Context) string {
+	if uid, ok := ctx.Get(controller.CtxUserIDKey); ok {
+		if userID, ok := uid.(int64); ok {
+			return "user:" + strconv.FormatInt(userID, 10)
+		}
+	}
+	parts := strings.SplitN(ctx.Request.Header.Get("Authorization"), " ", 2)
+	if len(parts) == 2 && parts[0] == "Bearer" {
+		if mc, err := jwt.ParseToken(parts[1]); err == nil {
+			return "user:" + strconv.FormatInt(mc.UserID, 10)
+		}
+	}
+	return "ip:" + ctx.ClientIP()
+}
+
+// ceilSeconds 向上取整到秒
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"bluebell/controller"
+	"bluebell/dao/mysql"
+	"bluebell/logic"
+	"bluebell/pkg/rbac"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RequirePermission 权限检查中间件，需要放在 JWTAuthMiddleware 之后
+// 只检查用户是否可能拥有该权限（全局角色或至少管理一个社区），涉及具体社区的检查由 logic 层完成
+// 角色和管理的社区每次从数据库读取，不使用 Token 中的声明，角色被撤销后立即失去权限
+func RequirePermission(perm rbac.Permission) func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		userID, ok := ctx.Value(controller.CtxUserIDKey).(int64)
+		if !ok {
+			controller.ResponseError(ctx, controller.CodeNeedLogin)
+			ctx.Abort()
+			return
+		}
+		principal, err := logic.GetPrincipal(userID)
+		if err != nil {
+			if errors.Is(err, mysql.ErrorUserNotExist) {
+				controller.ResponseError(ctx, controller.CodeInvalidToken)
+			} else {
+				zap.L().Error("logic.GetPrincipal failed", zap.Int64("user_id", userID), zap.Error(err))
+				controller.ResponseError(ctx, controller.CodeServerBusy)
+			}
+			ctx.Abort()
+			return
+		}
+		if !principal.Can(perm) {
+			controller.ResponseError(ctx, controller.CodeNoPermission)
+			ctx.Abort()
+			return
+		}
+		ctx.Set(controller.CtxPrincipalKey, principal)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,8 @@
+package models
+
+// Bookmark 用户收藏的帖子
+type Bookmark struct {
+	UserID   int64 `db:"user_id"`
+	PostID   int64 `db:"post_id"`
+	SaveTime int64 `db:"save_time"` // 收藏时间，毫秒时间戳
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Comment 评论结构体
+// ParentID 为 0 表示直接回复帖子；RootID 记录所属楼层的顶层评论，便于按楼层批量查询
+type Comment struct {
+	ID         int64     `json:"id,string" db:"comment_id"`
+	PostID     int64     `json:"post_id,string" db:"post_id"`
+	ParentID   int64     `json:"parent_id,string" db:"parent_id"`
+	RootID     int64     `json:"root_id,string" db:"root_id"`
+	AuthorID   int64     `json:"author_id,string" db:"author_id"`
+	Status     int32     `json:"status" db:"status"`
+	Content    string    `json:"content" db:"content"`
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+}
+
+// ApiCommentDetail 评论详情接口，Children 中为该评论下的回复
+type ApiCommentDetail struct {
+	AuthorName string              `json:"author_name"`
+	UpVotes    int64               `json:"up_votes"`
+	DownVotes  int64               `json:"down_votes"`
+	*Comment                       // 嵌入评论结构体
+	Children   []*ApiCommentDetail `json:"children"`
+}
+
+// PostCommentNum 帖子评论数
+type PostCommentNum struct {
+	PostID int64 `db:"post_id"`
+	Num    int64 `db:"num"`
+}
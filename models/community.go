@@ -2,13 +2,39 @@ package models
 
 import "time"
 
+// 社区状态
+const (
+	CommunityStatusArchived int32 = 0 // 已归档，不能再发帖
+	CommunityStatusNormal   int32 = 1 // 正常
+)
+
+// 社区成员角色
+const (
+	CommunityRoleModerator int8 = 1 // 版主
+	CommunityRoleCreator   int8 = 2 // 创建者
+)
+
 type Community struct {
 	ID   int64  `json:"id" db:"community_id"`
 	Name string `json:"name" db:"community_name"`
+	Slug string `json:"slug" db:"slug"`
 }
 type CommunityDetail struct {
 	ID        int64     `json:"id" db:"community_id"`
 	Name      string    `json:"name" db:"community_name"`
-	Intro     string    `json:"introduction omitempty" db:"introduction"`
+	Slug      string    `json:"slug" db:"slug"`
+	Intro     string    `json:"introduction,omitempty" db:"introduction"`
+	IconURL   string    `json:"icon_url" db:"icon_url"`
+	Rules     string    `json:"rules" db:"rules"`
+	Status    int32     `json:"status" db:"status"`
+	CreatorID int64     `json:"creator_id,string" db:"creator_id"`
 	CreatTime time.Time `json:"create_time" db:"create_time"`
 }
+
+// CommunityMember 社区的创建者和版主
+type CommunityMember struct {
+	CommunityID int64     `json:"community_id" db:"community_id"`
+	UserID      int64     `json:"user_id,string" db:"user_id"`
+	Role        int8      `json:"role" db:"role"`
+	CreateTime  time.Time `json:"create_time" db:"create_time"`
+}
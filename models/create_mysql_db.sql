@@ -4,35 +4,84 @@ CREATE TABLE `user` (
     `id` bigint(20) NOT NULL AUTO_INCREMENT,
     `user_id` bigint(20) NOT NULL,
     `username` varchar(64) COLLATE utf8mb4_general_ci NOT NULL,
-    `password` varchar(64) COLLATE utf8mb4_general_ci NOT NULL,
+    `password` varchar(255) COLLATE utf8mb4_general_ci NOT NULL COMMENT '自描述格式的密码哈希（PHC/bcrypt），旧版为 MD5',
     `email` varchar(64) COLLATE utf8mb4_general_ci,
+    `email_verified` tinyint(4) NOT NULL DEFAULT '0' COMMENT '邮箱是否已验证',
     `gender` tinyint(4) NOT NULL DEFAULT '0',
+    `role` varchar(16) COLLATE utf8mb4_general_ci NOT NULL DEFAULT 'user' COMMENT '全局角色：user、moderator、admin',
+    `bio` varchar(256) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '个人简介',
+    `avatar_url` varchar(512) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '头像地址',
     `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP,
     `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE
 CURRENT_TIMESTAMP,
     PRIMARY KEY (`id`),
     UNIQUE KEY `idx_username` (`username`) USING BTREE,
-    UNIQUE KEY `idx_user_id` (`user_id`) USING BTREE
+    UNIQUE KEY `idx_user_id` (`user_id`) USING BTREE,
+    UNIQUE KEY `idx_email` (`email`)
  ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+-- 已有数据库升级：ALTER TABLE `user` MODIFY `password` varchar(255) COLLATE utf8mb4_general_ci NOT NULL;
+-- 已有数据库升级：ALTER TABLE `user` ADD COLUMN `role` varchar(16) COLLATE utf8mb4_general_ci NOT NULL DEFAULT 'user' COMMENT '全局角色：user、moderator、admin' AFTER `gender`;
+-- 已有数据库升级：ALTER TABLE `user` ADD COLUMN `bio` varchar(256) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '个人简介' AFTER `role`,
+--     ADD COLUMN `avatar_url` varchar(512) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '头像地址' AFTER `bio`;
+-- 已有数据库升级：UPDATE `user` SET `email` = NULL WHERE `email` = '';
+--     ALTER TABLE `user` ADD COLUMN `email_verified` tinyint(4) NOT NULL DEFAULT '0' COMMENT '邮箱是否已验证' AFTER `email`, ADD UNIQUE KEY `idx_email` (`email`);
+-- 设置管理员：在配置的 admin_user_ids 中列出用户ID，启动时自动授予（原 admin_user_ids 中的管理员无需手动迁移）；也可以直接执行 UPDATE `user` SET `role` = 'admin' WHERE `user_id` = ?;
 
 DROP TABLE IF EXISTS `community`;
 CREATE TABLE `community` (
      `id` int(11) NOT NULL AUTO_INCREMENT,
      `community_id` int(10) unsigned NOT NULL,
      `community_name` varchar(128) COLLATE utf8mb4_general_ci NOT NULL,
+     `slug` varchar(64) COLLATE utf8mb4_general_ci NOT NULL COMMENT 'URL 中使用的短名称',
      `introduction` varchar(256) COLLATE utf8mb4_general_ci NOT NULL,
+     `icon_url` varchar(512) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '图标地址',
+     `rules` varchar(2048) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '发帖规则',
+     `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '社区状态：1 正常、0 已归档',
+     `creator_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '创建者的用户id',
      `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
      `update_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
      PRIMARY KEY (`id`),
      UNIQUE KEY `idx_community_id` (`community_id`),
-     UNIQUE KEY `idx_community_name` (`community_name`)
+     UNIQUE KEY `idx_community_name` (`community_name`),
+     UNIQUE KEY `idx_slug` (`slug`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+-- 已有数据库升级：
+-- ALTER TABLE `community` ADD COLUMN `slug` varchar(64) COLLATE utf8mb4_general_ci NOT NULL COMMENT 'URL 中使用的短名称' AFTER `community_name`,
+--     ADD COLUMN `icon_url` varchar(512) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '图标地址' AFTER `introduction`,
+--     ADD COLUMN `rules` varchar(2048) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '发帖规则' AFTER `icon_url`,
+--     ADD COLUMN `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '社区状态：1 正常、0 已归档' AFTER `rules`,
+--     ADD COLUMN `creator_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '创建者的用户id' AFTER `status`;
+-- UPDATE `community` SET `slug` = CAST(`community_id` AS CHAR) WHERE `slug` = '';
+-- ALTER TABLE `community` ADD UNIQUE KEY `idx_slug` (`slug`);
 
 
-INSERT INTO `community` VALUES ('1', '1', 'Go', 'Golang', '2016-11-01 08:10:10', '2016-11-01 08:10:10');
-INSERT INTO `community` VALUES ('2', '2', 'leetcode', '刷题刷题刷题', '2020-01-01 08:00:00', '2020-01-01 08:00:00');
-INSERT INTO `community` VALUES ('3', '3', 'CS:GO', 'Rush B。。。', '2018-08-07 08:30:00', '2018-08-07 08:30:00');
-INSERT INTO `community` VALUES ('4', '4', 'LOL', '欢迎来到英雄联盟!', '2016-01-01 08:00:00', '2016-01-01 08:00:00');
+INSERT INTO `community` (`id`, `community_id`, `community_name`, `slug`, `introduction`, `create_time`, `update_time`) VALUES ('1', '1', 'Go', 'go', 'Golang', '2016-11-01 08:10:10', '2016-11-01 08:10:10');
+INSERT INTO `community` (`id`, `community_id`, `community_name`, `slug`, `introduction`, `create_time`, `update_time`) VALUES ('2', '2', 'leetcode', 'leetcode', '刷题刷题刷题', '2020-01-01 08:00:00', '2020-01-01 08:00:00');
+INSERT INTO `community` (`id`, `community_id`, `community_name`, `slug`, `introduction`, `create_time`, `update_time`) VALUES ('3', '3', 'CS:GO', 'csgo', 'Rush B。。。', '2018-08-07 08:30:00', '2018-08-07 08:30:00');
+INSERT INTO `community` (`id`, `community_id`, `community_name`, `slug`, `introduction`, `create_time`, `update_time`) VALUES ('4', '4', 'LOL', 'lol', '欢迎来到英雄联盟!', '2016-01-01 08:00:00', '2016-01-01 08:00:00');
+
+DROP TABLE IF EXISTS `community_member`;
+CREATE TABLE `community_member` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `community_id` int(10) unsigned NOT NULL COMMENT '社区id',
+    `user_id` bigint(20) NOT NULL COMMENT '用户id',
+    `role` tinyint(4) NOT NULL COMMENT '角色：2 创建者、1 版主',
+    `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_community_user` (`community_id`, `user_id`),
+    KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+DROP TABLE IF EXISTS `community_subscription`;
+CREATE TABLE `community_subscription` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `user_id` bigint(20) NOT NULL COMMENT '用户id',
+    `community_id` int(10) unsigned NOT NULL COMMENT '社区id',
+    `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_user_community` (`user_id`, `community_id`),
+    KEY `idx_community_id` (`community_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci COMMENT='用户加入的社区';
 
 DROP TABLE IF EXISTS `post`;
 CREATE TABLE `post` (
@@ -42,23 +91,36 @@ CREATE TABLE `post` (
     `content` varchar(8192) COLLATE utf8mb4_general_ci NOT NULL COMMENT '内容',
     `author_id` bigint(20) NOT NULL COMMENT '作者的用户id',
     `community_id` bigint(20) NOT NULL COMMENT '所属社区',
-    `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '帖子状态',
+    `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '帖子状态：0 已删除、1 正常、2 待审核',
+    `locked` tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否被版主锁定，锁定后不能编辑和评论',
+    `pinned` tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否在社区中置顶',
     `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
     `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
     PRIMARY KEY (`id`),
     UNIQUE KEY `idx_post_id` (`post_id`),
     KEY `idx_author_id` (`author_id`),
-    KEY `idx_community_id` (`community_id`)
+    KEY `idx_community_id` (`community_id`),
+    FULLTEXT KEY `idx_title_content` (`title`, `content`) WITH PARSER ngram
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+-- 已有数据库升级：ALTER TABLE `post` ADD COLUMN `locked` tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否被版主锁定，锁定后不能编辑和评论' AFTER `status`,
+--     ADD COLUMN `pinned` tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否在社区中置顶' AFTER `locked`;
+-- 已有数据库升级：ALTER TABLE `post` ADD FULLTEXT INDEX `idx_title_content` (`title`, `content`) WITH PARSER ngram;
 
 DROP TABLE IF EXISTS `post_scores`;
 CREATE TABLE `post_scores` (
     `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键 ID',
     `post_id` BIGINT UNSIGNED NOT NULL COMMENT '帖子 ID',
     `score` DOUBLE NOT NULL DEFAULT 0 COMMENT '帖子分数',
+    `up_votes` BIGINT NOT NULL DEFAULT 0 COMMENT '归档时的赞成票数',
+    `down_votes` BIGINT NOT NULL DEFAULT 0 COMMENT '归档时的反对票数',
+    `archived` TINYINT NOT NULL DEFAULT 0 COMMENT '是否已归档，归档后分数和票数不再变化',
     PRIMARY KEY (`id`),
     UNIQUE KEY `idx_post_id` (`post_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='帖子分数表，存储帖子及其分数信息';
+-- 已有数据库升级：
+-- ALTER TABLE `post_scores` ADD COLUMN `up_votes` BIGINT NOT NULL DEFAULT 0 COMMENT '归档时的赞成票数',
+--     ADD COLUMN `down_votes` BIGINT NOT NULL DEFAULT 0 COMMENT '归档时的反对票数',
+--     ADD COLUMN `archived` TINYINT NOT NULL DEFAULT 0 COMMENT '是否已归档，归档后分数和票数不再变化';
 
 DROP TABLE IF EXISTS `post_votes`;
 CREATE TABLE `post_votes` (
@@ -69,3 +131,117 @@ CREATE TABLE `post_votes` (
     PRIMARY KEY (`id`),
     UNIQUE KEY `idx_post_user` (`post_id`, `user_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='帖子投票表，存储帖子及用户的投票信息';
+
+DROP TABLE IF EXISTS `bookmark`;
+CREATE TABLE `bookmark` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `user_id` bigint(20) NOT NULL COMMENT '用户id',
+    `post_id` bigint(20) NOT NULL COMMENT '收藏的帖子id',
+    `save_time` bigint(20) NOT NULL COMMENT '收藏时间，毫秒时间戳',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_user_post` (`user_id`, `post_id`),
+    KEY `idx_user_time` (`user_id`, `save_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci COMMENT='用户收藏的帖子，由 Redis 定时持久化';
+
+DROP TABLE IF EXISTS `comment`;
+CREATE TABLE `comment` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `comment_id` bigint(20) NOT NULL COMMENT '评论id',
+    `post_id` bigint(20) NOT NULL COMMENT '所属帖子id',
+    `parent_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '父评论id，0 表示直接回复帖子',
+    `root_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '所属楼层的顶层评论id，0 表示自身即为顶层评论',
+    `author_id` bigint(20) NOT NULL COMMENT '评论者的用户id',
+    `content` varchar(2048) COLLATE utf8mb4_general_ci NOT NULL COMMENT '评论内容',
+    `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '评论状态',
+    `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `update_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_comment_id` (`comment_id`),
+    KEY `idx_post_root` (`post_id`, `root_id`),
+    KEY `idx_root_id` (`root_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci COMMENT='评论表，parent_id/root_id 组成楼中楼结构';
+
+DROP TABLE IF EXISTS `post_revision`;
+CREATE TABLE `post_revision` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `post_id` bigint(20) NOT NULL COMMENT '帖子id',
+    `revision` int(11) NOT NULL COMMENT '版本号，从 1 开始递增',
+    `editor_id` bigint(20) NOT NULL COMMENT '编辑者的用户id',
+    `title` varchar(128) COLLATE utf8mb4_general_ci NOT NULL COMMENT '编辑前的标题',
+    `content` varchar(8192) COLLATE utf8mb4_general_ci NOT NULL COMMENT '编辑前的内容',
+    `create_time` timestamp NULL DEFAULT CURRENT_TIMESTAMP COMMENT '编辑时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_post_revision` (`post_id`, `revision`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci COMMENT='帖子修订历史，每次编辑保存一份编辑前的快照';
+
+DROP TABLE IF EXISTS `post_tag`;
+CREATE TABLE `post_tag` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `post_id` bigint(20) NOT NULL COMMENT '帖子id',
+    `tag` varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '规范化后的标签',
+    `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_post_tag` (`post_id`, `tag`),
+    KEY `idx_tag` (`tag`, `post_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci COMMENT='帖子的标签';
+
+DROP TABLE IF EXISTS `community_ban`;
+CREATE TABLE `community_ban` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `community_id` int(10) unsigned NOT NULL COMMENT '社区id',
+    `user_id` bigint(20) NOT NULL COMMENT '被封禁的用户id',
+    `moderator_id` bigint(20) NOT NULL COMMENT '执行封禁的用户id',
+    `reason` varchar(256) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '封禁原因',
+    `expire_time` timestamp NULL DEFAULT NULL COMMENT '解封时间，NULL 表示永久',
+    `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_community_user` (`community_id`, `user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci COMMENT='社区封禁的用户';
+
+DROP TABLE IF EXISTS `moderation_log`;
+CREATE TABLE `moderation_log` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `actor_id` bigint(20) NOT NULL COMMENT '操作人的用户id',
+    `action` varchar(32) COLLATE utf8mb4_general_ci NOT NULL COMMENT '操作类型',
+    `community_id` int(10) unsigned NOT NULL DEFAULT '0' COMMENT '所属社区，全局操作为 0',
+    `target_type` varchar(16) COLLATE utf8mb4_general_ci NOT NULL COMMENT '操作对象类型：post、user',
+    `target_id` bigint(20) NOT NULL COMMENT '操作对象id',
+    `reason` varchar(256) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '操作原因',
+    `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_community_id` (`community_id`, `id`),
+    KEY `idx_actor_id` (`actor_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci COMMENT='管理操作日志';
+
+DROP TABLE IF EXISTS `post_review`;
+CREATE TABLE `post_review` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `post_id` bigint(20) NOT NULL COMMENT '待审核的帖子id',
+    `community_id` bigint(20) NOT NULL COMMENT '帖子所属社区',
+    `author_id` bigint(20) NOT NULL COMMENT '帖子作者的用户id',
+    `kind` tinyint(4) NOT NULL DEFAULT '0' COMMENT '进入审核的原因：0 新帖子、1 编辑帖子',
+    `reasons` varchar(512) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '内容检查转人工审核的原因',
+    `status` tinyint(4) NOT NULL DEFAULT '0' COMMENT '审核状态：0 待审核、1 通过、2 拒绝',
+    `reviewer_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '审核人的用户id',
+    `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `review_time` timestamp NULL DEFAULT NULL COMMENT '审核时间',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_post_id` (`post_id`),
+    KEY `idx_status` (`status`, `community_id`, `id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci COMMENT='帖子审核队列，未通过内容检查的新帖子和编辑后的帖子在审核通过前不公开';
+
+DROP TABLE IF EXISTS `notification`;
+CREATE TABLE `notification` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `user_id` bigint(20) NOT NULL COMMENT '接收通知的用户id',
+    `actor_id` bigint(20) NOT NULL COMMENT '触发通知的用户id',
+    `type` varchar(16) COLLATE utf8mb4_general_ci NOT NULL COMMENT '通知类型：vote、reply、mention',
+    `post_id` bigint(20) NOT NULL COMMENT '相关的帖子id',
+    `comment_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '相关的评论id，0 表示帖子本身',
+    `content` varchar(256) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT '帖子标题或评论内容的摘要',
+    `is_read` tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否已读',
+    `create_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_dedup` (`user_id`, `type`, `post_id`, `comment_id`, `actor_id`),
+    KEY `idx_user_id` (`user_id`, `is_read`, `id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci COMMENT='用户通知，同一个用户对同一个对象的同类通知只保留一条';
@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// 管理操作类型
+const (
+	ModActionPostRemove = "post.remove"
+	ModActionPostLock   = "post.lock"
+	ModActionPostUnlock = "post.unlock"
+	ModActionPostPin    = "post.pin"
+	ModActionPostUnpin  = "post.unpin"
+	ModActionUserBan    = "user.ban"
+	ModActionUserUnban  = "user.unban"
+	ModActionUserRole   = "user.role"
+
+	// 审核队列中的帖子
+	ModActionPostApprove = "post.approve"
+	ModActionPostReject  = "post.reject"
+)
+
+// 管理操作对象类型
+const (
+	ModTargetPost = "post"
+	ModTargetUser = "user"
+)
+
+// ModerationLog 管理操作日志
+type ModerationLog struct {
+	ID          int64     `json:"id,string" db:"id"`
+	ActorID     int64     `json:"actor_id,string" db:"actor_id"`
+	Action      string    `json:"action" db:"action"`
+	CommunityID int64     `json:"community_id" db:"community_id"`
+	TargetType  string    `json:"target_type" db:"target_type"`
+	TargetID    int64     `json:"target_id,string" db:"target_id"`
+	Reason      string    `json:"reason" db:"reason"`
+	CreateTime  time.Time `json:"create_time" db:"create_time"`
+}
+
+// CommunityBan 社区封禁记录
+type CommunityBan struct {
+	CommunityID int64      `json:"community_id" db:"community_id"`
+	UserID      int64      `json:"user_id,string" db:"user_id"`
+	ModeratorID int64      `json:"moderator_id,string" db:"moderator_id"`
+	Reason      string     `json:"reason" db:"reason"`
+	ExpireTime  *time.Time `json:"expire_time" db:"expire_time"` // 为空表示永久封禁
+	CreateTime  time.Time  `json:"create_time" db:"create_time"`
+}
+
+// 帖子审核状态
+const (
+	ReviewStatusPending  int8 = 0 // 待审核
+	ReviewStatusApproved int8 = 1 // 通过
+	ReviewStatusRejected int8 = 2 // 拒绝
+)
+
+// 帖子进入审核队列的原因
+const (
+	ReviewKindCreate int8 = 0 // 新帖子，拒绝时删除帖子
+	ReviewKindEdit   int8 = 1 // 编辑后的帖子，拒绝时恢复编辑前的版本
+)
+
+// PostReview 审核队列中的帖子，未通过内容检查的帖子在审核通过前不公开
+type PostReview struct {
+	PostID      int64      `json:"post_id,string" db:"post_id"`
+	CommunityID int64      `json:"community_id" db:"community_id"`
+	AuthorID    int64      `json:"author_id,string" db:"author_id"`
+	Kind        int8       `json:"kind" db:"kind"`       // 0 新帖子、1 编辑后的帖子
+	Reasons     string     `json:"reasons" db:"reasons"` // 转人工审核的原因，多个原因用分号分隔
+	Status      int8       `json:"status" db:"status"`
+	ReviewerID  int64      `json:"reviewer_id,string" db:"reviewer_id"`
+	CreateTime  time.Time  `json:"create_time" db:"create_time"`
+	ReviewTime  *time.Time `json:"review_time" db:"review_time"`
+	Title       string     `json:"title" db:"title"`
+	Content     string     `json:"content" db:"content"`
+}
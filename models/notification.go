@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// 通知类型
+const (
+	NotificationVote    = "vote"    // 帖子收到赞成票
+	NotificationReply   = "reply"   // 帖子或评论收到回复
+	NotificationMention = "mention" // 在帖子中被 @
+)
+
+// Notification 通知
+type Notification struct {
+	ID         int64     `json:"id,string" db:"id"`
+	UserID     int64     `json:"-" db:"user_id"`
+	ActorID    int64     `json:"actor_id,string" db:"actor_id"`
+	ActorName  string    `json:"actor_name" db:"actor_name"`
+	Type       string    `json:"type" db:"type"`
+	PostID     int64     `json:"post_id,string" db:"post_id"`
+	CommentID  int64     `json:"comment_id,string" db:"comment_id"`
+	Content    string    `json:"content" db:"content"`
+	IsRead     bool      `json:"is_read" db:"is_read"`
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+}
+
+// ApiNotificationList 通知列表和未读数
+type ApiNotificationList struct {
+	Unread int64           `json:"unread"`
+	List   []*Notification `json:"list"`
+}
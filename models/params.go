@@ -5,6 +5,7 @@ package models
 // ParamSignUp 注册请求参数
 type ParamSignUp struct {
 	Username   string `json:"username" binding:"required"`
+	Email      string `json:"email" binding:"required,email,max=64"`
 	Password   string `json:"password" binding:"required"`
 	RePassword string `json:"re_password" binding:"required,eqfield=Password"`
 }
@@ -15,16 +16,124 @@ type ParamLogin struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// ParamUpdateProfile 修改个人资料参数，只修改请求中携带的字段
+type ParamUpdateProfile struct {
+	Email     *string `json:"email" binding:"omitempty,email,max=64"`
+	Gender    *int    `json:"gender" binding:"omitempty,oneof=0 1 2"` // 0 未知、1 男、2 女
+	Bio       *string `json:"bio" binding:"omitempty,max=256"`
+	AvatarURL *string `json:"avatar_url" binding:"omitempty,max=512"` // 为空表示清除头像，否则必须是 http(s) 地址
+}
+
+// ParamEmailToken 验证邮箱参数
+type ParamEmailToken struct {
+	Token string `json:"token" binding:"required"` // 邮件链接中的令牌
+}
+
+// ParamForgotPassword 忘记密码参数
+type ParamForgotPassword struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ParamResetPassword 重置密码参数
+type ParamResetPassword struct {
+	Token      string `json:"token" binding:"required"` // 邮件链接中的令牌
+	Password   string `json:"password" binding:"required"`
+	RePassword string `json:"re_password" binding:"required,eqfield=Password"`
+}
+
+// ParamNotificationList 通知列表的query string参数
+type ParamNotificationList struct {
+	Page   int64 `json:"page" form:"page"`
+	Size   int64 `json:"size" form:"size"`
+	Unread bool  `json:"unread" form:"unread"` // 只查询未读通知
+}
+
+// ParamReadNotifications 标记通知为已读的参数，IDs 为空时标记全部
+// 通知列表中的 id 是字符串，这里同样按字符串接收
+type ParamReadNotifications struct {
+	IDs []string `json:"ids" binding:"max=100,dive,numeric"`
+}
+
+// ParamStream 实时推送订阅的频道，community_id 和 post_id 可以重复传多个
+// 浏览器的 EventSource 和 WebSocket 不能设置请求头，可以用 access_token 携带 Access Token
+type ParamStream struct {
+	CommunityIDs []int64  `form:"community_id" binding:"max=20"`         // 推送这些社区的新帖子
+	PostIDs      []string `form:"post_id" binding:"max=50,dive,numeric"` // 推送这些帖子的票数变化
+}
+
+// ParamBookmarkList 收藏列表的query string参数
+type ParamBookmarkList struct {
+	Size   int64  `json:"size" form:"size"`     // 最多 50 条
+	Cursor string `json:"cursor" form:"cursor"` // 上一页返回的 next_cursor，第一页传空
+}
+
+// ParamTrendingTags 热门标签的query string参数
+type ParamTrendingTags struct {
+	Size int64 `json:"size" form:"size"` // 最多 50 个
+}
+
+// ParamKarmaLeaderboard karma 排行榜的query string参数
+type ParamKarmaLeaderboard struct {
+	CommunityID int64 `json:"community_id" form:"community_id"` // 为空时查询全局排行榜
+	Size        int64 `json:"size" form:"size"`                 // 最多 100 名
+}
+
+// ParamChangePassword 修改密码参数
+type ParamChangePassword struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	Password    string `json:"password" binding:"required,nefield=OldPassword"`
+	RePassword  string `json:"re_password" binding:"required,eqfield=Password"`
+}
+
+// ParamUpdatePost 编辑帖子请求参数
+type ParamUpdatePost struct {
+	Title   string   `json:"title" binding:"required,max=128"`
+	Content string   `json:"content" binding:"required,max=8192"`
+	Tags    []string `json:"tags"` // 不传时不修改标签，传空数组时清除标签
+}
+
+// ParamUnlockLogin 解除登录锁定请求参数，用户名和IP至少填一个
+type ParamUnlockLogin struct {
+	Username string `json:"username" binding:"required_without=IP"`
+	IP       string `json:"ip" binding:"omitempty,ip"`
+}
+
+// ParamRefreshToken 刷新Token请求参数
+type ParamRefreshToken struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 // ParamVoteData 投票数据
 type ParamVoteData struct {
 	PostID    string `json:"post_id" binding:"required"`              // 帖子ID
 	Direction int8   `json:"direction,string" binding:"oneof=1 0 -1"` // 赞成票(1)还是反对票(-1)还是取消投票(0)
 }
 
+// ParamCommentVoteData 评论投票数据
+type ParamCommentVoteData struct {
+	CommentID string `json:"comment_id" binding:"required"`           // 评论ID
+	Direction int8   `json:"direction,string" binding:"oneof=1 0 -1"` // 赞成票(1)还是反对票(-1)还是取消投票(0)
+}
+
+// ParamCreateComment 发表评论请求参数
+type ParamCreateComment struct {
+	ParentID int64  `json:"parent_id,string"` // 回复的评论ID，为空表示直接回复帖子
+	Content  string `json:"content" binding:"required,max=2048"`
+}
+
+// ParamCommentList 获取评论列表query string参数，分页针对顶层评论
+type ParamCommentList struct {
+	Page int64 `json:"page" form:"page"`
+	Size int64 `json:"size" form:"size"`
+}
+
 // ParamPostList 获取帖子列表query string参数
 const (
-	OrderTime  = "time"
-	OrderScore = "score"
+	OrderTime    = "time"
+	OrderScore   = "score"   // 线性投票分数
+	OrderHot     = "hot"     // Reddit hot 算法
+	OrderGravity = "gravity" // Hacker News 重力衰减算法
+	OrderBest    = "best"    // 威尔逊得分区间下界
 )
 
 // ParamPostList 获取帖子列表query string参数
@@ -32,7 +141,85 @@ type ParamPostList struct {
 	Page        int64  `json:"page" form:"page"`
 	Size        int64  `json:"size" form:"size"`
 	CommunityID int64  `json:"community_id" form:"community_id"` // 社区ID 可以为空
-	Order       string `json:"order" form:"order"`
+	Tag         string `json:"tag" form:"tag"`                   // 标签 可以为空，可以与社区ID同时使用
+	Order       string `json:"order" form:"order"`               // 排序方式：time、score、hot、gravity、best
+	Cursor      string `json:"cursor" form:"cursor"`             // 游标分页：上一页返回的 next_cursor，第一页传空；携带该参数时忽略 page
+}
+
+// ParamCreateCommunity 创建社区参数
+type ParamCreateCommunity struct {
+	Name         string `json:"name" binding:"required,max=128"`
+	Slug         string `json:"slug" binding:"required,min=2,max=64"` // 只能包含小写字母、数字和连字符，创建后不能修改
+	Introduction string `json:"introduction" binding:"max=256"`
+	IconURL      string `json:"icon_url" binding:"omitempty,url,max=512"`
+	Rules        string `json:"rules" binding:"max=2048"`
+}
+
+// ParamUpdateCommunity 修改社区参数
+type ParamUpdateCommunity struct {
+	Name         string `json:"name" binding:"required,max=128"`
+	Introduction string `json:"introduction" binding:"max=256"`
+	IconURL      string `json:"icon_url" binding:"omitempty,url,max=512"`
+	Rules        string `json:"rules" binding:"max=2048"`
+}
+
+// ParamCommunityModerator 设置版主参数
+type ParamCommunityModerator struct {
+	UserID int64 `json:"user_id,string" binding:"required"`
+}
+
+// ParamModeratePost 删除帖子参数
+type ParamModeratePost struct {
+	Reason string `json:"reason" binding:"max=256"`
+}
+
+// ParamLockPost 锁定/解锁帖子参数
+type ParamLockPost struct {
+	Locked bool   `json:"locked"`
+	Reason string `json:"reason" binding:"max=256"`
+}
+
+// ParamPinPost 置顶/取消置顶帖子参数
+type ParamPinPost struct {
+	Pinned bool   `json:"pinned"`
+	Reason string `json:"reason" binding:"max=256"`
+}
+
+// ParamBanUser 社区封禁用户参数
+type ParamBanUser struct {
+	UserID   int64  `json:"user_id,string" binding:"required"`
+	Reason   string `json:"reason" binding:"max=256"`
+	Duration int64  `json:"duration" binding:"min=0"` // 封禁时长，单位：小时，0 表示永久
+}
+
+// ParamModerationLogList 管理操作日志的query string参数
+type ParamModerationLogList struct {
+	CommunityID int64 `json:"community_id" form:"community_id"` // 社区版主必须指定自己管理的社区
+	Page        int64 `json:"page" form:"page"`
+	Size        int64 `json:"size" form:"size"`
+}
+
+// ParamPostReviewList 审核队列的query string参数
+type ParamPostReviewList struct {
+	CommunityID int64 `json:"community_id" form:"community_id"` // 社区版主可以只查询自己管理的某个社区
+	Page        int64 `json:"page" form:"page"`
+	Size        int64 `json:"size" form:"size" binding:"max=100"`
+}
+
+// ParamSetRole 修改用户全局角色参数
+type ParamSetRole struct {
+	Role   string `json:"role" binding:"required,oneof=user moderator admin"`
+	Reason string `json:"reason" binding:"max=256"`
+}
+
+// ParamSearch 搜索帖子的query string参数
+type ParamSearch struct {
+	Keyword     string `json:"q" form:"q" binding:"required"`
+	CommunityID int64  `json:"community_id" form:"community_id"` // 社区ID 可以为空
+	Start       int64  `json:"start" form:"start"`               // 发帖时间下限，Unix 时间戳（秒），可以为空
+	End         int64  `json:"end" form:"end"`                   // 发帖时间上限，Unix 时间戳（秒），可以为空
+	Page        int64  `json:"page" form:"page"`
+	Size        int64  `json:"size" form:"size"`
 }
 
 // ParamCommunityList 按社区ID获取帖子列表的query string参数
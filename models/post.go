@@ -4,6 +4,13 @@ import (
 	"time"
 )
 
+// 帖子状态
+const (
+	PostStatusDeleted int32 = 0 // 已删除（软删除）
+	PostStatusNormal  int32 = 1 // 正常
+	PostStatusPending int32 = 2 // 待审核，审核通过前不公开
+)
+
 // 内存对齐
 
 // Post 帖子结构体
@@ -12,25 +19,76 @@ type Post struct {
 	AuthorID    int64     `json:"author_id,string" db:"author_id"`
 	CommunityID int64     `json:"community_id" db:"community_id" binding:"required"`
 	Status      int32     `json:"status" db:"status"`
+	Locked      bool      `json:"locked" db:"locked"` // 被版主锁定，不能编辑和评论
+	Pinned      bool      `json:"pinned" db:"pinned"` // 在社区中置顶
 	Title       string    `json:"title" db:"title" binding:"required"`
 	Content     string    `json:"content" db:"content" binding:"required"`
 	CreateTime  time.Time `json:"create_time" db:"create_time"`
+	Tags        []string  `json:"tags" db:"-"` // 规范化后的标签，保存在 post_tag 表中
 }
 
 // ApiPostDetail 帖子详情接口
 type ApiPostDetail struct {
 	AuthorName       string             `json:"author_name"`
-	VoteNum          int64              `json:"vote_num"`
+	VoteNum          int64              `json:"vote_num"` // 赞成票数，与 up_votes 相同，保留给旧版客户端
+	CommentNum       int64              `json:"comment_num"`
+	PostVoteStats                       // 嵌入投票统计
 	*Post                               // 嵌入帖子结构体
 	*CommunityDetail `json:"community"` // 嵌入社区结构体
 }
 
+// ApiPostPage 游标分页的帖子列表
+type ApiPostPage struct {
+	List       []*ApiPostDetail `json:"list"`
+	NextCursor string           `json:"next_cursor"` // 下一页的游标，没有更多数据时为空
+	HasMore    bool             `json:"has_more"`
+}
+
+// ApiSearchResult 帖子搜索结果，按相关度排序
+type ApiSearchResult struct {
+	Total int64            `json:"total"`
+	List  []*ApiPostDetail `json:"list"`
+}
+
+// PostVoteStats 帖子的投票统计
+type PostVoteStats struct {
+	UpVotes   int64 `json:"up_votes"`   // 赞成票数
+	DownVotes int64 `json:"down_votes"` // 反对票数
+	Score     int64 `json:"score"`      // 净得票：赞成票数 - 反对票数
+	MyVote    int8  `json:"my_vote"`    // 当前用户的投票：1 赞成、-1 反对、0 未投票
+}
+
+// PostRevision 帖子修订记录，保存的是编辑前的标题和内容
+type PostRevision struct {
+	PostID     int64     `json:"post_id,string" db:"post_id"`
+	Revision   int32     `json:"revision" db:"revision"`
+	EditorID   int64     `json:"editor_id,string" db:"editor_id"`
+	Title      string    `json:"title" db:"title"`
+	Content    string    `json:"content" db:"content"`
+	CreateTime time.Time `json:"create_time" db:"create_time"`
+}
+
 // PostScore 帖子分数
 type PostScore struct {
 	ID    int64 `json:"id,string" db:"post_id"`
 	Score int64 `json:"score" db:"score"`
 }
 
+// ArchivedPostScore 已归档帖子的最终分数和票数
+type ArchivedPostScore struct {
+	ID        int64   `json:"id,string" db:"post_id"`
+	Score     float64 `json:"score" db:"score"`
+	UpVotes   int64   `json:"up_votes" db:"up_votes"`
+	DownVotes int64   `json:"down_votes" db:"down_votes"`
+}
+
+// PostVoteResult 投票后帖子最新的票数
+type PostVoteResult struct {
+	PostID    string `json:"post_id"`
+	UpVotes   int64  `json:"up_votes"`
+	DownVotes int64  `json:"down_votes"`
+}
+
 // PostVoteData 帖子投票数据
 type PostVoteData struct {
 	PostID    int64 `json:"post_id,string" db:"post_id"`
@@ -0,0 +1,13 @@
+package models
+
+// TrendingTag 热门标签
+type TrendingTag struct {
+	Tag     string `json:"tag"`
+	PostNum int64  `json:"post_num"` // 统计窗口内使用该标签的新帖子数
+}
+
+// PostTag 帖子的一个标签
+type PostTag struct {
+	PostID int64  `db:"post_id"`
+	Tag    string `db:"tag"`
+}
@@ -1,11 +1,58 @@
 package models
 
+import "time"
+
 type User struct {
 	UserID       int64  `json:"user_id,string" db:"user_id"` // 指定json序列化/反序列化时使用小写user_id
 	Username     string `json:"username" db:"username"`
 	Password     string `json:"password" db:"password"`
-	Email        string `json:"email" db:"email"`   // 邮箱
-	Gender       int    `json:"gender" db:"gender"` // 性别
+	Email        string `json:"email" db:"email"`           // 邮箱
+	Gender       int    `json:"gender" db:"gender"`         // 性别
+	Role         string `json:"role" db:"role"`             // 全局角色
+	AvatarURL    string `json:"avatar_url" db:"avatar_url"` // 头像地址
 	AccessToken  string
 	RefreshToken string
 }
+
+// 性别
+const (
+	GenderUnknown = 0
+	GenderMale    = 1
+	GenderFemale  = 2
+)
+
+// UserProfile 用户的公开资料，邮箱只在查询自己的资料时返回
+type UserProfile struct {
+	UserID        int64     `json:"user_id,string" db:"user_id"`
+	Username      string    `json:"username" db:"username"`
+	Email         string    `json:"email,omitempty" db:"email"`
+	EmailVerified bool      `json:"email_verified,omitempty" db:"email_verified"`
+	Gender        int       `json:"gender" db:"gender"`
+	Bio           string    `json:"bio" db:"bio"`
+	AvatarURL     string    `json:"avatar_url" db:"avatar_url"`
+	Karma         int64     `json:"karma"`    // 收到的赞成票减反对票
+	PostNum       int64     `json:"post_num"` // 发布的帖子数
+	CreateTime    time.Time `json:"create_time" db:"create_time"`
+}
+
+// ApiUserProfile 用户主页：资料和发布的帖子
+type ApiUserProfile struct {
+	*UserProfile
+	Posts []*ApiPostDetail `json:"posts"`
+}
+
+// UserKarma 用户在一个社区中收到的赞成票减反对票
+type UserKarma struct {
+	UserID      int64 `db:"user_id"`
+	CommunityID int64 `db:"community_id"`
+	Karma       int64 `db:"karma"`
+}
+
+// KarmaRank karma 排行榜中的一名用户
+type KarmaRank struct {
+	Rank      int64  `json:"rank"`
+	UserID    int64  `json:"user_id,string"`
+	Username  string `json:"username"`
+	AvatarURL string `json:"avatar_url"`
+	Karma     int64  `json:"karma"`
+}
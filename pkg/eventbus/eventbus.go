@@ -0,0 +1,102 @@
+package eventbus
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// 进程内的事件总线
+// 生产者只负责发布事件，不关心有哪些订阅者；新增一类消费者只需要订阅对应的主题。
+// 事件放入有界队列后由固定数量的 worker 异步处理，发布不会阻塞请求，队列满时丢弃事件并记录日志。
+
+// Event 事件，Topic 为事件的主题
+type Event interface {
+	Topic() string
+}
+
+// Handler 事件处理函数
+type Handler func(Event)
+
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	queue    chan Event
+	wg       sync.WaitGroup
+
+	closeMu sync.RWMutex // 保护 closed，Close 与 Publish 互斥，避免向已关闭的队列发送事件
+	closed  bool
+}
+
+// New 创建事件总线，buffer 为队列长度
+func New(buffer int) *Bus {
+	return &Bus{
+		handlers: make(map[string][]Handler),
+		queue:    make(chan Event, buffer),
+	}
+}
+
+// Subscribe 订阅主题，需要在 Start 之前调用
+func (b *Bus) Subscribe(topic string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topic] = append(b.handlers[topic], h)
+}
+
+// Publish 发布事件，队列满或事件总线已关闭时丢弃并返回 false
+func (b *Bus) Publish(e Event) bool {
+	b.closeMu.RLock()
+	defer b.closeMu.RUnlock()
+	if b.closed {
+		zap.L().Warn("event bus is closed, drop event", zap.String("topic", e.Topic()))
+		return false
+	}
+	select {
+	case b.queue <- e:
+		return true
+	default:
+		zap.L().Warn("event bus queue is full, drop event", zap.String("topic", e.Topic()))
+		return false
+	}
+}
+
+// Start 启动 workers 个 worker 处理事件
+func (b *Bus) Start(workers int) {
+	for i := 0; i < workers; i++ {
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			for e := range b.queue {
+				b.dispatch(e)
+			}
+		}()
+	}
+}
+
+// Close 停止接收事件，等待队列中已有的事件处理完
+func (b *Bus) Close() {
+	b.closeMu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+	b.closeMu.Unlock()
+	b.wg.Wait()
+}
+
+// dispatch 依次调用订阅者，单个订阅者 panic 不影响其他订阅者
+func (b *Bus) dispatch(e Event) {
+	b.mu.RLock()
+	handlers := b.handlers[e.Topic()]
+	b.mu.RUnlock()
+	for _, h := range handlers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					zap.L().Error("event handler panic", zap.String("topic", e.Topic()), zap.Any("panic", r))
+				}
+			}()
+			h(e)
+		}()
+	}
+}
@@ -0,0 +1,69 @@
+package eventbus
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testEvent struct {
+	topic string
+	n     int
+}
+
+func (e testEvent) Topic() string { return e.topic }
+
+func TestPublishDispatchesToSubscribers(t *testing.T) {
+	b := New(16)
+	var mu sync.Mutex
+	got := map[string]int{}
+	b.Subscribe("a", func(e Event) {
+		mu.Lock()
+		got["a1"] += e.(testEvent).n
+		mu.Unlock()
+	})
+	b.Subscribe("a", func(e Event) {
+		mu.Lock()
+		got["a2"] += e.(testEvent).n
+		mu.Unlock()
+	})
+	b.Subscribe("b", func(e Event) { panic("boom") })
+	b.Start(2)
+
+	assert.True(t, b.Publish(testEvent{topic: "a", n: 1}))
+	assert.True(t, b.Publish(testEvent{topic: "b"}))
+	assert.True(t, b.Publish(testEvent{topic: "a", n: 2}))
+	assert.True(t, b.Publish(testEvent{topic: "none"}))
+	b.Close()
+
+	assert.Equal(t, map[string]int{"a1": 3, "a2": 3}, got)
+}
+
+func TestPublishDropsWhenFull(t *testing.T) {
+	b := New(1)
+	assert.True(t, b.Publish(testEvent{topic: "a"}))
+	assert.False(t, b.Publish(testEvent{topic: "a"}))
+	b.Start(1)
+	b.Close()
+}
+
+func TestPublishAfterClose(t *testing.T) {
+	b := New(16)
+	b.Start(1)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				b.Publish(testEvent{topic: "a"})
+			}
+		}()
+	}
+	b.Close()
+	wg.Wait()
+	assert.False(t, b.Publish(testEvent{topic: "a"}))
+	// 重复关闭不会 panic
+	b.Close()
+}
@@ -1,6 +1,9 @@
 package jwt
 
 import (
+	"bluebell/pkg/rbac"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -9,44 +12,141 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var mySecret = []byte("浅吟轻唱一曲离歌")
+var ErrorInvalidToken = errors.New("invalid token")
 
-func keyFunc(_ *jwt.Token) (i interface{}, err error) {
-	return mySecret, nil
-}
+const (
+	issuer = "bluebell"
+
+	// Token 类型，防止 Access Token 被当作 Refresh Token 使用，反之亦然
+	typeAccess  = "access"
+	typeRefresh = "refresh"
+)
 
+// MyClaims Access Token 的声明
+// jti 用于注销时加入黑名单，fid 为所属的 token 家族（一次登录会话）
+// role 为全局角色，mods 为担任版主的社区ID，签发时从数据库读取，变更在下次刷新 Token 后生效
 type MyClaims struct {
+	UserID      int64   `json:"user_id"`
+	Username    string  `json:"username"`
+	Role        string  `json:"role"`
+	Communities []int64 `json:"mods,omitempty"`
+	FamilyID    string  `json:"fid"`
+	Type        string  `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// Principal 当前用户的权限主体
+func (c *MyClaims) Principal() *rbac.Principal {
+	role := c.Role
+	if role == "" {
+		// 升级前签发的 Token 没有 role 声明
+		role = rbac.RoleUser
+	}
+	return &rbac.Principal{UserID: c.UserID, Role: role, Communities: c.Communities}
+}
+
+// Subject Token 的持有者
+type Subject struct {
+	UserID      int64
+	Username    string
+	Role        string
+	Communities []int64 // 担任版主的社区
+}
+
+// RefreshClaims Refresh Token 的声明
+// 每次刷新都会签发新的 jti，同一次登录产生的 Refresh Token 共享同一个 fid
+type RefreshClaims struct {
 	UserID   int64  `json:"user_id"`
-	Username string `json:"username"`
-	jwt.MapClaims
+	FamilyID string `json:"fid"`
+	Type     string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// TokenPair 签发的一对 Token 及其元数据
+type TokenPair struct {
+	AccessToken   string
+	RefreshToken  string
+	AccessID      string // Access Token 的 jti
+	RefreshID     string // Refresh Token 的 jti
+	FamilyID      string
+	RefreshExpire time.Duration
+}
+
+// AccessExpire Access Token 的有效期
+func AccessExpire() time.Duration {
+	return time.Hour * time.Duration(viper.GetInt("auth.jwt_expire"))
+}
+
+// RefreshExpire Refresh Token 的有效期
+func RefreshExpire() time.Duration {
+	return AccessExpire() * 30
+}
+
+// NewTokenID 生成随机的 Token ID，用作 jti 和 fid
+func NewTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
 
-// GenToken 生成JWT
-func GenToken(userID int64, username string) (atoken, rtoken string, err error) {
+// GenToken 生成JWT，familyID 为空时开启一个新的 token 家族
+func GenToken(sub *Subject, familyID string) (pair *TokenPair, err error) {
+	pair = &TokenPair{
+		FamilyID:      familyID,
+		RefreshExpire: RefreshExpire(),
+	}
+	if pair.FamilyID == "" {
+		if pair.FamilyID, err = NewTokenID(); err != nil {
+			return nil, err
+		}
+	}
+	if pair.AccessID, err = NewTokenID(); err != nil {
+		return nil, err
+	}
+	if pair.RefreshID, err = NewTokenID(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
 	// 创建一个我们自己的声明
 	c := MyClaims{
-		userID,   // 自定义字段
-		username, // 自定义字段
-		jwt.MapClaims{
-			"exp": time.Now().Add(
-				time.Hour * time.Duration(viper.GetInt("auth.jwt_expire"))).Unix(), // 过期时间
-			"iat": time.Now().Unix(), // 发布时间
-			"iss": "bluebell",        // 签发人
+		UserID:      sub.UserID,   // 自定义字段
+		Username:    sub.Username, // 自定义字段
+		Role:        sub.Role,
+		Communities: sub.Communities,
+		FamilyID:    pair.FamilyID,
+		Type:        typeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        pair.AccessID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessExpire())), // 过期时间
+			IssuedAt:  jwt.NewNumericDate(now),                     // 发布时间
+			Issuer:    issuer,                                      // 签发人
 		},
 	}
 	// 加密并获取完整的编码后的字符串token
-	atoken, err = jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(mySecret)
-
-	// Refresh Token 不需要任何自定义字段
-	rtoken, err = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"exp": time.Now().Add(time.Hour * time.Duration(viper.GetInt("auth.jwt_expire")) * 30).Unix(), // 过期时间
-		"iat": time.Now().Unix(),                                                                      // 发布时间
-		"iss": "bluebell",                                                                             // 签发人
-	}).SignedString(mySecret)
-	return
+	if pair.AccessToken, err = sign(c); err != nil {
+		return nil, err
+	}
+
+	rc := RefreshClaims{
+		UserID:   sub.UserID,
+		FamilyID: pair.FamilyID,
+		Type:     typeRefresh,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        pair.RefreshID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(pair.RefreshExpire)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    issuer,
+		},
+	}
+	if pair.RefreshToken, err = sign(rc); err != nil {
+		return nil, err
+	}
+	return pair, nil
 }
 
-// ParseToken 解析JWT
+// ParseToken 解析 Access Token
 func ParseToken(tokenString string) (claims *MyClaims, err error) {
 	// 解析token
 	var token *jwt.Token
@@ -55,29 +155,22 @@ func ParseToken(tokenString string) (claims *MyClaims, err error) {
 	if err != nil {
 		return
 	}
-	if !token.Valid {
-		err = errors.New("invalid token")
+	if !token.Valid || claims.Type != typeAccess || claims.ID == "" || claims.FamilyID == "" {
+		err = ErrorInvalidToken
 	}
 	return
 }
 
-// RefreshToken 刷新Token
-func RefreshToken(atoken, rtoken string) (NewAToken, NewRToken string, err error) {
-	// refresh token 无效直接返回
-	if _, err = jwt.Parse(rtoken, keyFunc); err != nil {
-		return
-	}
-	// 从旧Access Token中解析出claims数据
-	var claims MyClaims
-	_, err = jwt.ParseWithClaims(atoken, &claims, keyFunc)
+// ParseRefreshToken 解析 Refresh Token，只校验签名和有效期，是否已被使用由调用方判断
+func ParseRefreshToken(tokenString string) (claims *RefreshClaims, err error) {
+	var token *jwt.Token
+	claims = new(RefreshClaims)
+	token, err = jwt.ParseWithClaims(tokenString, claims, keyFunc)
 	if err != nil {
-		// 检查是否为过期错误
-		if errors.Is(err, jwt.ErrTokenExpired) {
-			// 生成新的Access Token
-			NewAToken, _, err = GenToken(claims.UserID, claims.Username)
-			return
-		}
 		return
 	}
+	if !token.Valid || claims.Type != typeRefresh || claims.ID == "" || claims.FamilyID == "" {
+		err = ErrorInvalidToken
+	}
 	return
 }
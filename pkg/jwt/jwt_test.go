@@ -0,0 +1,62 @@
+package jwt
+
+import (
+	"bluebell/setting"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestMain(m *testing.M) {
+	err := Init(&setting.AuthConfig{
+		SigningKey: "test",
+		Keys:       []*setting.JWTKeyConfig{{KID: "test", Algorithm: AlgorithmHS256, Secret: testSecret}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestGenToken(t *testing.T) {
+	viper.Set("auth.jwt_expire", 1)
+
+	pair, err := GenToken(&Subject{UserID: 1, Username: "bluebell", Role: "user", Communities: []int64{2}}, "")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, pair.FamilyID)
+
+	mc, err := ParseToken(pair.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), mc.UserID)
+	assert.Equal(t, "user", mc.Role)
+	assert.Equal(t, []int64{2}, mc.Communities)
+	assert.Equal(t, pair.AccessID, mc.ID)
+	assert.Equal(t, pair.FamilyID, mc.FamilyID)
+
+	rc, err := ParseRefreshToken(pair.RefreshToken)
+	assert.NoError(t, err)
+	assert.Equal(t, pair.RefreshID, rc.ID)
+	assert.Equal(t, pair.FamilyID, rc.FamilyID)
+
+	// 刷新时沿用原来的家族
+	next, err := GenToken(&Subject{UserID: 1, Username: "bluebell", Role: "user"}, pair.FamilyID)
+	assert.NoError(t, err)
+	assert.Equal(t, pair.FamilyID, next.FamilyID)
+	assert.NotEqual(t, pair.RefreshID, next.RefreshID)
+}
+
+func TestTokenTypeMismatch(t *testing.T) {
+	viper.Set("auth.jwt_expire", 1)
+
+	pair, err := GenToken(&Subject{UserID: 1, Username: "bluebell", Role: "user"}, "")
+	assert.NoError(t, err)
+
+	_, err = ParseToken(pair.RefreshToken)
+	assert.Error(t, err)
+	_, err = ParseRefreshToken(pair.AccessToken)
+	assert.Error(t, err)
+}
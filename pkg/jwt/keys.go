@@ -0,0 +1,207 @@
+package jwt
+
+import (
+	"bluebell/setting"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 签名密钥管理
+// 配置中的 auth.signing_key 指定签名使用的密钥，auth.keys 中的所有密钥都可用于校验。
+// 轮换密钥时先把新密钥加入 keys，再切换 signing_key，等旧 Token 全部过期后删除旧密钥即可。
+// 签发的 Token 头部带有 kid，校验时据此选择密钥；非对称密钥的公钥通过 JWKS 对外公开。
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+var (
+	ErrorUnknownKey       = errors.New("unknown signing key")
+	ErrorUnknownAlgorithm = errors.New("unknown signing algorithm")
+)
+
+type signingKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	signKey   interface{} // 只用于校验的密钥为 nil
+	verifyKey interface{}
+}
+
+var ErrorNoSigningKey = errors.New("jwt signing key is not configured")
+
+var (
+	// 调用 Init 之前没有任何可用的密钥，签发和校验都会失败
+	signer     *signingKey
+	verifiers  = map[string]*signingKey{}
+	publicKeys []JWK
+)
+
+// Init 根据配置加载签名密钥和校验密钥，没有配置签名密钥时返回错误
+func Init(cfg *setting.AuthConfig) (err error) {
+	if cfg == nil || len(cfg.Keys) == 0 {
+		return ErrorNoSigningKey
+	}
+	newVerifiers := make(map[string]*signingKey, len(cfg.Keys))
+	newPublicKeys := make([]JWK, 0, len(cfg.Keys))
+	for _, kc := range cfg.Keys {
+		if kc.KID == "" {
+			return errors.New("jwt key must have a kid")
+		}
+		if _, ok := newVerifiers[kc.KID]; ok {
+			return fmt.Errorf("duplicate jwt key kid: %s", kc.KID)
+		}
+		k, err := loadKey(kc)
+		if err != nil {
+			return fmt.Errorf("load jwt key %s failed: %w", kc.KID, err)
+		}
+		newVerifiers[k.kid] = k
+		if jwk, ok := toJWK(k); ok {
+			newPublicKeys = append(newPublicKeys, jwk)
+		}
+	}
+	newSigner, ok := newVerifiers[cfg.SigningKey]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrorUnknownKey, cfg.SigningKey)
+	}
+	if newSigner.signKey == nil {
+		return fmt.Errorf("jwt key %s has no private key and cannot sign", cfg.SigningKey)
+	}
+	signer, verifiers, publicKeys = newSigner, newVerifiers, newPublicKeys
+	return nil
+}
+
+// loadKey 根据算法加载密钥，非对称算法只配置公钥时该密钥只用于校验
+func loadKey(kc *setting.JWTKeyConfig) (k *signingKey, err error) {
+	k = &signingKey{kid: kc.KID}
+	switch kc.Algorithm {
+	case AlgorithmHS256:
+		secret, err := setting.ResolveSecret("HS256 secret", kc.Secret, kc.SecretEnv, kc.SecretFile)
+		if err != nil {
+			return nil, err
+		}
+		k.method = jwt.SigningMethodHS256
+		k.signKey = []byte(secret)
+		k.verifyKey = k.signKey
+	case AlgorithmRS256:
+		k.method = jwt.SigningMethodRS256
+		if kc.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			priv, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			k.signKey, k.verifyKey = priv, &priv.PublicKey
+		} else {
+			pem, err := os.ReadFile(kc.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			if k.verifyKey, err = jwt.ParseRSAPublicKeyFromPEM(pem); err != nil {
+				return nil, err
+			}
+		}
+	case AlgorithmEdDSA:
+		k.method = jwt.SigningMethodEdDSA
+		if kc.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			priv, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			k.signKey, k.verifyKey = priv, priv.(crypto.Signer).Public()
+		} else {
+			pem, err := os.ReadFile(kc.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			if k.verifyKey, err = jwt.ParseEdPublicKeyFromPEM(pem); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrorUnknownAlgorithm, kc.Algorithm)
+	}
+	return k, nil
+}
+
+// keyFunc 根据 Token 头部的 kid 选择校验密钥，并要求签名算法与密钥一致，防止算法混淆攻击
+func keyFunc(token *jwt.Token) (i interface{}, err error) {
+	kid, _ := token.Header["kid"].(string)
+	k, ok := verifiers[kid]
+	if !ok {
+		return nil, ErrorUnknownKey
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, ErrorUnknownAlgorithm
+	}
+	return k.verifyKey, nil
+}
+
+// sign 使用当前的签名密钥签发 Token
+func sign(claims jwt.Claims) (string, error) {
+	if signer == nil {
+		return "", ErrorNoSigningKey
+	}
+	token := jwt.NewWithClaims(signer.method, claims)
+	if signer.kid != "" {
+		token.Header["kid"] = signer.kid
+	}
+	return token.SignedString(signer.signKey)
+}
+
+// JWK RFC 7517 中的公钥格式
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKeys 返回所有非对称密钥的公钥，HS256 的密钥永远不会公开
+func PublicKeys() *JWKS {
+	return &JWKS{Keys: publicKeys}
+}
+
+func toJWK(k *signingKey) (jwk JWK, ok bool) {
+	jwk = JWK{Kid: k.kid, Use: "sig", Alg: k.method.Alg()}
+	switch pub := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return jwk, false
+	}
+	return jwk, true
+}
@@ -0,0 +1,90 @@
+package jwt
+
+import (
+	"bluebell/setting"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// writePEM 把私钥写入临时目录，返回文件路径
+func writePEM(t *testing.T, name string, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return path
+}
+
+func TestKeyRotation(t *testing.T) {
+	viper.Set("auth.jwt_expire", 1)
+	oldSigner, oldVerifiers, oldPublicKeys := signer, verifiers, publicKeys
+	t.Cleanup(func() {
+		signer, verifiers, publicKeys = oldSigner, oldVerifiers, oldPublicKeys
+	})
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	keys := []*setting.JWTKeyConfig{
+		{KID: "rs", Algorithm: AlgorithmRS256, PrivateKeyFile: writePEM(t, "rs.pem", rsaKey)},
+		{KID: "ed", Algorithm: AlgorithmEdDSA, PrivateKeyFile: writePEM(t, "ed.pem", edKey)},
+		{KID: "hs", Algorithm: AlgorithmHS256, Secret: testSecret},
+	}
+
+	// 1. 使用 RS256 签发
+	assert.NoError(t, Init(&setting.AuthConfig{SigningKey: "rs", Keys: keys}))
+	old, err := GenToken(&Subject{UserID: 1, Username: "bluebell", Role: "user"}, "")
+	assert.NoError(t, err)
+	assert.Len(t, PublicKeys().Keys, 2) // HS256 的密钥不公开
+
+	// 2. 轮换到 EdDSA，旧 Token 在轮换期内仍然有效
+	assert.NoError(t, Init(&setting.AuthConfig{SigningKey: "ed", Keys: keys}))
+	_, err = ParseToken(old.AccessToken)
+	assert.NoError(t, err)
+	pair, err := GenToken(&Subject{UserID: 1, Username: "bluebell", Role: "user"}, "")
+	assert.NoError(t, err)
+	_, err = ParseToken(pair.AccessToken)
+	assert.NoError(t, err)
+
+	// 3. 删除旧密钥后，旧 Token 失效
+	assert.NoError(t, Init(&setting.AuthConfig{SigningKey: "ed", Keys: keys[1:]}))
+	_, err = ParseToken(old.AccessToken)
+	assert.Error(t, err)
+
+	// 签名密钥必须存在
+	assert.Error(t, Init(&setting.AuthConfig{SigningKey: "rs", Keys: keys[1:]}))
+}
+
+func TestInitRejectsWeakSecret(t *testing.T) {
+	oldSigner, oldVerifiers, oldPublicKeys := signer, verifiers, publicKeys
+	t.Cleanup(func() {
+		signer, verifiers, publicKeys = oldSigner, oldVerifiers, oldPublicKeys
+	})
+
+	assert.ErrorIs(t, Init(nil), ErrorNoSigningKey)
+	for _, secret := range []string{"", "xxxx", "short"} {
+		err := Init(&setting.AuthConfig{
+			SigningKey: "hs",
+			Keys:       []*setting.JWTKeyConfig{{KID: "hs", Algorithm: AlgorithmHS256, Secret: secret}},
+		})
+		assert.Error(t, err, secret)
+	}
+
+	t.Setenv("BLUEBELL_TEST_JWT_SECRET", testSecret+"-env")
+	assert.NoError(t, Init(&setting.AuthConfig{
+		SigningKey: "hs",
+		Keys: []*setting.JWTKeyConfig{
+			{KID: "hs", Algorithm: AlgorithmHS256, Secret: "xxxx", SecretEnv: "BLUEBELL_TEST_JWT_SECRET"},
+		},
+	}))
+}
@@ -0,0 +1,57 @@
+package mailer
+
+import (
+	"bluebell/setting"
+	"errors"
+	"fmt"
+)
+
+// 邮件发送
+// 生产环境使用 SMTP 发送；本地开发和测试使用 log 驱动，把邮件写入文件或日志，不真正发送
+
+const (
+	DriverSMTP = "smtp"
+	DriverLog  = "log"
+)
+
+var ErrorUnknownDriver = errors.New("未知的邮件驱动")
+
+// Message 一封邮件
+type Message struct {
+	To      string
+	Subject string
+	Body    string // 纯文本
+}
+
+// Mailer 邮件发送器
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+var defaultMailer Mailer = &LogMailer{}
+
+// Init 根据配置初始化默认的邮件发送器
+func Init(cfg *setting.MailConfig) error {
+	if cfg == nil {
+		cfg = new(setting.MailConfig)
+	}
+	switch cfg.Driver {
+	case "", DriverLog:
+		defaultMailer = &LogMailer{Path: cfg.LogFile}
+	case DriverSMTP:
+		defaultMailer = NewSMTPMailer(cfg)
+	default:
+		return fmt.Errorf("%w: %s", ErrorUnknownDriver, cfg.Driver)
+	}
+	return nil
+}
+
+// SetDefault 替换默认的邮件发送器，用于测试
+func SetDefault(m Mailer) {
+	defaultMailer = m
+}
+
+// Send 使用默认的邮件发送器发送邮件
+func Send(msg *Message) error {
+	return defaultMailer.Send(msg)
+}
@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"bluebell/setting"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogMailerWritesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	assert.NoError(t, Init(&setting.MailConfig{Driver: DriverLog, LogFile: path}))
+
+	assert.NoError(t, Send(&Message{To: "a@example.com", Subject: "验证邮箱", Body: "token: abc"}))
+	assert.NoError(t, Send(&Message{To: "b@example.com", Subject: "重置密码", Body: "token: def"}))
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "To: a@example.com")
+	assert.Contains(t, string(data), "token: def")
+}
+
+func TestInitUnknownDriver(t *testing.T) {
+	assert.ErrorIs(t, Init(&setting.MailConfig{Driver: "pigeon"}), ErrorUnknownDriver)
+}
+
+func TestBuildMessage(t *testing.T) {
+	raw := string(buildMessage("bluebell <no-reply@example.com>", &Message{
+		To:      "a@example.com",
+		Subject: "验证邮箱",
+		Body:    "第一行\n第二行",
+	}))
+	assert.Contains(t, raw, "Subject: =?UTF-8?b?")
+	assert.Contains(t, raw, "\r\n\r\n第一行\r\n第二行")
+	assert.False(t, strings.Contains(raw, "Subject: 验证邮箱"))
+}
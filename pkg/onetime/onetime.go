@@ -0,0 +1,70 @@
+package onetime
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// 一次性令牌，用于邮件中的验证邮箱、重置密码链接
+// 格式为 base64url(用途|用户ID|nonce).base64url(HMAC-SHA256)，签名防止伪造和跨用途使用；
+// 令牌是否已使用、是否过期由调用方在 Redis 中以 nonce 为 key 记录
+
+var ErrorInvalidToken = errors.New("无效的令牌")
+
+// Claims 令牌的内容
+type Claims struct {
+	Purpose string
+	UserID  int64
+	Nonce   string
+}
+
+// New 签发一个用途为 purpose 的令牌
+func New(secret []byte, purpose string, userID int64) (token string, c *Claims, err error) {
+	b := make([]byte, 16)
+	if _, err = rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	c = &Claims{Purpose: purpose, UserID: userID, Nonce: hex.EncodeToString(b)}
+	payload := c.Purpose + "|" + strconv.FormatInt(c.UserID, 10) + "|" + c.Nonce
+	enc := base64.RawURLEncoding
+	token = enc.EncodeToString([]byte(payload)) + "." + enc.EncodeToString(sign(secret, payload))
+	return token, c, nil
+}
+
+// Parse 校验令牌的签名和用途
+func Parse(secret []byte, purpose, token string) (*Claims, error) {
+	enc := base64.RawURLEncoding
+	p, s, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrorInvalidToken
+	}
+	payload, err := enc.DecodeString(p)
+	if err != nil {
+		return nil, ErrorInvalidToken
+	}
+	sig, err := enc.DecodeString(s)
+	if err != nil || !hmac.Equal(sig, sign(secret, string(payload))) {
+		return nil, ErrorInvalidToken
+	}
+	parts := strings.Split(string(payload), "|")
+	if len(parts) != 3 || parts[0] != purpose || parts[2] == "" {
+		return nil, ErrorInvalidToken
+	}
+	userID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrorInvalidToken
+	}
+	return &Claims{Purpose: parts[0], UserID: userID, Nonce: parts[2]}, nil
+}
+
+func sign(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
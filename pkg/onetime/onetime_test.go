@@ -0,0 +1,36 @@
+package onetime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAndParse(t *testing.T) {
+	secret := []byte("secret")
+	token, c, err := New(secret, "verify", 42)
+	assert.NoError(t, err)
+
+	got, err := Parse(secret, "verify", token)
+	assert.NoError(t, err)
+	assert.Equal(t, c, got)
+	assert.Equal(t, int64(42), got.UserID)
+}
+
+func TestParseRejects(t *testing.T) {
+	secret := []byte("secret")
+	token, _, err := New(secret, "verify", 42)
+	assert.NoError(t, err)
+
+	// 用途不同
+	_, err = Parse(secret, "reset", token)
+	assert.ErrorIs(t, err, ErrorInvalidToken)
+	// 密钥不同
+	_, err = Parse([]byte("other"), "verify", token)
+	assert.ErrorIs(t, err, ErrorInvalidToken)
+	// 篡改内容
+	_, err = Parse(secret, "verify", "x"+token)
+	assert.ErrorIs(t, err, ErrorInvalidToken)
+	_, err = Parse(secret, "verify", "no-dot")
+	assert.ErrorIs(t, err, ErrorInvalidToken)
+}
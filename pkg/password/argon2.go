@@ -0,0 +1,104 @@
+package password
+
+import (
+	"bluebell/setting"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// argon2id 默认参数，参考 RFC 9106 的推荐配置
+const (
+	defaultArgon2Memory      = 64 * 1024 // KiB
+	defaultArgon2Iterations  = 3
+	defaultArgon2Parallelism = 2
+	argon2SaltLen            = 16
+	argon2KeyLen             = 32
+)
+
+type argon2idHasher struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+func newArgon2idHasher(cfg *setting.PasswordConfig) *argon2idHasher {
+	h := &argon2idHasher{
+		memory:      defaultArgon2Memory,
+		iterations:  defaultArgon2Iterations,
+		parallelism: defaultArgon2Parallelism,
+	}
+	if cfg == nil {
+		return h
+	}
+	if cfg.Argon2Memory > 0 {
+		h.memory = cfg.Argon2Memory
+	}
+	if cfg.Argon2Iterations > 0 {
+		h.iterations = cfg.Argon2Iterations
+	}
+	if cfg.Argon2Parallelism > 0 {
+		h.parallelism = cfg.Argon2Parallelism
+	}
+	return h
+}
+
+// Hash 生成 PHC 格式的哈希：$argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<hash>
+func (h *argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.iterations, h.memory, h.parallelism, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.memory, h.iterations, h.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify 使用哈希中记录的参数重新计算并比较
+func (h *argon2idHasher) Verify(password, encoded string) (bool, error) {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	other := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// NeedsRehash 哈希参数与当前配置不一致时需要重新哈希
+func (h *argon2idHasher) NeedsRehash(encoded string) bool {
+	p, _, _, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return *p != *h
+}
+
+// decodeArgon2id 解析 PHC 格式的 argon2id 哈希
+func decodeArgon2id(encoded string) (p *argon2idHasher, salt, key []byte, err error) {
+	// "", "argon2id", "v=19", "m=65536,t=3,p=2", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, ErrorInvalidHash
+	}
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrorInvalidHash
+	}
+	p = new(argon2idHasher)
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return nil, nil, nil, ErrorInvalidHash
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, nil, nil, ErrorInvalidHash
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return nil, nil, nil, ErrorInvalidHash
+	}
+	return p, salt, key, nil
+}
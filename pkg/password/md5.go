@@ -0,0 +1,30 @@
+package password
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+)
+
+// 旧版密码哈希：md5(secret) 拼接在明文密码之后再做 hex 编码
+// 该方案不安全，只保留校验能力，用于已有账号登录时迁移到新算法
+
+const legacySecret = "WMGray"
+
+type legacyMD5Hasher struct{}
+
+func (legacyMD5Hasher) Hash(string) (string, error) {
+	return "", errors.New("旧版 MD5 哈希仅支持校验")
+}
+
+func (legacyMD5Hasher) Verify(password, encoded string) (bool, error) {
+	h := md5.New()
+	h.Write([]byte(legacySecret))
+	expected := hex.EncodeToString(h.Sum([]byte(password)))
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(encoded)) == 1, nil
+}
+
+func (legacyMD5Hasher) NeedsRehash(string) bool {
+	return true
+}
@@ -0,0 +1,105 @@
+package password
+
+import (
+	"bluebell/setting"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// 密码哈希
+// 新密码统一存成自描述的格式（PHC 字符串，如 $argon2id$v=19$m=65536,t=3,p=2$salt$hash；bcrypt 自带 $2a$ 前缀），
+// 校验时根据前缀选择对应的算法，因此可以随时切换默认算法而不影响已有账号。
+// 不以 $ 开头的是旧版 MD5 哈希，只用于校验，登录成功后会被重新哈希。
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	ErrorUnknownAlgorithm = errors.New("未知的密码哈希算法")
+	ErrorInvalidHash      = errors.New("无效的密码哈希")
+)
+
+// Hasher 密码哈希算法
+type Hasher interface {
+	// Hash 对明文密码进行哈希，返回自描述格式的字符串
+	Hash(password string) (string, error)
+	// Verify 校验明文密码与哈希是否匹配
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash 哈希参数与当前配置不一致时返回 true
+	NeedsRehash(encoded string) bool
+}
+
+var (
+	defaultHasher Hasher = newArgon2idHasher(nil)
+	legacy        Hasher = legacyMD5Hasher{}
+
+	dummyOnce sync.Once
+	dummyHash string
+)
+
+// Init 根据配置初始化默认的哈希算法
+func Init(cfg *setting.PasswordConfig) (err error) {
+	if cfg == nil {
+		cfg = new(setting.PasswordConfig)
+	}
+	switch cfg.Algorithm {
+	case "", AlgorithmArgon2id:
+		defaultHasher = newArgon2idHasher(cfg)
+	case AlgorithmBcrypt:
+		defaultHasher = newBcryptHasher(cfg)
+	default:
+		return fmt.Errorf("%w: %s", ErrorUnknownAlgorithm, cfg.Algorithm)
+	}
+	return
+}
+
+// Hash 使用默认算法对密码进行哈希
+func Hash(password string) (string, error) {
+	return defaultHasher.Hash(password)
+}
+
+// Verify 校验密码，ok 为 true 且 needRehash 为 true 时，调用方应使用 Hash 重新计算并保存
+func Verify(password, encoded string) (ok, needRehash bool, err error) {
+	h, err := identify(encoded)
+	if err != nil {
+		return false, false, err
+	}
+	if ok, err = h.Verify(password, encoded); err != nil || !ok {
+		return false, false, err
+	}
+	needRehash = h != defaultHasher || h.NeedsRehash(encoded)
+	return true, needRehash, nil
+}
+
+// VerifyDummy 用户不存在时也做一次同等代价的校验，使响应耗时与密码错误时一致，防止通过耗时枚举用户名
+func VerifyDummy(password string) {
+	dummyOnce.Do(func() {
+		dummyHash, _ = defaultHasher.Hash("bluebell")
+	})
+	_, _ = defaultHasher.Verify(password, dummyHash)
+}
+
+// identify 根据哈希的前缀找到对应的算法
+func identify(encoded string) (Hasher, error) {
+	if !strings.HasPrefix(encoded, "$") {
+		return legacy, nil
+	}
+	parts := strings.SplitN(encoded[1:], "$", 2)
+	switch parts[0] {
+	case "argon2id":
+		if h, ok := defaultHasher.(*argon2idHasher); ok {
+			return h, nil
+		}
+		return newArgon2idHasher(nil), nil
+	case "2a", "2b", "2y":
+		if h, ok := defaultHasher.(*bcryptHasher); ok {
+			return h, nil
+		}
+		return newBcryptHasher(nil), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrorUnknownAlgorithm, parts[0])
+}
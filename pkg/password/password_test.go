@@ -0,0 +1,60 @@
+package password
+
+import (
+	"bluebell/setting"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashAndVerify(t *testing.T) {
+	cfgs := []*setting.PasswordConfig{
+		{Algorithm: AlgorithmArgon2id, Argon2Memory: 1024, Argon2Iterations: 1, Argon2Parallelism: 1},
+		{Algorithm: AlgorithmBcrypt, BcryptCost: 4},
+	}
+	for _, cfg := range cfgs {
+		t.Run(cfg.Algorithm, func(t *testing.T) {
+			assert.NoError(t, Init(cfg))
+			encoded, err := Hash("123456")
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(encoded, "$"))
+
+			ok, needRehash, err := Verify("123456", encoded)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.False(t, needRehash)
+
+			ok, _, err = Verify("654321", encoded)
+			assert.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestVerifyLegacyMD5(t *testing.T) {
+	assert.NoError(t, Init(&setting.PasswordConfig{Algorithm: AlgorithmBcrypt, BcryptCost: 4}))
+	// 旧版 mysql.encryptPassword("123456") 的结果
+	encoded := "3132333435365cddcd0a4ccc4fd827333ce570fb765f"
+
+	ok, needRehash, err := Verify("123456", encoded)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, needRehash)
+
+	ok, _, err = Verify("1234567", encoded)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestNeedsRehashOnAlgorithmChange(t *testing.T) {
+	assert.NoError(t, Init(&setting.PasswordConfig{Algorithm: AlgorithmBcrypt, BcryptCost: 4}))
+	encoded, err := Hash("123456")
+	assert.NoError(t, err)
+
+	assert.NoError(t, Init(&setting.PasswordConfig{Algorithm: AlgorithmArgon2id, Argon2Memory: 1024, Argon2Iterations: 1, Argon2Parallelism: 1}))
+	ok, needRehash, err := Verify("123456", encoded)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, needRehash)
+}
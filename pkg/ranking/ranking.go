@@ -0,0 +1,114 @@
+package ranking
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// 帖子排序算法
+// 每种算法根据帖子的发布时间和赞成/反对票数计算一个分数，分数越大排得越靠前。
+// 每种算法的分数各自保存在一个 Redis ZSet 中，投票后重新计算，后台任务再定期重算最近的帖子。
+// 这里注册的算法就是帖子列表支持的全部排序方式（发帖时间除外），Redis 中的 ZSet 也由算法名称得出。
+
+// PostStats 计算分数需要的帖子数据
+type PostStats struct {
+	CreateTime int64 // 发帖时间，Unix 时间戳（秒）
+	Up         int64 // 赞成票数
+	Down       int64 // 反对票数
+}
+
+// Ranker 排序算法
+type Ranker interface {
+	// Score 计算帖子在 now 时刻的分数
+	Score(s *PostStats, now time.Time) float64
+}
+
+var rankers = make(map[string]Ranker)
+
+// Register 注册排序算法，name 即帖子列表接口的 order 参数
+func Register(name string, r Ranker) {
+	rankers[name] = r
+}
+
+// Get 根据名称获取排序算法
+func Get(name string) (Ranker, bool) {
+	r, ok := rankers[name]
+	return r, ok
+}
+
+// Names 所有已注册的排序算法名称
+func Names() []string {
+	names := make([]string, 0, len(rankers))
+	for name := range rankers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ScoreAll 使用所有已注册的算法计算帖子分数
+func ScoreAll(s *PostStats, now time.Time) map[string]float64 {
+	scores := make(map[string]float64, len(rankers))
+	for name, r := range rankers {
+		scores[name] = r.Score(s, now)
+	}
+	return scores
+}
+
+// Linear 简化版的阮一峰投票算法：每一票 432 分
+// 86400(s) / 200 = 432 --> 200张赞成票可以给你的帖子续一天
+type Linear struct {
+	ScorePerVote float64
+}
+
+func (l Linear) Score(s *PostStats, _ time.Time) float64 {
+	return float64(s.Up-s.Down) * l.ScorePerVote
+}
+
+// RedditHot Reddit 的 hot 算法
+// 得票数取对数，前 10 票与之后的 100 票权重相同；发帖时间每晚 12.5 小时需要多 10 倍的票数才能追平
+type RedditHot struct{}
+
+// redditEpoch Reddit 算法中的起始时间 2005-12-08 07:46:43 UTC
+const redditEpoch = 1134028003
+
+func (RedditHot) Score(s *PostStats, _ time.Time) float64 {
+	net := s.Up - s.Down
+	order := math.Log10(math.Max(math.Abs(float64(net)), 1))
+	var sign float64
+	switch {
+	case net > 0:
+		sign = 1
+	case net < 0:
+		sign = -1
+	}
+	seconds := float64(s.CreateTime - redditEpoch)
+	return math.Round((sign*order+seconds/45000)*1e7) / 1e7
+}
+
+// HNGravity Hacker News 的重力衰减算法：score = votes / (hours + 2) ^ gravity
+type HNGravity struct {
+	Gravity float64
+}
+
+func (g HNGravity) Score(s *PostStats, now time.Time) float64 {
+	hours := math.Max(float64(now.Unix()-s.CreateTime)/3600, 0)
+	return float64(s.Up-s.Down) / math.Pow(hours+2, g.Gravity)
+}
+
+// Wilson 威尔逊得分区间的下界，用于 best 排序
+// 在票数较少时对好评率做保守估计，避免一两票的帖子排在前面
+type Wilson struct {
+	Z float64 // 置信水平对应的 z 值，1.96 对应 95%
+}
+
+func (w Wilson) Score(s *PostStats, _ time.Time) float64 {
+	n := float64(s.Up + s.Down)
+	if n == 0 {
+		return 0
+	}
+	phat := float64(s.Up) / n
+	z2 := w.Z * w.Z
+	return (phat + z2/(2*n) - w.Z*math.Sqrt((phat*(1-phat)+z2/(4*n))/n)) / (1 + z2/n)
+}
@@ -0,0 +1,46 @@
+package ranking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinear(t *testing.T) {
+	r := Linear{ScorePerVote: 432}
+	assert.Equal(t, 432*3.0, r.Score(&PostStats{Up: 5, Down: 2}, time.Now()))
+	assert.Equal(t, 0.0, r.Score(&PostStats{}, time.Now()))
+}
+
+func TestRedditHot(t *testing.T) {
+	r := RedditHot{}
+	now := time.Now().Unix()
+	// 票数相同时，新帖排在前面
+	assert.Greater(t, r.Score(&PostStats{CreateTime: now, Up: 10}, time.Now()),
+		r.Score(&PostStats{CreateTime: now - 3600, Up: 10}, time.Now()))
+	// 发帖时间晚 12.5 小时，需要多 10 倍的票数才能追平
+	assert.InDelta(t, r.Score(&PostStats{CreateTime: now, Up: 10}, time.Now()),
+		r.Score(&PostStats{CreateTime: now - 45000, Up: 100}, time.Now()), 1e-6)
+	// 反对票多于赞成票时分数降低
+	assert.Less(t, r.Score(&PostStats{CreateTime: now, Down: 10}, time.Now()),
+		r.Score(&PostStats{CreateTime: now}, time.Now()))
+}
+
+func TestHNGravity(t *testing.T) {
+	r := HNGravity{Gravity: 1.8}
+	created := time.Now().Add(-time.Hour).Unix()
+	s := &PostStats{CreateTime: created, Up: 10}
+	// 分数随时间衰减
+	assert.Greater(t, r.Score(s, time.Now()), r.Score(s, time.Now().Add(24*time.Hour)))
+}
+
+func TestWilson(t *testing.T) {
+	r := Wilson{Z: 1.96}
+	assert.Equal(t, 0.0, r.Score(&PostStats{}, time.Now()))
+	// 好评率相同时，票数越多下界越高
+	assert.Greater(t, r.Score(&PostStats{Up: 100, Down: 10}, time.Now()),
+		r.Score(&PostStats{Up: 10, Down: 1}, time.Now()))
+	// 下界不会超过好评率
+	assert.Less(t, r.Score(&PostStats{Up: 1}, time.Now()), 1.0)
+}
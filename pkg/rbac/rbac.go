@@ -0,0 +1,76 @@
+package rbac
+
+import "slices"
+
+// 基于角色的权限控制
+// 全局角色决定用户在所有社区中拥有的权限；社区版主（包括社区创建者）只在自己管理的社区中拥有社区级的权限。
+// 用户的角色和管理的社区在登录/刷新 Token 时写入 JWT，需要权限的接口则每次从数据库读取，角色变更立即生效。
+
+// 全局角色
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Permission 权限
+type Permission string
+
+const (
+	PermPostModerate Permission = "post:moderate" // 删除、锁定、置顶帖子
+	PermUserBan      Permission = "user:ban"      // 在社区中封禁用户
+	PermAuditRead    Permission = "audit:read"    // 查看管理操作日志
+	PermLoginUnlock  Permission = "login:unlock"  // 解除登录锁定
+	PermRoleManage   Permission = "role:manage"   // 修改用户的全局角色
+)
+
+// communityPerms 社区版主在其管理的社区中拥有的权限
+var communityPerms = []Permission{PermPostModerate, PermUserBan, PermAuditRead}
+
+// rolePerms 全局角色拥有的权限，在所有社区中生效
+var rolePerms = map[string][]Permission{
+	RoleUser:      nil,
+	RoleModerator: communityPerms,
+	RoleAdmin:     {PermPostModerate, PermUserBan, PermAuditRead, PermLoginUnlock, PermRoleManage},
+}
+
+// ValidRole 是否为已定义的全局角色
+func ValidRole(role string) bool {
+	_, ok := rolePerms[role]
+	return ok
+}
+
+// Principal 当前操作的用户
+type Principal struct {
+	UserID      int64
+	Role        string
+	Communities []int64 // 担任版主（或创建者）的社区
+}
+
+// Can 用户是否拥有权限 perm：全局角色拥有该权限，或者是社区级权限且用户至少管理一个社区
+// 用于路由级的粗粒度检查，涉及具体社区时还需要用 CanIn 检查
+func (p *Principal) Can(perm Permission) bool {
+	if p == nil {
+		return false
+	}
+	if slices.Contains(rolePerms[p.Role], perm) {
+		return true
+	}
+	return len(p.Communities) > 0 && slices.Contains(communityPerms, perm)
+}
+
+// CanIn 用户是否拥有社区 communityID 中的权限 perm
+func (p *Principal) CanIn(perm Permission, communityID int64) bool {
+	if p == nil {
+		return false
+	}
+	if slices.Contains(rolePerms[p.Role], perm) {
+		return true
+	}
+	return slices.Contains(communityPerms, perm) && slices.Contains(p.Communities, communityID)
+}
+
+// Global 用户是否通过全局角色拥有权限 perm，不考虑社区版主身份
+func (p *Principal) Global(perm Permission) bool {
+	return p != nil && slices.Contains(rolePerms[p.Role], perm)
+}
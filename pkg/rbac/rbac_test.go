@@ -0,0 +1,34 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrincipal(t *testing.T) {
+	user := &Principal{UserID: 1, Role: RoleUser}
+	assert.False(t, user.Can(PermPostModerate))
+	assert.False(t, user.CanIn(PermPostModerate, 1))
+
+	// 社区版主只能管理自己的社区
+	mod := &Principal{UserID: 2, Role: RoleUser, Communities: []int64{1}}
+	assert.True(t, mod.Can(PermPostModerate))
+	assert.True(t, mod.CanIn(PermUserBan, 1))
+	assert.False(t, mod.CanIn(PermUserBan, 2))
+	assert.False(t, mod.Can(PermLoginUnlock))
+	assert.False(t, mod.Global(PermAuditRead))
+
+	// 全局版主可以管理所有社区，但不能修改角色
+	gmod := &Principal{UserID: 3, Role: RoleModerator}
+	assert.True(t, gmod.CanIn(PermPostModerate, 2))
+	assert.False(t, gmod.Can(PermRoleManage))
+
+	admin := &Principal{UserID: 4, Role: RoleAdmin}
+	assert.True(t, admin.Can(PermRoleManage))
+	assert.True(t, admin.CanIn(PermAuditRead, 3))
+
+	var nobody *Principal
+	assert.False(t, nobody.Can(PermPostModerate))
+	assert.False(t, ValidRole("root"))
+}
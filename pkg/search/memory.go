@@ -0,0 +1,164 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// MemoryIndex 进程内的倒排索引，数据不持久化，用于测试和开发环境
+// 分词方式与 MySQL 的 ngram 解析器一致：连续的中日韩文字切成两个字一组，其它文字按单词切分并转为小写。
+// 相关度使用 TF-IDF，标题中的词权重加倍。
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	docs     map[int64]*Document
+	postings map[string]map[int64]float64 // 词 -> 帖子ID -> 词频（已计入标题权重）
+}
+
+// titleWeight 标题中的词相对正文的权重
+const titleWeight = 2
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		docs:     make(map[int64]*Document),
+		postings: make(map[string]map[int64]float64),
+	}
+}
+
+func (m *MemoryIndex) Index(doc *Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(doc.ID)
+	d := *doc
+	m.docs[doc.ID] = &d
+	for _, t := range tokenize(doc.Title) {
+		m.addPosting(t, doc.ID, titleWeight)
+	}
+	for _, t := range tokenize(doc.Content) {
+		m.addPosting(t, doc.ID, 1)
+	}
+	return nil
+}
+
+func (m *MemoryIndex) Remove(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(id)
+	return nil
+}
+
+func (m *MemoryIndex) Search(q *Query) (*Result, error) {
+	terms := tokenize(q.Keyword)
+	if len(terms) == 0 {
+		return nil, ErrorEmptyKeyword
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// 命中任意一个词的帖子都会返回，按 TF-IDF 之和排序
+	scores := make(map[int64]float64)
+	total := float64(len(m.docs))
+	for _, t := range terms {
+		posting := m.postings[t]
+		if len(posting) == 0 {
+			continue
+		}
+		idf := math.Log(1 + total/float64(len(posting)))
+		for id, tf := range posting {
+			if !q.match(m.docs[id]) {
+				continue
+			}
+			scores[id] += tf * idf
+		}
+	}
+
+	ids := make([]int64, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] > ids[j] // 相关度相同时新帖在前
+	})
+	res := &Result{Total: int64(len(ids))}
+	start := min(q.Offset, int64(len(ids)))
+	end := int64(len(ids))
+	if q.Limit > 0 {
+		end = min(start+q.Limit, end)
+	}
+	res.IDs = ids[start:end]
+	return res, nil
+}
+
+func (m *MemoryIndex) addPosting(term string, id int64, weight float64) {
+	posting, ok := m.postings[term]
+	if !ok {
+		posting = make(map[int64]float64)
+		m.postings[term] = posting
+	}
+	posting[id] += weight
+}
+
+// remove 删除帖子的所有倒排记录，调用方需持有写锁
+func (m *MemoryIndex) remove(id int64) {
+	doc, ok := m.docs[id]
+	if !ok {
+		return
+	}
+	delete(m.docs, id)
+	for _, t := range append(tokenize(doc.Title), tokenize(doc.Content)...) {
+		if posting, ok := m.postings[t]; ok {
+			delete(posting, id)
+			if len(posting) == 0 {
+				delete(m.postings, t)
+			}
+		}
+	}
+}
+
+// tokenize 分词：中日韩文字按相邻两个字切分（单独一个字时保留该字），其它文字按字母数字切分为单词
+func tokenize(text string) []string {
+	tokens := make([]string, 0)
+	var word strings.Builder
+	var cjk []rune
+	flushWord := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, strings.ToLower(word.String()))
+			word.Reset()
+		}
+	}
+	flushCJK := func() {
+		if len(cjk) == 1 {
+			tokens = append(tokens, string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			tokens = append(tokens, string(cjk[i:i+2]))
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word.WriteRune(r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
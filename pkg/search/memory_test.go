@@ -0,0 +1,47 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"go", "语言", "言入", "入门"}, tokenize("Go语言入门"))
+	assert.Equal(t, []string{"hello", "world", "好"}, tokenize("Hello, World! 好"))
+}
+
+func TestMemoryIndex(t *testing.T) {
+	m := NewMemoryIndex()
+	now := time.Now()
+	_ = m.Index(&Document{ID: 1, CommunityID: 1, Title: "Go语言入门", Content: "学习 Go 语言", CreateTime: now.Add(-48 * time.Hour)})
+	_ = m.Index(&Document{ID: 2, CommunityID: 2, Title: "Redis 教程", Content: "在 Go 语言中使用 Redis", CreateTime: now})
+	_ = m.Index(&Document{ID: 3, CommunityID: 1, Title: "无关的帖子", Content: "今天天气不错", CreateTime: now})
+
+	// 标题命中的帖子排在前面
+	res, err := m.Search(&Query{Keyword: "go语言"})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, res.IDs)
+	assert.Equal(t, int64(2), res.Total)
+
+	// 社区和时间过滤
+	res, _ = m.Search(&Query{Keyword: "go语言", CommunityID: 2})
+	assert.Equal(t, []int64{2}, res.IDs)
+	res, _ = m.Search(&Query{Keyword: "go语言", Start: now.Add(-time.Hour)})
+	assert.Equal(t, []int64{2}, res.IDs)
+
+	// 分页
+	res, _ = m.Search(&Query{Keyword: "go", Offset: 1, Limit: 1})
+	assert.Len(t, res.IDs, 1)
+	assert.Equal(t, int64(2), res.Total)
+
+	// 更新和删除
+	_ = m.Index(&Document{ID: 1, CommunityID: 1, Title: "Python", Content: "", CreateTime: now})
+	_ = m.Remove(2)
+	res, _ = m.Search(&Query{Keyword: "go语言"})
+	assert.Empty(t, res.IDs)
+
+	_, err = m.Search(&Query{Keyword: " !"})
+	assert.ErrorIs(t, err, ErrorEmptyKeyword)
+}
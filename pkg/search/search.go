@@ -0,0 +1,61 @@
+package search
+
+import (
+	"errors"
+	"time"
+)
+
+// 帖子全文搜索
+// Index 是搜索索引的抽象，帖子发布、编辑、删除时由 logic 层同步更新索引；
+// 生产环境使用 MySQL FULLTEXT 索引（ngram 分词，见 dao/mysql），测试和开发环境可以使用进程内的倒排索引 MemoryIndex。
+
+var ErrorEmptyKeyword = errors.New("搜索关键词不能为空")
+
+// Document 被索引的帖子
+type Document struct {
+	ID          int64
+	CommunityID int64
+	Title       string
+	Content     string
+	CreateTime  time.Time
+}
+
+// Query 搜索条件
+type Query struct {
+	Keyword     string
+	CommunityID int64     // 为 0 时不限社区
+	Start       time.Time // 发帖时间下限，零值表示不限
+	End         time.Time // 发帖时间上限，零值表示不限
+	Offset      int64
+	Limit       int64
+}
+
+// Result 搜索结果，IDs 按相关度从高到低排列
+type Result struct {
+	IDs   []int64
+	Total int64
+}
+
+// Index 搜索索引
+type Index interface {
+	// Index 添加或更新帖子
+	Index(doc *Document) error
+	// Remove 删除帖子
+	Remove(id int64) error
+	// Search 搜索帖子
+	Search(q *Query) (*Result, error)
+}
+
+// match 帖子是否满足社区和时间范围的过滤条件
+func (q *Query) match(doc *Document) bool {
+	if q.CommunityID != 0 && doc.CommunityID != q.CommunityID {
+		return false
+	}
+	if !q.Start.IsZero() && doc.CreateTime.Before(q.Start) {
+		return false
+	}
+	if !q.End.IsZero() && doc.CreateTime.After(q.End) {
+		return false
+	}
+	return true
+}
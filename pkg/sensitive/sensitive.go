@@ -0,0 +1,207 @@
+package sensitive
+
+import (
+	"unicode"
+)
+
+// 敏感词匹配，基于 Aho-Corasick 自动机，一次扫描找出文本中的所有敏感词
+// 匹配前对文本和词库做相同的规范化：字母转小写、全角字符转半角；
+// 文本中夹杂的空白、标点和符号会被跳过（如 "傻.逼"、"f u c k"），
+// 只由英文字母和数字组成的词两端必须是单词边界，避免 "ass" 匹配 "class"。
+
+// Match 文本中的一处匹配，Start、End 为在原文 []rune 中的位置，[Start, End)
+type Match struct {
+	Start int
+	End   int
+	Index int // 匹配到的词在词库中的下标
+}
+
+type node struct {
+	next map[rune]int32
+	fail int32
+	word int32 // 以该节点结尾的词的下标，-1 表示没有
+	dict int32 // 沿 fail 链最近的有词的节点，-1 表示没有
+	size int32 // 以该节点结尾的词的长度（规范化后的字符数）
+}
+
+// Matcher 构建后只读，可以并发使用
+type Matcher struct {
+	nodes []node
+	words []string
+	// bounded 对应的词两端需要单词边界
+	bounded []bool
+}
+
+// New 用词库构建匹配器，规范化后为空的词被忽略，重复的词取第一次出现的下标
+func New(words []string) *Matcher {
+	m := &Matcher{
+		nodes:   []node{newNode()},
+		words:   words,
+		bounded: make([]bool, len(words)),
+	}
+	for i, w := range words {
+		m.insert(i, w)
+	}
+	m.build()
+	return m
+}
+
+func newNode() node {
+	return node{next: make(map[rune]int32), word: -1, dict: -1}
+}
+
+// insert 把一个词加入字典树
+func (m *Matcher) insert(index int, word string) {
+	cur := int32(0)
+	var size int32
+	for _, r := range word {
+		if skip(r) {
+			continue
+		}
+		r = fold(r)
+		nxt, ok := m.nodes[cur].next[r]
+		if !ok {
+			nxt = int32(len(m.nodes))
+			m.nodes = append(m.nodes, newNode())
+			m.nodes[cur].next[r] = nxt
+		}
+		cur = nxt
+		size++
+	}
+	if cur == 0 || m.nodes[cur].word >= 0 {
+		return
+	}
+	m.nodes[cur].word = int32(index)
+	m.nodes[cur].size = size
+	m.bounded[index] = isWordBounded(word)
+}
+
+// build 按层次遍历计算 fail 指针和输出链
+func (m *Matcher) build() {
+	queue := make([]int32, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].next {
+			f := m.nodes[cur].fail
+			for {
+				if nxt, ok := m.nodes[f].next[r]; ok && nxt != child {
+					m.nodes[child].fail = nxt
+					break
+				}
+				if f == 0 {
+					break
+				}
+				f = m.nodes[f].fail
+			}
+			fail := m.nodes[child].fail
+			if m.nodes[fail].word >= 0 {
+				m.nodes[child].dict = fail
+			} else {
+				m.nodes[child].dict = m.nodes[fail].dict
+			}
+			queue = append(queue, child)
+		}
+	}
+}
+
+// Word 返回词库中下标为 index 的词
+func (m *Matcher) Word(index int) string {
+	return m.words[index]
+}
+
+// FindAll 找出文本中的所有匹配（可能重叠），按结束位置排序
+func (m *Matcher) FindAll(text string) []Match {
+	runes := []rune(text)
+	var matches []Match
+	// pos 为参与匹配的字符在原文中的位置
+	pos := make([]int, 0, len(runes))
+	cur := int32(0)
+	for i, r := range runes {
+		if skip(r) {
+			continue
+		}
+		pos = append(pos, i)
+		r = fold(r)
+		for {
+			if nxt, ok := m.nodes[cur].next[r]; ok {
+				cur = nxt
+				break
+			}
+			if cur == 0 {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		for n := cur; n > 0; n = m.nodes[n].dict {
+			nd := &m.nodes[n]
+			if nd.word < 0 {
+				continue
+			}
+			start := pos[len(pos)-int(nd.size)]
+			if m.bounded[nd.word] && !isBoundary(runes, start, i+1) {
+				continue
+			}
+			matches = append(matches, Match{Start: start, End: i + 1, Index: int(nd.word)})
+		}
+	}
+	return matches
+}
+
+// Mask 把 matches 覆盖的字符替换成 mask，matches 必须是在同一个 text 上找到的
+func Mask(text string, matches []Match, mask rune) string {
+	if len(matches) == 0 {
+		return text
+	}
+	runes := []rune(text)
+	for _, mt := range matches {
+		for i := mt.Start; i < mt.End && i < len(runes); i++ {
+			if !skip(runes[i]) {
+				runes[i] = mask
+			}
+		}
+	}
+	return string(runes)
+}
+
+// fold 规范化字符：全角转半角，字母转小写
+func fold(r rune) rune {
+	if r >= '！' && r <= '～' {
+		r -= 0xFEE0
+	}
+	return unicode.ToLower(r)
+}
+
+// skip 匹配时跳过的字符：空白、标点和符号
+func skip(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+func isASCIIAlnum(r rune) bool {
+	r = fold(r)
+	return r >= 'a' && r <= 'z' || r >= '0' && r <= '9'
+}
+
+// isWordBounded 只由英文字母和数字（以及被跳过的字符）组成的词需要单词边界
+func isWordBounded(word string) bool {
+	for _, r := range word {
+		if !skip(r) && !isASCIIAlnum(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isBoundary 匹配 [start, end) 的前后是否不是英文字母或数字
+func isBoundary(runes []rune, start, end int) bool {
+	if start > 0 && isASCIIAlnum(runes[start-1]) {
+		return false
+	}
+	if end < len(runes) && isASCIIAlnum(runes[end]) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package sensitive
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func words(m *Matcher, matches []Match) []string {
+	res := make([]string, 0, len(matches))
+	for _, mt := range matches {
+		res = append(res, m.Word(mt.Index))
+	}
+	return res
+}
+
+func TestFindAll(t *testing.T) {
+	m := New([]string{"he", "she", "his", "hers", "", "..."})
+	matches := m.FindAll("ushers")
+	// 英文词需要单词边界，"ushers" 中的 she、he、hers 都不算
+	assert.Empty(t, matches)
+
+	m = New([]string{"发票", "代开发票", "开发"})
+	matches = m.FindAll("专业代开发票")
+	assert.Equal(t, []string{"开发", "代开发票", "发票"}, words(m, matches))
+	assert.Equal(t, Match{Start: 2, End: 6, Index: 1}, matches[1])
+}
+
+func TestFindAllNormalize(t *testing.T) {
+	m := New([]string{"Spam", "傻逼"})
+	assert.Equal(t, []string{"Spam"}, words(m, m.FindAll("buy ＳＰＡＭ now")))
+	assert.Equal(t, []string{"Spam"}, words(m, m.FindAll("s.p-a m!")))
+	assert.Equal(t, []string{"傻逼"}, words(m, m.FindAll("你是傻 ·逼吗")))
+	assert.Empty(t, m.FindAll("spammer"))
+	assert.Empty(t, m.FindAll("antispam"))
+	assert.Equal(t, []string{"Spam"}, words(m, m.FindAll("中文spam中文")))
+}
+
+func TestMask(t *testing.T) {
+	m := New([]string{"傻逼", "fuck"})
+	text := "你这个傻.逼, FUCK!"
+	assert.Equal(t, "你这个*.*, ****!", Mask(text, m.FindAll(text), '*'))
+	assert.Equal(t, "hello", Mask("hello", nil, '*'))
+}
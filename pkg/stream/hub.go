@@ -0,0 +1,104 @@
+package stream
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// 实时推送的本地分发
+// 每个实例只向 Redis 订阅一次，收到的消息由 Hub 按频道分发给本实例上的连接。
+// 连接消费太慢、缓冲区满时直接丢弃消息，不阻塞其他连接；投票数之类的消息下一条会覆盖上一条，丢弃是可以接受的。
+
+// Message 推送给客户端的消息，Channel 为频道，如 community:1、post:2
+type Message struct {
+	Channel string          `json:"channel"`
+	Type    string          `json:"type"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Subscriber 一个连接的订阅
+type Subscriber struct {
+	channels []string
+	ch       chan Message
+	closed   bool
+}
+
+// Messages 接收订阅频道的消息，取消订阅后关闭
+func (s *Subscriber) Messages() <-chan Message {
+	return s.ch
+}
+
+type Hub struct {
+	mu     sync.RWMutex
+	subs   map[string]map[*Subscriber]struct{}
+	buffer int
+}
+
+// NewHub 创建 Hub，buffer 为每个连接的缓冲区长度
+func NewHub(buffer int) *Hub {
+	return &Hub{
+		subs:   make(map[string]map[*Subscriber]struct{}),
+		buffer: buffer,
+	}
+}
+
+// Subscribe 订阅频道，重复的频道只订阅一次
+func (h *Hub) Subscribe(channels ...string) *Subscriber {
+	s := &Subscriber{ch: make(chan Message, h.buffer)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, channel := range channels {
+		subs, ok := h.subs[channel]
+		if !ok {
+			subs = make(map[*Subscriber]struct{})
+			h.subs[channel] = subs
+		}
+		if _, ok := subs[s]; ok {
+			continue
+		}
+		subs[s] = struct{}{}
+		s.channels = append(s.channels, channel)
+	}
+	return s
+}
+
+// Unsubscribe 取消订阅并关闭消息通道，可以重复调用
+func (h *Hub) Unsubscribe(s *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s.closed {
+		return
+	}
+	for _, channel := range s.channels {
+		subs := h.subs[channel]
+		delete(subs, s)
+		if len(subs) == 0 {
+			delete(h.subs, channel)
+		}
+	}
+	s.closed = true
+	close(s.ch)
+}
+
+// Dispatch 把消息分发给订阅了该频道的连接，返回成功投递的连接数
+func (h *Hub) Dispatch(m Message) int {
+	// 持有读锁发送，Unsubscribe 需要写锁，不会向已关闭的通道发送
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	n := 0
+	for s := range h.subs[m.Channel] {
+		select {
+		case s.ch <- m:
+			n++
+		default:
+		}
+	}
+	return n
+}
+
+// Channels 当前有订阅者的频道数
+func (h *Hub) Channels() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
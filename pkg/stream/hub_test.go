@@ -0,0 +1,34 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHubDispatch(t *testing.T) {
+	h := NewHub(1)
+	a := h.Subscribe("community:1", "post:1", "post:1")
+	b := h.Subscribe("post:1")
+	assert.Equal(t, 2, h.Channels())
+
+	assert.Equal(t, 2, h.Dispatch(Message{Channel: "post:1", Type: "post.voted"}))
+	assert.Equal(t, 0, h.Dispatch(Message{Channel: "post:2"}))
+	// a 的缓冲区已满，丢弃
+	assert.Equal(t, 0, h.Dispatch(Message{Channel: "community:1"}))
+
+	m := <-a.Messages()
+	assert.Equal(t, "post.voted", m.Type)
+	m = <-b.Messages()
+	assert.Equal(t, "post:1", m.Channel)
+
+	h.Unsubscribe(a)
+	h.Unsubscribe(a)
+	_, ok := <-a.Messages()
+	assert.False(t, ok)
+	assert.Equal(t, 1, h.Channels())
+	assert.Equal(t, 1, h.Dispatch(Message{Channel: "post:1"}))
+
+	h.Unsubscribe(b)
+	assert.Equal(t, 0, h.Channels())
+}
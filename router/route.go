@@ -4,6 +4,8 @@ import (
 	"bluebell/controller"
 	"bluebell/logger"
 	"bluebell/middlewares"
+	"bluebell/pkg/rbac"
+	"bluebell/setting"
 	"net/http"
 
 	"github.com/gin-contrib/pprof"
@@ -13,21 +15,33 @@ import (
 	_ "bluebell/docs" // 千万不要忘了导入把你上面生成的docs
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 // gin-swagger middleware
 // swagger embed files
 
+// newEngine 创建 gin 引擎，只信任配置中的反向代理
+// 否则任何人都能伪造 X-Forwarded-For 改变 ClientIP，绕过按IP的限流和登录失败计数
+func newEngine(trustedProxies []string) *gin.Engine {
+	r := gin.New()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		// 配置有误时退回到不信任任何代理
+		zap.L().Error("SetTrustedProxies failed, trust no proxy", zap.Error(err))
+		_ = r.SetTrustedProxies(nil)
+	}
+	return r
+}
+
 // SetupRouter 路由
 func SetupRouter(mode string) *gin.Engine {
 	if mode == gin.ReleaseMode {
 		gin.SetMode(gin.ReleaseMode) // gin设置成发布模式
 	}
-	r := gin.New()
+	r := newEngine(setting.Conf.TrustedProxies)
 
-	//r.Use(logger.GinLogger(), logger.GinRecovery(true), middlewares.RateLimitMiddleware(2*time.Second, 1))
 	// 令牌桶中间件
-	r.Use(logger.GinLogger(), logger.GinRecovery(true), middlewares.RateLimitMiddleware(20, 10000))
+	r.Use(logger.GinLogger(), logger.GinRecovery(true), middlewares.RateLimitMiddleware(setting.Conf.RateLimitConfig))
 
 	// 加载静态文件
 	r.LoadHTMLFiles("./templates/index.html")
@@ -42,8 +56,19 @@ func SetupRouter(mode string) *gin.Engine {
 
 	// 登录业务路由 --> controller.LoginHandler
 	v1.POST("./login", controller.LoginHandler)
+
+	// 刷新Token --> controller.RefreshTokenHandler
+	v1.POST("/auth/refresh", controller.RefreshTokenHandler)
+	// 邮箱验证和找回密码，使用邮件中的一次性令牌
+	v1.POST("/auth/email/verify", controller.VerifyEmailHandler)
+	v1.POST("/auth/password/forgot", controller.ForgotPasswordHandler)
+	v1.POST("/auth/password/reset", controller.ResetPasswordHandler)
 	//v1.GET("/posts", controller.GetPostListHandler)
 
+	// 实时推送，SSE 优先，也支持 WebSocket；与其他接口使用同一个 Access Token，允许放在 access_token 查询参数中
+	v1.GET("/stream", middlewares.JWTStreamAuthMiddleware(), controller.StreamHandler)
+	v1.GET("/stream/ws", middlewares.JWTStreamAuthMiddleware(), controller.StreamWSHandler)
+
 	// 使用中间件
 	// JWT 认证中间件
 	v1.Use(middlewares.JWTAuthMiddleware())
@@ -51,18 +76,83 @@ func SetupRouter(mode string) *gin.Engine {
 		// 发帖业务路由 --> controller.CreatePostHandler
 		v1.GET("/community", controller.CommunityHandler)
 		v1.GET("/community/:id", controller.CommunityDetailHandler)
+		// 社区管理
+		v1.POST("/community", controller.CreateCommunityHandler)
+		v1.PUT("/community/:id", controller.UpdateCommunityHandler)
+		v1.POST("/community/:id/archive", controller.ArchiveCommunityHandler)
+		v1.GET("/community/:id/moderators", controller.GetCommunityMembersHandler)
+		v1.POST("/community/:id/moderators", controller.AddCommunityModeratorHandler)
+		v1.DELETE("/community/:id/moderators/:user_id", controller.RemoveCommunityModeratorHandler)
+		// 加入和退出社区
+		v1.POST("/community/:id/join", controller.JoinCommunityHandler)
+		v1.POST("/community/:id/leave", controller.LeaveCommunityHandler)
+		v1.GET("/me/communities", controller.GetSubscribedCommunitiesHandler)
+
+		// 用户主页和账号设置
+		v1.GET("/user/:id", controller.GetUserProfileHandler)
+		v1.GET("/me", controller.GetMyProfileHandler)
+		v1.PATCH("/me", controller.UpdateProfileHandler)
+		v1.PUT("/me/password", controller.ChangePasswordHandler)
+		v1.POST("/me/email/verify", controller.ResendVerifyEmailHandler)
+		v1.GET("/leaderboard/karma", controller.KarmaLeaderboardHandler)
 
 		v1.POST("/post", controller.CreatePostHandler)
 		v1.GET("/post/:id", controller.GetPostDetailHandler)
+		v1.PUT("/post/:id", controller.UpdatePostHandler)
+		v1.DELETE("/post/:id", controller.DeletePostHandler)
+		v1.GET("/post/:id/revisions", controller.GetPostRevisionsHandler)
+		// 收藏
+		v1.POST("/post/:id/bookmark", controller.BookmarkPostHandler)
+		v1.DELETE("/post/:id/bookmark", controller.UnbookmarkPostHandler)
+		v1.GET("/me/bookmarks", controller.GetBookmarksHandler)
 		//v1.GET("/posts", controller.GetPostListHandler)
 		// 根据帖子时间或者分数进行排序，然后返回
 		v1.GET("/posts2", controller.GetPostListHandler2)
+		// 热门标签
+		v1.GET("/tags/trending", controller.TrendingTagsHandler)
+		// 按关键词搜索帖子
+		v1.GET("/search", controller.SearchHandler)
+		// 首页：加入的社区的帖子
+		v1.GET("/feed", controller.GetFeedHandler)
+		// 注销
+		v1.POST("/auth/logout", controller.LogoutHandler)
+
 		v1.POST("/vote", controller.PostVoteHandler)
 
+		// 评论
+		v1.POST("/post/:id/comments", controller.CreateCommentHandler)
+		v1.GET("/post/:id/comments", controller.GetCommentListHandler)
+		v1.POST("/comment/vote", controller.CommentVoteHandler)
+
+		// 通知
+		v1.GET("/notifications", controller.GetNotificationsHandler)
+		v1.GET("/notifications/unread", controller.GetUnreadCountHandler)
+		v1.POST("/notifications/read", controller.ReadNotificationsHandler)
+
+		// 版主工具，具体社区的权限在 logic 中检查
+		mod := v1.Group("/mod")
+		mod.POST("/post/:id/remove", middlewares.RequirePermission(rbac.PermPostModerate), controller.RemovePostHandler)
+		mod.POST("/post/:id/lock", middlewares.RequirePermission(rbac.PermPostModerate), controller.LockPostHandler)
+		mod.POST("/post/:id/pin", middlewares.RequirePermission(rbac.PermPostModerate), controller.PinPostHandler)
+		mod.POST("/community/:id/ban", middlewares.RequirePermission(rbac.PermUserBan), controller.BanUserHandler)
+		mod.DELETE("/community/:id/ban/:user_id", middlewares.RequirePermission(rbac.PermUserBan), controller.UnbanUserHandler)
+		mod.GET("/logs", middlewares.RequirePermission(rbac.PermAuditRead), controller.GetModerationLogsHandler)
+		mod.GET("/reviews", middlewares.RequirePermission(rbac.PermPostModerate), controller.GetPostReviewsHandler)
+		mod.POST("/reviews/:id/approve", middlewares.RequirePermission(rbac.PermPostModerate), controller.ApprovePostHandler)
+		mod.POST("/reviews/:id/reject", middlewares.RequirePermission(rbac.PermPostModerate), controller.RejectPostHandler)
+
+		// 管理员
+		admin := v1.Group("/admin")
+		admin.POST("/login/unlock", middlewares.RequirePermission(rbac.PermLoginUnlock), controller.UnlockLoginHandler)
+		admin.PUT("/users/:id/role", middlewares.RequirePermission(rbac.PermRoleManage), controller.SetUserRoleHandler)
+
 		// 文档
 		r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
 
+	// JWT 公钥
+	r.GET("/.well-known/jwks.json", controller.JWKSHandler)
+
 	r.GET("/ping", func(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, "ping --> pong")
 	})
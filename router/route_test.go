@@ -0,0 +1,75 @@
+package router
+
+import (
+	"bluebell/dao/redis"
+	"bluebell/setting"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	mr := miniredis.NewMiniRedis()
+	if err := mr.Start(); err != nil {
+		panic(err)
+	}
+	port, _ := strconv.Atoi(mr.Port())
+	if err := redis.Init(&setting.RedisConfig{Host: mr.Host(), Port: port}); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	redis.Close()
+	mr.Close()
+	os.Exit(code)
+}
+
+// loginFailureEngine 每次请求都以不同的用户名记录一次登录失败，只有IP计数会累积
+func loginFailureEngine(t *testing.T, trustedProxies []string) *gin.Engine {
+	rule := &redis.LoginGuardRule{MaxAttempts: 3, Window: time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour}
+	userRule := &redis.LoginGuardRule{MaxAttempts: 100, Window: time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour}
+	r := newEngine(trustedProxies)
+	r.POST("/login", func(ctx *gin.Context) {
+		lock, err := redis.RecordLoginFailure(ctx.Query("u"), ctx.ClientIP(), userRule, rule)
+		require.NoError(t, err)
+		if lock > 0 {
+			ctx.Status(http.StatusTooManyRequests)
+			return
+		}
+		ctx.Status(http.StatusOK)
+	})
+	return r
+}
+
+func loginFailure(r *gin.Engine, username, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodPost, "/login?u="+username, nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestForgedForwardedForKeepsIPCounter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// 没有配置信任的代理：伪造的 X-Forwarded-For 被忽略，同一来源的失败次数持续累积
+	r := loginFailureEngine(t, nil)
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, loginFailure(r, "a"+strconv.Itoa(i), "203.0.113.7:1234", "10.0.0."+strconv.Itoa(i)))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, loginFailure(r, "a2", "203.0.113.7:1234", "10.0.0.2"))
+
+	// 请求来自信任的代理时才采纳 X-Forwarded-For，各客户端分别计数
+	r = loginFailureEngine(t, []string{"198.51.100.1"})
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, loginFailure(r, "b"+strconv.Itoa(i), "198.51.100.1:1234", "192.0.2."+strconv.Itoa(i)))
+	}
+}
@@ -0,0 +1,43 @@
+package setting
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 密钥类配置（JWT 签名密钥、一次性令牌的签名密钥）不应提交到配置文件中，
+// 通过环境变量或密钥文件提供；启动时密钥缺失、是占位符或太短都会拒绝启动。
+
+// MinSecretLength HMAC 密钥的最小长度（字节）
+const MinSecretLength = 32
+
+var placeholderSecrets = []string{"xxxx", "xxx", "changeme", "change-me", "secret", "your-secret", "todo"}
+
+// ResolveSecret 按环境变量 env、密钥文件 file、配置值 value 的顺序读取密钥，name 用于错误信息
+func ResolveSecret(name, value, env, file string) (string, error) {
+	secret := value
+	switch {
+	case env != "" && os.Getenv(env) != "":
+		secret = os.Getenv(env)
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("read %s from file failed: %w", name, err)
+		}
+		secret = string(data)
+	}
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return "", fmt.Errorf("%s is not configured", name)
+	}
+	for _, p := range placeholderSecrets {
+		if strings.EqualFold(secret, p) {
+			return "", fmt.Errorf("%s is a placeholder, set a real secret", name)
+		}
+	}
+	if len(secret) < MinSecretLength {
+		return "", fmt.Errorf("%s must be at least %d bytes", name, MinSecretLength)
+	}
+	return secret, nil
+}
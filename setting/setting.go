@@ -17,10 +17,37 @@ type AppConfig struct {
 	MachineID int64  `mapstructure:"machine_id"`
 	Port      int    `mapstructure:"port"`
 
+	*AuthConfig             `mapstructure:"auth"`
 	*LogConfig              `mapstructure:"log"`
 	*MySQLConfig            `mapstructure:"mysql"`
 	*RedisConfig            `mapstructure:"redis"`
 	*RedisPersistenceConfig `mapstructure:"redis_persistence"`
+	*PasswordConfig         `mapstructure:"password"`
+	*RateLimitConfig        `mapstructure:"rate_limit"`
+	*LoginGuardConfig       `mapstructure:"login_guard"`
+	*RankingConfig          `mapstructure:"ranking"`
+	*SearchConfig           `mapstructure:"search"`
+	*MailConfig             `mapstructure:"mail"`
+	*ModerationConfig       `mapstructure:"moderation"`
+
+	AdminUserIDs   []int64  `mapstructure:"admin_user_ids"`  // 启动时授予管理员角色的用户ID
+	TrustedProxies []string `mapstructure:"trusted_proxies"` // 信任的反向代理地址，只有来自这些地址的 X-Forwarded-For 才会被采纳
+}
+
+type AuthConfig struct {
+	JwtExpire  int             `mapstructure:"jwt_expire"`
+	SigningKey string          `mapstructure:"signing_key"`
+	Keys       []*JWTKeyConfig `mapstructure:"keys"`
+}
+
+type JWTKeyConfig struct {
+	KID            string `mapstructure:"kid"`
+	Algorithm      string `mapstructure:"algorithm"`
+	Secret         string `mapstructure:"secret"`      // 不建议直接写在配置文件中
+	SecretEnv      string `mapstructure:"secret_env"`  // 从环境变量读取 HS256 密钥
+	SecretFile     string `mapstructure:"secret_file"` // 从文件读取 HS256 密钥
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
 }
 
 type MySQLConfig struct {
@@ -56,10 +83,75 @@ type RedisPersistenceConfig struct {
 	ScoreFixedDays    int    `mapstructure:"score_fixed_days"`
 	BatchSize         int    `mapstructure:"batch_size"`
 	Timeout           int    `mapstructure:"timeout"`
-	CleanAfterPersist bool   `mapstructure:"clean_after_persist"`
+	CleanAfterPersist bool   `mapstructure:"cleanup_after_persist"`
 	LogLevel          string `mapstructure:"log_level"`
 }
 
+type PasswordConfig struct {
+	Algorithm         string `mapstructure:"algorithm"`
+	Argon2Memory      uint32 `mapstructure:"argon2_memory"`
+	Argon2Iterations  uint32 `mapstructure:"argon2_iterations"`
+	Argon2Parallelism uint8  `mapstructure:"argon2_parallelism"`
+	BcryptCost        int    `mapstructure:"bcrypt_cost"`
+}
+
+type RateLimitConfig struct {
+	Default *RateLimitRule   `mapstructure:"default"`
+	Routes  []*RateLimitRule `mapstructure:"routes"`
+}
+
+type RateLimitRule struct {
+	Method   string  `mapstructure:"method"`
+	Path     string  `mapstructure:"path"`
+	Rate     float64 `mapstructure:"rate"`
+	Capacity int64   `mapstructure:"capacity"`
+}
+
+type LoginGuardConfig struct {
+	MaxAttempts   int64 `mapstructure:"max_attempts"`
+	IPMaxAttempts int64 `mapstructure:"ip_max_attempts"`
+	Window        int   `mapstructure:"window"`
+	BaseLockout   int   `mapstructure:"base_lockout"`
+	MaxLockout    int   `mapstructure:"max_lockout"`
+}
+
+type RankingConfig struct {
+	Interval   int `mapstructure:"interval"`
+	WindowDays int `mapstructure:"window_days"`
+	BatchSize  int `mapstructure:"batch_size"`
+	Timeout    int `mapstructure:"timeout"`
+}
+
+type SearchConfig struct {
+	Engine string `mapstructure:"engine"`
+}
+
+type MailConfig struct {
+	Driver          string `mapstructure:"driver"`
+	Host            string `mapstructure:"host"`
+	Port            int    `mapstructure:"port"`
+	Username        string `mapstructure:"username"`
+	Password        string `mapstructure:"password"`
+	From            string `mapstructure:"from"`
+	LogFile         string `mapstructure:"log_file"`
+	BaseURL         string `mapstructure:"base_url"`
+	TokenSecret     string `mapstructure:"token_secret"`      // 不建议直接写在配置文件中
+	TokenSecretEnv  string `mapstructure:"token_secret_env"`  // 从环境变量读取一次性令牌的签名密钥
+	TokenSecretFile string `mapstructure:"token_secret_file"` // 从文件读取一次性令牌的签名密钥
+	VerifyTTL       int    `mapstructure:"verify_ttl"`
+	ResetTTL        int    `mapstructure:"reset_ttl"`
+}
+
+type ModerationConfig struct {
+	WordFiles            []string `mapstructure:"word_files"`
+	DefaultAction        string   `mapstructure:"default_action"`
+	MaxLinks             int      `mapstructure:"max_links"`
+	BlockedDomains       []string `mapstructure:"blocked_domains"`
+	MaxRepeat            int      `mapstructure:"max_repeat"`
+	NewAccountDays       int      `mapstructure:"new_account_days"`
+	NewAccountDailyPosts int64    `mapstructure:"new_account_daily_posts"`
+}
+
 // Init 配置项初始化接口
 func Init(filePath string) (err error) {
 	// 方式1：直接指定配置文件路径（相对路径或者绝对路径）